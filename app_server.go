@@ -15,14 +15,26 @@ import (
 // AppServer 应用服务器结构体，封装所有服务和处理器
 type AppServer struct {
 	xiaohongshuService *XiaohongshuService
-	router             *gin.Engine
-	httpServer         *http.Server
+	tools              *ToolRegistry
+	sessions           *SessionManager
+	resourceWatcher    *ResourceWatcher
+	// confirmations 确认模式下的确认令牌，为空表示未开启确认模式
+	confirmations *ConfirmationStore
+	// auth API Key 认证，为空表示未开启认证
+	auth *Authenticator
+	// canary 定期页面巡检，为空表示未开启
+	canary     *CanaryRunner
+	router     *gin.Engine
+	httpServer *http.Server
 }
 
 // NewAppServer 创建新的应用服务器实例
 func NewAppServer(xiaohongshuService *XiaohongshuService) *AppServer {
 	return &AppServer{
 		xiaohongshuService: xiaohongshuService,
+		tools:              NewToolRegistry(defaultTools()...),
+		sessions:           NewSessionManager(defaultSessionIdleTimeout),
+		resourceWatcher:    NewResourceWatcher(xiaohongshuService.noteStats, resourceWatchInterval),
 	}
 }
 
@@ -35,6 +47,15 @@ func (s *AppServer) Start(port string) error {
 		Handler: s.router,
 	}
 
+	// 定期清理空闲的 MCP 会话、轮询订阅的笔记，服务器关闭时停止
+	janitorCtx, stopJanitor := context.WithCancel(context.Background())
+	defer stopJanitor()
+	go s.sessions.RunJanitor(janitorCtx)
+	go s.resourceWatcher.Run(janitorCtx)
+	if s.canary != nil {
+		go s.canary.Run(janitorCtx)
+	}
+
 	// 启动服务器的 goroutine
 	go func() {
 		logrus.Infof("启动 HTTP 服务器: %s", port)
@@ -50,6 +71,7 @@ func (s *AppServer) Start(port string) error {
 	<-quit
 
 	logrus.Infof("正在关闭服务器...")
+	stopJanitor()
 
 	// 优雅关闭
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
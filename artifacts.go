@@ -0,0 +1,744 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
+)
+
+const (
+	// 每次浏览器操作最多保留的控制台消息、网络请求和步骤日志条数
+	maxArtifactConsole  = 200
+	maxArtifactRequests = 300
+	maxArtifactSteps    = 500
+	// maxArtifactBundles 目录下最多保留的失败现场数量，超出时删除最旧的
+	maxArtifactBundles = 100
+)
+
+// 失败现场中的文件
+const (
+	artifactMetaFile         = "artifact.json"
+	artifactScreenshotFile   = "screenshot.png"
+	artifactHTMLFile         = "page.html"
+	artifactInitialStateFile = "initial_state.json"
+	artifactStepsFile        = "steps.log"
+	artifactConsoleFile      = "console.json"
+	artifactHARFile          = "network.har"
+)
+
+// artifactIDPattern 失败现场 ID：时间戳加随机后缀，同时防止路径穿越
+var artifactIDPattern = regexp.MustCompile(`^\d{8}-\d{6}-[0-9a-f]{8}$`)
+
+// redactedHeaders 写入 HAR 时隐藏的请求头，避免泄露登录态
+var redactedHeaders = map[string]bool{
+	"cookie":        true,
+	"set-cookie":    true,
+	"authorization": true,
+}
+
+// ringBuffer 只保留最近 limit 条记录
+type ringBuffer[T any] struct {
+	items []T
+	limit int
+}
+
+// push 追加一条记录，超出容量时返回被淘汰的最旧记录
+func (r *ringBuffer[T]) push(item T) (evicted T, ok bool) {
+	r.items = append(r.items, item)
+	if len(r.items) <= r.limit {
+		return evicted, false
+	}
+	evicted = r.items[0]
+	r.items = r.items[1:]
+	return evicted, true
+}
+
+// consoleEntry 页面控制台消息或未捕获的异常
+type consoleEntry struct {
+	Time  time.Time `json:"time"`
+	Level string    `json:"level"`
+	Text  string    `json:"text"`
+	URL   string    `json:"url,omitempty"`
+}
+
+// networkEntry 一次网络请求
+type networkEntry struct {
+	id              proto.NetworkRequestID
+	startedAt       time.Time
+	start           proto.MonotonicTime
+	responded       proto.MonotonicTime
+	end             proto.MonotonicTime
+	method          string
+	url             string
+	resourceType    string
+	requestHeaders  proto.NetworkHeaders
+	status          int
+	statusText      string
+	protocol        string
+	mimeType        string
+	responseHeaders proto.NetworkHeaders
+	size            float64
+	errorText       string
+}
+
+// artifactRecorder 记录一次浏览器操作过程中的控制台消息、网络请求和步骤日志，
+// 操作失败时写入失败现场
+type artifactRecorder struct {
+	mu       sync.Mutex
+	console  ringBuffer[consoleEntry]
+	requests ringBuffer[*networkEntry]
+	pending  map[proto.NetworkRequestID]*networkEntry
+	steps    ringBuffer[string]
+	stop     func()
+}
+
+func newArtifactRecorder() *artifactRecorder {
+	return &artifactRecorder{
+		console:  ringBuffer[consoleEntry]{limit: maxArtifactConsole},
+		requests: ringBuffer[*networkEntry]{limit: maxArtifactRequests},
+		pending:  make(map[proto.NetworkRequestID]*networkEntry),
+		steps:    ringBuffer[string]{limit: maxArtifactSteps},
+		stop:     func() {},
+	}
+}
+
+// startArtifactRecorder 开始记录页面的控制台和网络事件，调用 stop 结束
+func startArtifactRecorder(page *rod.Page) *artifactRecorder {
+	r := newArtifactRecorder()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wait := page.Context(ctx).EachEvent(
+		r.onConsole,
+		r.onException,
+		r.onRequest,
+		r.onResponse,
+		r.onFinished,
+		r.onFailed,
+		r.onNavigated,
+	)
+	go wait()
+
+	r.stop = cancel
+	return r
+}
+
+func (r *artifactRecorder) onConsole(e *proto.RuntimeConsoleAPICalled) {
+	texts := make([]string, 0, len(e.Args))
+	for _, arg := range e.Args {
+		texts = append(texts, remoteObjectText(arg))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.console.push(consoleEntry{
+		Time:  runtimeTime(e.Timestamp),
+		Level: string(e.Type),
+		Text:  strings.Join(texts, " "),
+	})
+}
+
+func (r *artifactRecorder) onException(e *proto.RuntimeExceptionThrown) {
+	if e.ExceptionDetails == nil {
+		return
+	}
+
+	text := e.ExceptionDetails.Text
+	if e.ExceptionDetails.Exception != nil {
+		text += " " + remoteObjectText(e.ExceptionDetails.Exception)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.console.push(consoleEntry{
+		Time:  runtimeTime(e.Timestamp),
+		Level: "exception",
+		Text:  text,
+		URL:   e.ExceptionDetails.URL,
+	})
+}
+
+func (r *artifactRecorder) onRequest(e *proto.NetworkRequestWillBeSent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// 重定向沿用同一个 requestId，先结束上一跳
+	if prev, ok := r.pending[e.RequestID]; ok && e.RedirectResponse != nil {
+		prev.setResponse(e.RedirectResponse, e.Timestamp)
+		prev.end = e.Timestamp
+		delete(r.pending, e.RequestID)
+	}
+
+	entry := &networkEntry{
+		id:           e.RequestID,
+		startedAt:    e.WallTime.Time(),
+		start:        e.Timestamp,
+		resourceType: string(e.Type),
+	}
+	if e.Request != nil {
+		entry.method = e.Request.Method
+		entry.url = e.Request.URL
+		entry.requestHeaders = e.Request.Headers
+	}
+
+	r.pending[e.RequestID] = entry
+	if evicted, ok := r.requests.push(entry); ok && r.pending[evicted.id] == evicted {
+		delete(r.pending, evicted.id)
+	}
+}
+
+func (r *artifactRecorder) onResponse(e *proto.NetworkResponseReceived) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry, ok := r.pending[e.RequestID]; ok && e.Response != nil {
+		entry.setResponse(e.Response, e.Timestamp)
+	}
+}
+
+func (r *artifactRecorder) onFinished(e *proto.NetworkLoadingFinished) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry, ok := r.pending[e.RequestID]; ok {
+		entry.end = e.Timestamp
+		entry.size = e.EncodedDataLength
+		delete(r.pending, e.RequestID)
+	}
+}
+
+func (r *artifactRecorder) onFailed(e *proto.NetworkLoadingFailed) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry, ok := r.pending[e.RequestID]; ok {
+		entry.end = e.Timestamp
+		entry.errorText = e.ErrorText
+		delete(r.pending, e.RequestID)
+	}
+}
+
+func (r *artifactRecorder) onNavigated(e *proto.PageFrameNavigated) {
+	if e.Frame == nil || e.Frame.ParentID != "" {
+		return
+	}
+	r.step(time.Now(), "打开页面 "+e.Frame.URL)
+}
+
+// step 追加一行步骤日志
+func (r *artifactRecorder) step(t time.Time, line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.steps.push(t.Format("15:04:05.000") + " " + line)
+}
+
+func (e *networkEntry) setResponse(resp *proto.NetworkResponse, at proto.MonotonicTime) {
+	e.responded = at
+	e.status = resp.Status
+	e.statusText = resp.StatusText
+	e.protocol = resp.Protocol
+	e.mimeType = resp.MIMEType
+	e.responseHeaders = resp.Headers
+}
+
+// remoteObjectText 控制台参数的文本形式
+func remoteObjectText(obj *proto.RuntimeRemoteObject) string {
+	switch {
+	case obj.Description != "":
+		return obj.Description
+	case obj.UnserializableValue != "":
+		return string(obj.UnserializableValue)
+	case obj.Value.Nil():
+		return string(obj.Type)
+	default:
+		return obj.Value.Str()
+	}
+}
+
+// runtimeTime Runtime 域的时间戳为毫秒
+func runtimeTime(ts proto.RuntimeTimestamp) time.Time {
+	return time.UnixMilli(int64(ts))
+}
+
+// artifactRecorderContextKey 记录器在 context 中的 key，步骤日志通过它关联到当前操作
+type artifactRecorderContextKey struct{}
+
+func withArtifactRecorder(ctx context.Context, r *artifactRecorder) context.Context {
+	return context.WithValue(ctx, artifactRecorderContextKey{}, r)
+}
+
+func artifactRecorderFromContext(ctx context.Context) (*artifactRecorder, bool) {
+	r, ok := ctx.Value(artifactRecorderContextKey{}).(*artifactRecorder)
+	return r, ok
+}
+
+// artifactStepHook 将携带记录器 context 的日志写入步骤日志
+type artifactStepHook struct {
+	formatter logrus.TextFormatter
+}
+
+func newArtifactStepHook() *artifactStepHook {
+	return &artifactStepHook{formatter: logrus.TextFormatter{DisableColors: true, DisableTimestamp: true}}
+}
+
+// Levels 实现 logrus.Hook
+func (h *artifactStepHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire 实现 logrus.Hook
+func (h *artifactStepHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+
+	r, ok := artifactRecorderFromContext(entry.Context)
+	if !ok {
+		return nil
+	}
+
+	line, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	r.step(entry.Time, strings.TrimSpace(string(line)))
+	return nil
+}
+
+// artifactSnapshot 失败时的页面快照
+type artifactSnapshot struct {
+	URL          string
+	Screenshot   []byte
+	HTML         string
+	InitialState string
+}
+
+// captureArtifactSnapshot 截取整页截图并导出 HTML 和 __INITIAL_STATE__，单项失败时跳过
+func captureArtifactSnapshot(page *rod.Page) artifactSnapshot {
+	var snapshot artifactSnapshot
+	page = page.Timeout(10 * time.Second)
+	defer page.CancelTimeout()
+
+	if info, err := page.Info(); err == nil {
+		snapshot.URL = info.URL
+	}
+
+	var err error
+	if snapshot.Screenshot, err = page.Screenshot(true, nil); err != nil {
+		logrus.WithError(err).Warn("失败现场：截取整页截图失败")
+	}
+	if snapshot.HTML, err = page.HTML(); err != nil {
+		logrus.WithError(err).Warn("失败现场：导出页面 HTML 失败")
+	}
+	if result, err := page.Eval(xiaohongshu.InitialStateScript); err != nil {
+		logrus.WithError(err).Warn("失败现场：导出 __INITIAL_STATE__ 失败")
+	} else {
+		snapshot.InitialState = result.Value.Str()
+	}
+
+	return snapshot
+}
+
+// ArtifactMeta 失败现场的基本信息
+type ArtifactMeta struct {
+	ID        string    `json:"id"`
+	Account   string    `json:"account"`
+	URL       string    `json:"url,omitempty"`
+	Error     string    `json:"error"`
+	CreatedAt time.Time `json:"created_at"`
+	Files     []string  `json:"files"`
+}
+
+// ArtifactStore 失败现场存储：每次失败保存为目录下的一个子目录
+type ArtifactStore struct {
+	dir string
+	now func() time.Time
+
+	// mu 保证写入和清理不会同时进行
+	mu sync.Mutex
+}
+
+// NewArtifactStore 创建失败现场存储，目录不存在时自动创建
+func NewArtifactStore(dir string) (*ArtifactStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.Wrap(err, "create artifacts dir")
+	}
+	return &ArtifactStore{dir: dir, now: time.Now}, nil
+}
+
+// Save 保存失败现场，返回其 ID
+func (s *ArtifactStore) Save(account string, cause error, snapshot artifactSnapshot, recorder *artifactRecorder) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	id, err := newArtifactID(now)
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(s.dir, id)
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return "", errors.Wrap(err, "create artifact dir")
+	}
+
+	files := map[string][]byte{}
+	if len(snapshot.Screenshot) > 0 {
+		files[artifactScreenshotFile] = snapshot.Screenshot
+	}
+	if snapshot.HTML != "" {
+		files[artifactHTMLFile] = []byte(snapshot.HTML)
+	}
+	if snapshot.InitialState != "" {
+		files[artifactInitialStateFile] = []byte(snapshot.InitialState)
+	}
+
+	if recorder != nil {
+		recorder.mu.Lock()
+		steps := strings.Join(recorder.steps.items, "\n")
+		console, _ := json.MarshalIndent(recorder.console.items, "", "  ")
+		har, _ := json.MarshalIndent(buildHAR(recorder.requests.items), "", "  ")
+		recorder.mu.Unlock()
+
+		files[artifactStepsFile] = []byte(steps + "\n")
+		files[artifactConsoleFile] = console
+		files[artifactHARFile] = har
+	}
+
+	meta := ArtifactMeta{
+		ID:        id,
+		Account:   displayAccount(account),
+		URL:       snapshot.URL,
+		Error:     cause.Error(),
+		CreatedAt: now,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			return "", errors.Wrapf(err, "write %s", name)
+		}
+		meta.Files = append(meta.Files, name)
+	}
+	sort.Strings(meta.Files)
+
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return "", errors.Wrap(err, "marshal artifact meta")
+	}
+	if err := os.WriteFile(filepath.Join(dir, artifactMetaFile), data, 0o644); err != nil {
+		return "", errors.Wrap(err, "write artifact meta")
+	}
+
+	s.prune()
+	return id, nil
+}
+
+// prune 超出保留数量时删除最旧的失败现场，ID 以时间戳开头，按名称排序即按时间排序
+func (s *ArtifactStore) prune() {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		logrus.WithError(err).Warn("读取失败现场目录失败")
+		return
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if entry.IsDir() && artifactIDPattern.MatchString(entry.Name()) {
+			ids = append(ids, entry.Name())
+		}
+	}
+	sort.Strings(ids)
+
+	for len(ids) > maxArtifactBundles {
+		if err := os.RemoveAll(filepath.Join(s.dir, ids[0])); err != nil {
+			logrus.WithError(err).Warnf("删除失败现场 %s 失败", ids[0])
+		}
+		ids = ids[1:]
+	}
+}
+
+// WriteZip 将失败现场打包为 zip 写入 w
+func (s *ArtifactStore) WriteZip(w io.Writer, id string) error {
+	dir, err := s.path(id)
+	if err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return errors.Wrap(err, "read artifact dir")
+	}
+
+	zw := zip.NewWriter(w)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if err := addZipFile(zw, filepath.Join(dir, entry.Name()), id+"/"+entry.Name()); err != nil {
+			return err
+		}
+	}
+	return errors.Wrap(zw.Close(), "close zip")
+}
+
+// path 失败现场目录，ID 不合法或不存在时返回 os.ErrNotExist
+func (s *ArtifactStore) path(id string) (string, error) {
+	if !artifactIDPattern.MatchString(id) {
+		return "", errors.WithStack(os.ErrNotExist)
+	}
+
+	dir := filepath.Join(s.dir, id)
+	if _, err := os.Stat(dir); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return dir, nil
+}
+
+func addZipFile(zw *zip.Writer, path, name string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrapf(err, "open %s", name)
+	}
+	defer f.Close()
+
+	w, err := zw.Create(name)
+	if err != nil {
+		return errors.Wrapf(err, "create zip entry %s", name)
+	}
+	_, err = io.Copy(w, f)
+	return errors.Wrapf(err, "write zip entry %s", name)
+}
+
+func newArtifactID(now time.Time) (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", errors.Wrap(err, "generate artifact id")
+	}
+	return now.Format("20060102-150405") + "-" + hex.EncodeToString(suffix), nil
+}
+
+// HAR 1.2 格式，只包含排查问题需要的字段
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	ResourceType    string      `json:"_resourceType,omitempty"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// buildHAR 将网络请求记录转换为 HAR
+func buildHAR(entries []*networkEntry) harFile {
+	har := harFile{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "xiaohongshu-mcp", Version: "1.0.0"},
+		Entries: make([]harEntry, 0, len(entries)),
+	}}
+
+	for _, e := range entries {
+		wait, receive := -1.0, -1.0
+		if e.responded > 0 {
+			wait = milliseconds(e.responded - e.start)
+			if e.end > 0 {
+				receive = milliseconds(e.end - e.responded)
+			}
+		}
+
+		total := 0.0
+		if e.end > 0 {
+			total = milliseconds(e.end - e.start)
+		}
+
+		har.Log.Entries = append(har.Log.Entries, harEntry{
+			StartedDateTime: e.startedAt.Format(time.RFC3339Nano),
+			Time:            total,
+			Request: harRequest{
+				Method:      e.method,
+				URL:         e.url,
+				HTTPVersion: e.protocol,
+				Cookies:     []harNameValue{},
+				Headers:     harHeaders(e.requestHeaders),
+				QueryString: harQueryString(e.url),
+				HeadersSize: -1,
+				BodySize:    -1,
+			},
+			Response: harResponse{
+				Status:      e.status,
+				StatusText:  e.statusText,
+				HTTPVersion: e.protocol,
+				Cookies:     []harNameValue{},
+				Headers:     harHeaders(e.responseHeaders),
+				Content:     harContent{Size: int(e.size), MimeType: e.mimeType},
+				HeadersSize: -1,
+				BodySize:    int(e.size),
+			},
+			Timings:      harTimings{Send: 0, Wait: wait, Receive: receive},
+			ResourceType: e.resourceType,
+			Error:        e.errorText,
+		})
+	}
+
+	return har
+}
+
+func milliseconds(d proto.MonotonicTime) float64 {
+	return float64(d.Duration()) / float64(time.Millisecond)
+}
+
+// harHeaders 按名称排序，隐藏包含登录态的请求头
+func harHeaders(headers proto.NetworkHeaders) []harNameValue {
+	values := make([]harNameValue, 0, len(headers))
+	for name, value := range headers {
+		v := value.Str()
+		if redactedHeaders[strings.ToLower(name)] {
+			v = "[redacted]"
+		}
+		values = append(values, harNameValue{Name: name, Value: v})
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].Name < values[j].Name })
+	return values
+}
+
+func harQueryString(rawURL string) []harNameValue {
+	values := []harNameValue{}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return values
+	}
+
+	query := u.Query()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, v := range query[name] {
+			values = append(values, harNameValue{Name: name, Value: v})
+		}
+	}
+	return values
+}
+
+// SetArtifactStore 设置失败现场存储，为空表示不记录
+func (s *XiaohongshuService) SetArtifactStore(store *ArtifactStore) {
+	s.artifacts = store
+}
+
+// saveArtifacts 保存失败现场，返回其 ID，未开启或保存失败时返回空字符串
+func (s *XiaohongshuService) saveArtifacts(ctx context.Context, page *rod.Page, recorder *artifactRecorder, cause error) string {
+	if s.artifacts == nil {
+		return ""
+	}
+
+	id, err := s.artifacts.Save(accountFromContext(ctx), cause, captureArtifactSnapshot(page), recorder)
+	if err != nil {
+		logrus.WithContext(ctx).WithError(err).Warn("保存失败现场失败")
+		return ""
+	}
+
+	logrus.WithContext(ctx).WithField("artifact_id", id).Info("已保存失败现场")
+	return id
+}
+
+// artifactDownloadHandler 下载失败现场的 zip 包，需要 admin 权限
+func (s *AppServer) artifactDownloadHandler(c *gin.Context) {
+	if err := checkScope(c.Request.Context(), ScopeAdmin); err != nil {
+		respondError(c, http.StatusForbidden, "FORBIDDEN", "权限不足", err.Error())
+		return
+	}
+
+	store := s.xiaohongshuService.artifacts
+	if store == nil {
+		respondError(c, http.StatusNotFound, "NOT_FOUND", "未开启失败现场记录", nil)
+		return
+	}
+
+	id := c.Param("id")
+	if _, err := store.path(id); err != nil {
+		respondError(c, http.StatusNotFound, "NOT_FOUND", "失败现场不存在", id)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", `attachment; filename="artifact-`+id+`.zip"`)
+	if err := store.WriteZip(c.Writer, id); err != nil {
+		logrus.WithError(err).Errorf("打包失败现场 %s 失败", id)
+		return
+	}
+	logrus.Infof("%s %s %s %d", c.Request.Method, c.Request.URL.Path, c.GetString("account"), http.StatusOK)
+}
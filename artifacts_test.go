@@ -0,0 +1,196 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ysmood/gson"
+)
+
+func TestArtifactRecorder(t *testing.T) {
+	r := newArtifactRecorder()
+
+	r.onConsole(&proto.RuntimeConsoleAPICalled{
+		Type:      proto.RuntimeConsoleAPICalledTypeError,
+		Args:      []*proto.RuntimeRemoteObject{{Type: "string", Value: gson.New("upload failed")}, {Type: "number", Value: gson.New(413)}},
+		Timestamp: 1700000000000,
+	})
+	r.onException(&proto.RuntimeExceptionThrown{ExceptionDetails: &proto.RuntimeExceptionDetails{
+		Text:      "Uncaught",
+		Exception: &proto.RuntimeRemoteObject{Type: "object", Description: "TypeError: x is undefined"},
+		URL:       "https://creator.xiaohongshu.com/app.js",
+	}})
+
+	r.onRequest(&proto.NetworkRequestWillBeSent{
+		RequestID: "1",
+		Request: &proto.NetworkRequest{
+			Method:  http.MethodPost,
+			URL:     "https://edith.xiaohongshu.com/api/sns/web/v1/feed?source=pc",
+			Headers: proto.NetworkHeaders{"Cookie": gson.New("a1=secret"), "Accept": gson.New("application/json")},
+		},
+		Timestamp: 10,
+		WallTime:  1700000000,
+		Type:      proto.NetworkResourceTypeXHR,
+	})
+	r.onResponse(&proto.NetworkResponseReceived{RequestID: "1", Timestamp: 10.2, Response: &proto.NetworkResponse{
+		Status: 461, StatusText: "Risk", Protocol: "h2", MIMEType: "application/json",
+	}})
+	r.onFinished(&proto.NetworkLoadingFinished{RequestID: "1", Timestamp: 10.25, EncodedDataLength: 128})
+	r.onRequest(&proto.NetworkRequestWillBeSent{RequestID: "2", Request: &proto.NetworkRequest{Method: http.MethodGet, URL: "https://sns-img.xhscdn.com/a.jpg"}, Timestamp: 11})
+	r.onFailed(&proto.NetworkLoadingFailed{RequestID: "2", Timestamp: 12, ErrorText: "net::ERR_FAILED"})
+	r.onNavigated(&proto.PageFrameNavigated{Frame: &proto.PageFrame{URL: "https://www.xiaohongshu.com/explore"}})
+
+	// 携带记录器 context 的日志写入步骤日志
+	logger := logrus.New()
+	logger.SetOutput(&bytes.Buffer{})
+	logger.AddHook(newArtifactStepHook())
+	logger.WithContext(withArtifactRecorder(context.Background(), r)).WithField("count", 3).Info("找不到下一步按钮")
+	logger.WithContext(context.Background()).Info("与当前操作无关")
+
+	require.Len(t, r.console.items, 2)
+	assert.Equal(t, "upload failed 413", r.console.items[0].Text)
+	assert.Equal(t, "error", r.console.items[0].Level)
+	assert.Equal(t, "Uncaught TypeError: x is undefined", r.console.items[1].Text)
+
+	require.Len(t, r.steps.items, 2)
+	assert.Contains(t, r.steps.items[0], "打开页面 https://www.xiaohongshu.com/explore")
+	assert.Contains(t, r.steps.items[1], `msg="找不到下一步按钮" count=3`)
+
+	har := buildHAR(r.requests.items)
+	require.Len(t, har.Log.Entries, 2)
+	feed := har.Log.Entries[0]
+	assert.Equal(t, 461, feed.Response.Status)
+	assert.InDelta(t, 250, feed.Time, 0.001)
+	assert.InDelta(t, 200, feed.Timings.Wait, 0.001)
+	assert.Equal(t, []harNameValue{{Name: "source", Value: "pc"}}, feed.Request.QueryString)
+	assert.Equal(t, []harNameValue{{Name: "Accept", Value: "application/json"}, {Name: "Cookie", Value: "[redacted]"}}, feed.Request.Headers)
+	assert.Equal(t, "net::ERR_FAILED", har.Log.Entries[1].Error)
+	assert.Empty(t, r.pending)
+}
+
+func TestRingBuffer(t *testing.T) {
+	r := ringBuffer[int]{limit: 2}
+	r.push(1)
+	r.push(2)
+	evicted, ok := r.push(3)
+	assert.True(t, ok)
+	assert.Equal(t, 1, evicted)
+	assert.Equal(t, []int{2, 3}, r.items)
+}
+
+func TestArtifactStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewArtifactStore(dir)
+	require.NoError(t, err)
+
+	recorder := newArtifactRecorder()
+	recorder.step(time.Now(), "打开页面 https://creator.xiaohongshu.com/publish/publish")
+
+	id, err := store.Save("alice", errors.New("找不到下一步按钮"), artifactSnapshot{
+		URL:          "https://creator.xiaohongshu.com/publish/publish",
+		Screenshot:   []byte("png"),
+		HTML:         "<html></html>",
+		InitialState: `{"user":{}}`,
+	}, recorder)
+	require.NoError(t, err)
+	assert.Regexp(t, artifactIDPattern, id)
+
+	data, err := os.ReadFile(filepath.Join(dir, id, artifactMetaFile))
+	require.NoError(t, err)
+	var meta ArtifactMeta
+	require.NoError(t, json.Unmarshal(data, &meta))
+	assert.Equal(t, "alice", meta.Account)
+	assert.Equal(t, "找不到下一步按钮", meta.Error)
+	assert.Equal(t, []string{artifactConsoleFile, artifactInitialStateFile, artifactHARFile, artifactHTMLFile,
+		artifactScreenshotFile, artifactStepsFile}, meta.Files)
+
+	// 超出保留数量时删除最旧的失败现场
+	store.now = func() time.Time { return time.Now().Add(time.Hour) }
+	for i := 0; i < maxArtifactBundles; i++ {
+		_, err := store.Save("", errors.New("timeout"), artifactSnapshot{}, nil)
+		require.NoError(t, err)
+	}
+	_, err = os.Stat(filepath.Join(dir, id))
+	assert.True(t, os.IsNotExist(err))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, maxArtifactBundles)
+}
+
+func TestArtifactDownloadAPI(t *testing.T) {
+	store, err := NewArtifactStore(t.TempDir())
+	require.NoError(t, err)
+	id, err := store.Save("alice", errors.New("找不到下一步按钮"), artifactSnapshot{HTML: "<html></html>"}, newArtifactRecorder())
+	require.NoError(t, err)
+
+	service := NewXiaohongshuService()
+	service.SetArtifactStore(store)
+	appServer := NewAppServer(service)
+	appServer.EnableAuth(testAuthConfig)
+	router := setupRoutes(appServer)
+
+	request := func(path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	assert.Equal(t, http.StatusForbidden, request("/api/v1/artifacts/"+id, "read-key").Code)
+	assert.Equal(t, http.StatusNotFound, request("/api/v1/artifacts/..", "admin-key").Code)
+	assert.Equal(t, http.StatusNotFound, request("/api/v1/artifacts/20250101-000000-00000000", "admin-key").Code)
+
+	recorder := request("/api/v1/artifacts/"+id, "admin-key")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/zip", recorder.Header().Get("Content-Type"))
+
+	body := recorder.Body.Bytes()
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{
+		id + "/" + artifactMetaFile, id + "/" + artifactConsoleFile, id + "/" + artifactHARFile,
+		id + "/" + artifactHTMLFile, id + "/" + artifactStepsFile,
+	}, names)
+}
+
+func TestErrorResponsesReferenceArtifact(t *testing.T) {
+	err := errors.Wrap(&BrowserActionError{Err: errors.New("找不到下一步按钮"), ArtifactID: "20250101-120000-0123abcd"}, "小红书发布失败")
+
+	result := errorResult("发布失败", err)
+	require.Len(t, result.Content, 2)
+	assert.Contains(t, result.Content[1].Text, "20250101-120000-0123abcd")
+
+	service := NewXiaohongshuService()
+	tool, ok := NewAppServer(service).tools.Get("publish_content")
+	require.True(t, ok)
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(tool.Method, "/api/v1"+tool.Path, nil)
+	respondToolError(c, tool, err)
+
+	var response ErrorResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, "20250101-120000-0123abcd", response.ArtifactID)
+}
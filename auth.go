@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// Scope API Key 的权限范围
+type Scope string
+
+const (
+	// ScopeRead 读取数据：检查登录状态、搜索、获取详情等
+	ScopeRead Scope = "read"
+	// ScopePublish 发布内容
+	ScopePublish Scope = "publish"
+	// ScopeAdmin 账号管理（扫码登录等），拥有全部权限
+	ScopeAdmin Scope = "admin"
+)
+
+const (
+	// authFileEnv 认证配置文件路径的环境变量
+	authFileEnv = "XHS_MCP_AUTH_FILE"
+	// apiKeyEnv 单个管理员 API Key 的环境变量，适合只有一个调用方的场景
+	apiKeyEnv = "XHS_MCP_API_KEY"
+
+	apiKeyHeader = "X-API-Key"
+
+	// errCodeForbidden 调用方缺少权限时返回的 JSON-RPC 错误码
+	errCodeForbidden = -32001
+)
+
+// methodScopes 需要权限的 MCP 方法，tools/call 按工具各自的 Scope 检查
+var methodScopes = map[string]Scope{
+	"resources/list":           ScopeRead,
+	"resources/templates/list": ScopeRead,
+	"resources/read":           ScopeRead,
+	"resources/subscribe":      ScopeRead,
+	"resources/unsubscribe":    ScopeRead,
+	"prompts/list":             ScopeRead,
+	"prompts/get":              ScopeRead,
+}
+
+// accountNamePattern 账号名会用于 cookies 文件名，只允许字母、数字、下划线和连字符
+var accountNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// APIKey 调用方的凭证
+type APIKey struct {
+	// Name 调用方名称，用于日志和会话绑定
+	Name   string  `json:"name"`
+	Key    string  `json:"key"`
+	Scopes []Scope `json:"scopes"`
+	// Account 使用的小红书账号，为空时使用默认账号
+	Account string `json:"account,omitempty"`
+}
+
+// Allows 是否拥有指定权限，admin 拥有全部权限
+func (k *APIKey) Allows(scope Scope) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthConfig 认证配置
+//
+//	{
+//	  "keys": [
+//	    {"name": "agent", "key": "xxx", "scopes": ["read", "publish"], "account": "alice"}
+//	  ]
+//	}
+type AuthConfig struct {
+	Keys []APIKey `json:"keys"`
+}
+
+// LoadAuthConfig 加载认证配置：path 为空时读取 XHS_MCP_AUTH_FILE 指定的文件，
+// 另外 XHS_MCP_API_KEY 会追加一个管理员 Key。都没有配置时返回 nil，表示不开启认证
+func LoadAuthConfig(path string) (*AuthConfig, error) {
+	if path == "" {
+		path = os.Getenv(authFileEnv)
+	}
+
+	config := &AuthConfig{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrap(err, "read auth config")
+		}
+		if err := json.Unmarshal(data, config); err != nil {
+			return nil, errors.Wrap(err, "parse auth config")
+		}
+	}
+
+	if key := os.Getenv(apiKeyEnv); key != "" {
+		config.Keys = append(config.Keys, APIKey{Name: "env", Key: key, Scopes: []Scope{ScopeAdmin}})
+	}
+
+	if len(config.Keys) == 0 {
+		return nil, nil
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (c *AuthConfig) validate() error {
+	names := make(map[string]bool, len(c.Keys))
+	for i, key := range c.Keys {
+		if key.Name == "" || key.Key == "" {
+			return errors.Errorf("auth key #%d: name and key are required", i)
+		}
+		if names[key.Name] {
+			return errors.Errorf("auth key %s: duplicate name", key.Name)
+		}
+		names[key.Name] = true
+
+		if len(key.Scopes) == 0 {
+			return errors.Errorf("auth key %s: scopes are required", key.Name)
+		}
+		for _, scope := range key.Scopes {
+			switch scope {
+			case ScopeRead, ScopePublish, ScopeAdmin:
+			default:
+				return errors.Errorf("auth key %s: unknown scope %q", key.Name, scope)
+			}
+		}
+
+		if key.Account != "" && !accountNamePattern.MatchString(key.Account) {
+			return errors.Errorf("auth key %s: invalid account %q", key.Name, key.Account)
+		}
+	}
+	return nil
+}
+
+// Authenticator 校验请求中的 API Key
+type Authenticator struct {
+	keys []APIKey
+}
+
+// NewAuthenticator 创建 Authenticator
+func NewAuthenticator(config *AuthConfig) *Authenticator {
+	return &Authenticator{keys: config.Keys}
+}
+
+// Authenticate 从 Authorization: Bearer 或 X-API-Key 请求头中读取并校验 API Key
+func (a *Authenticator) Authenticate(r *http.Request) (*APIKey, bool) {
+	token := r.Header.Get(apiKeyHeader)
+	if auth := r.Header.Get("Authorization"); token == "" && auth != "" {
+		scheme, value, ok := strings.Cut(auth, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			token = strings.TrimSpace(value)
+		}
+	}
+	if token == "" {
+		return nil, false
+	}
+
+	for i := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(a.keys[i].Key), []byte(token)) == 1 {
+			return &a.keys[i], true
+		}
+	}
+	return nil, false
+}
+
+// EnableAuth 开启 API Key 认证，REST 接口和 MCP 端点都需要携带有效的 Key
+func (s *AppServer) EnableAuth(config *AuthConfig) {
+	s.auth = NewAuthenticator(config)
+}
+
+// authMiddleware REST 接口的认证中间件
+func (s *AppServer) authMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.auth == nil {
+			c.Next()
+			return
+		}
+
+		key, ok := s.auth.Authenticate(c.Request)
+		if !ok {
+			c.Header("WWW-Authenticate", `Bearer realm="xiaohongshu-mcp"`)
+			respondError(c, http.StatusUnauthorized, "UNAUTHORIZED",
+				"缺少或无效的 API Key", nil)
+			c.Abort()
+			return
+		}
+
+		c.Request = c.Request.WithContext(withAPIKey(c.Request.Context(), key))
+		c.Set("account", key.Name)
+		c.Next()
+	}
+}
+
+type apiKeyContextKey struct{}
+
+type accountContextKey struct{}
+
+// withAPIKey 在 context 中记录调用方，同时记录其对应的小红书账号
+func withAPIKey(ctx context.Context, key *APIKey) context.Context {
+	ctx = context.WithValue(ctx, apiKeyContextKey{}, key)
+	return withAccount(ctx, key.Account)
+}
+
+func apiKeyFromContext(ctx context.Context) (*APIKey, bool) {
+	key, ok := ctx.Value(apiKeyContextKey{}).(*APIKey)
+	return key, ok
+}
+
+// withAccount 指定浏览器操作使用的小红书账号
+func withAccount(ctx context.Context, account string) context.Context {
+	return context.WithValue(ctx, accountContextKey{}, account)
+}
+
+// accountFromContext 浏览器操作使用的小红书账号，空字符串表示默认账号
+func accountFromContext(ctx context.Context) string {
+	account, _ := ctx.Value(accountContextKey{}).(string)
+	return account
+}
+
+// checkScope 检查调用方是否拥有指定权限。
+// context 中没有调用方表示未开启认证或是本地 stdio 调用，不做限制
+func checkScope(ctx context.Context, scope Scope) error {
+	key, ok := apiKeyFromContext(ctx)
+	if !ok || key.Allows(scope) {
+		return nil
+	}
+	return errors.Errorf("API key %s lacks the %s scope", key.Name, scope)
+}
+
+// writeUnauthorized 返回 401 并提示使用 Bearer 认证
+func writeUnauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="xiaohongshu-mcp"`)
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
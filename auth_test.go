@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testAuthConfig = &AuthConfig{Keys: []APIKey{
+	{Name: "reader", Key: "read-key", Scopes: []Scope{ScopeRead}, Account: "alice"},
+	{Name: "publisher", Key: "publish-key", Scopes: []Scope{ScopePublish}},
+	{Name: "admin", Key: "admin-key", Scopes: []Scope{ScopeAdmin}},
+}}
+
+func TestLoadAuthConfig(t *testing.T) {
+	t.Setenv(authFileEnv, "")
+	t.Setenv(apiKeyEnv, "")
+
+	// 没有配置时不开启认证
+	config, err := LoadAuthConfig("")
+	require.NoError(t, err)
+	assert.Nil(t, config)
+
+	path := filepath.Join(t.TempDir(), "auth.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"keys":[{"name":"agent","key":"k1","scopes":["read"],"account":"alice"}]}`), 0600))
+
+	t.Setenv(authFileEnv, path)
+	t.Setenv(apiKeyEnv, "env-key")
+	config, err = LoadAuthConfig("")
+	require.NoError(t, err)
+	require.Len(t, config.Keys, 2)
+	assert.Equal(t, "alice", config.Keys[0].Account)
+	assert.Equal(t, APIKey{Name: "env", Key: "env-key", Scopes: []Scope{ScopeAdmin}}, config.Keys[1])
+
+	invalid := map[string]string{
+		"unknown scope":   `{"keys":[{"name":"a","key":"k","scopes":["write"]}]}`,
+		"missing scopes":  `{"keys":[{"name":"a","key":"k"}]}`,
+		"duplicate name":  `{"keys":[{"name":"a","key":"k1","scopes":["read"]},{"name":"a","key":"k2","scopes":["read"]}]}`,
+		"invalid account": `{"keys":[{"name":"a","key":"k","scopes":["read"],"account":"../x"}]}`,
+	}
+	t.Setenv(apiKeyEnv, "")
+	for name, content := range invalid {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		_, err := LoadAuthConfig(path)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	auth := NewAuthenticator(testAuthConfig)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	_, ok := auth.Authenticate(req)
+	assert.False(t, ok)
+
+	req.Header.Set("Authorization", "Bearer read-key")
+	key, ok := auth.Authenticate(req)
+	require.True(t, ok)
+	assert.Equal(t, "reader", key.Name)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(apiKeyHeader, "admin-key")
+	key, ok = auth.Authenticate(req)
+	require.True(t, ok)
+	assert.True(t, key.Allows(ScopePublish))
+
+	req.Header.Set(apiKeyHeader, "wrong")
+	_, ok = auth.Authenticate(req)
+	assert.False(t, ok)
+}
+
+func TestRESTAuth(t *testing.T) {
+	appServer := NewAppServer(NewXiaohongshuService())
+	appServer.EnableAuth(testAuthConfig)
+	router := setupRoutes(appServer)
+
+	request := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// 健康检查不需要认证
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/health", "", "").Code)
+
+	recorder := request(http.MethodGet, "/api/v1/feeds/search", "", "")
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	assert.NotEmpty(t, recorder.Header().Get("WWW-Authenticate"))
+
+	// 权限检查先于参数校验
+	recorder = request(http.MethodPost, "/api/v1/publish", "read-key", `{"title":"标题"}`)
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "FORBIDDEN")
+
+	assert.Equal(t, http.StatusForbidden, request(http.MethodGet, "/api/v1/login/qrcode", "publish-key", "").Code)
+
+	// 通过认证后进入参数校验
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodGet, "/api/v1/feeds/search", "read-key", "").Code)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/api/v1/publish", "publish-key", `{"title":"标题"}`).Code)
+}
+
+func TestStreamableHTTPAuth(t *testing.T) {
+	appServer := NewAppServer(NewXiaohongshuService())
+	appServer.EnableAuth(testAuthConfig)
+	server := httptest.NewServer(appServer.StreamableHTTPHandler())
+	t.Cleanup(server.Close)
+
+	post := func(key, sessionID, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		if sessionID != "" {
+			req.Header.Set(mcpSessionHeader, sessionID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+	decode := func(resp *http.Response) *JSONRPCResponse {
+		var response JSONRPCResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+		return &response
+	}
+
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`
+	resp := post("", "", initialize)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("WWW-Authenticate"))
+
+	resp = post("publish-key", "", initialize)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	sessionID := resp.Header.Get(mcpSessionHeader)
+	require.NotEmpty(t, sessionID)
+
+	// 会话只能由创建它的 Key 使用
+	resp = post("read-key", sessionID, `{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// 只列出有权限调用的工具
+	response := decode(post("publish-key", sessionID, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`))
+	require.Nil(t, response.Error)
+	var names []string
+	for _, tool := range response.Result.(map[string]any)["tools"].([]any) {
+		names = append(names, tool.(map[string]any)["name"].(string))
+	}
+	assert.ElementsMatch(t, []string{"publish_content", "publish_longtext"}, names)
+
+	response = decode(post("publish-key", sessionID, `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"search_feeds","arguments":{"keyword":"咖啡"}}}`))
+	require.NotNil(t, response.Error)
+	assert.Equal(t, errCodeForbidden, response.Error.Code)
+
+	response = decode(post("publish-key", sessionID, `{"jsonrpc":"2.0","id":4,"method":"resources/read","params":{"uri":"xhs://feeds"}}`))
+	require.NotNil(t, response.Error)
+	assert.Equal(t, errCodeForbidden, response.Error.Code)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
+)
+
+// defaultRiskCooldown 触发风控后暂停账号的时长
+const defaultRiskCooldown = 30 * time.Minute
+
+// AccountPausedError 账号触发风控后处于暂停期，RetryAfter 后恢复
+type AccountPausedError struct {
+	Account    string
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *AccountPausedError) Error() string {
+	return fmt.Sprintf("账号 %s 触发风控已暂停自动化操作（%s），请在 %s 后重试或人工处理后恢复",
+		displayAccount(e.Account), e.Reason, e.RetryAfter.Round(time.Second))
+}
+
+// AccountStatus 账号的熔断状态
+type AccountStatus struct {
+	Account     string     `json:"account" description:"账号名，default 表示默认账号"`
+	Paused      bool       `json:"paused" description:"是否处于暂停期"`
+	Reason      string     `json:"reason,omitempty" description:"最近一次触发风控的原因"`
+	URL         string     `json:"url,omitempty" description:"触发风控的页面"`
+	TrippedAt   *time.Time `json:"tripped_at,omitempty"`
+	PausedUntil *time.Time `json:"paused_until,omitempty"`
+	Trips       int        `json:"trips" description:"累计触发风控的次数"`
+}
+
+type breakerState struct {
+	reason    string
+	url       string
+	trippedAt time.Time
+	until     time.Time
+	trips     int
+}
+
+// CircuitBreaker 账号熔断器：检测到风控页面后，在冷却期内暂停该账号的所有自动化操作
+type CircuitBreaker struct {
+	mu       sync.Mutex
+	cooldown time.Duration
+	accounts map[string]*breakerState
+	now      func() time.Time
+}
+
+// NewCircuitBreaker 创建熔断器
+func NewCircuitBreaker(cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		cooldown: cooldown,
+		accounts: make(map[string]*breakerState),
+		now:      time.Now,
+	}
+}
+
+// Check 账号处于暂停期时返回 *AccountPausedError
+func (b *CircuitBreaker) Check(account string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state, ok := b.accounts[account]
+	if !ok {
+		return nil
+	}
+
+	now := b.now()
+	if !now.Before(state.until) {
+		return nil
+	}
+	return &AccountPausedError{Account: account, Reason: state.reason, RetryAfter: state.until.Sub(now)}
+}
+
+// Trip 记录账号触发风控，开始冷却
+func (b *CircuitBreaker) Trip(account string, riskErr *xiaohongshu.RiskControlError) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state, ok := b.accounts[account]
+	if !ok {
+		state = &breakerState{}
+		b.accounts[account] = state
+	}
+
+	now := b.now()
+	state.reason = riskErr.Reason
+	state.url = riskErr.URL
+	state.trippedAt = now
+	state.until = now.Add(b.cooldown)
+	state.trips++
+}
+
+// Resume 人工处理验证后提前恢复账号，返回账号此前是否处于暂停期
+func (b *CircuitBreaker) Resume(account string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state, ok := b.accounts[account]
+	if !ok || !b.now().Before(state.until) {
+		return false
+	}
+	state.until = b.now()
+	return true
+}
+
+// Status 所有触发过风控的账号状态，按账号名排序
+func (b *CircuitBreaker) Status() []AccountStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	statuses := make([]AccountStatus, 0, len(b.accounts))
+	for account, state := range b.accounts {
+		trippedAt := state.trippedAt
+		status := AccountStatus{
+			Account:   displayAccount(account),
+			Paused:    now.Before(state.until),
+			Reason:    state.reason,
+			URL:       state.url,
+			TrippedAt: &trippedAt,
+			Trips:     state.trips,
+		}
+		if status.Paused {
+			until := state.until
+			status.PausedUntil = &until
+		}
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Account < statuses[j].Account })
+	return statuses
+}
+
+// displayAccount 默认账号显示为 default
+func displayAccount(account string) string {
+	if account == "" {
+		return "default"
+	}
+	return account
+}
+
+// SetCircuitBreaker 设置账号熔断器，为空表示检测到风控时不暂停账号
+func (s *XiaohongshuService) SetCircuitBreaker(breaker *CircuitBreaker) {
+	s.breaker = breaker
+}
+
+// recordRiskControl 操作因风控失败时暂停账号
+func (s *XiaohongshuService) recordRiskControl(ctx context.Context, err error) {
+	var riskErr *xiaohongshu.RiskControlError
+	if s.breaker == nil || !errors.As(err, &riskErr) {
+		return
+	}
+
+	account := accountFromContext(ctx)
+	s.breaker.Trip(account, riskErr)
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"account": displayAccount(account),
+		"url":     riskErr.URL,
+	}).Errorf("检测到风控页面，暂停账号 %s: %s", s.breaker.cooldown, riskErr.Reason)
+}
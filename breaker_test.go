@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	breaker := NewCircuitBreaker(30 * time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	require.NoError(t, breaker.Check("alice"))
+
+	breaker.Trip("alice", &xiaohongshu.RiskControlError{URL: "https://www.xiaohongshu.com/website-login/captcha", Reason: "出现验证码"})
+
+	var pausedErr *AccountPausedError
+	require.True(t, errors.As(breaker.Check("alice"), &pausedErr))
+	assert.Equal(t, 30*time.Minute, pausedErr.RetryAfter)
+	// 只暂停触发风控的账号
+	require.NoError(t, breaker.Check(""))
+
+	status := breaker.Status()
+	require.Len(t, status, 1)
+	assert.Equal(t, "alice", status[0].Account)
+	assert.True(t, status[0].Paused)
+	assert.Equal(t, 1, status[0].Trips)
+
+	now = now.Add(30 * time.Minute)
+	require.NoError(t, breaker.Check("alice"))
+	assert.False(t, breaker.Status()[0].Paused)
+
+	breaker.Trip("alice", &xiaohongshu.RiskControlError{Reason: "页面提示「安全验证」"})
+	assert.True(t, breaker.Resume("alice"))
+	require.NoError(t, breaker.Check("alice"))
+	assert.False(t, breaker.Resume("alice"))
+	assert.Equal(t, 2, breaker.Status()[0].Trips)
+}
+
+func TestServiceTripsBreakerOnRiskControl(t *testing.T) {
+	service := NewXiaohongshuService()
+	service.SetCircuitBreaker(NewCircuitBreaker(time.Hour))
+
+	ctx := withAccount(context.Background(), "alice")
+	// withBrowserPage 会把风控错误包装为带截图的 BrowserActionError
+	service.recordRiskControl(ctx, &BrowserActionError{Err: &xiaohongshu.RiskControlError{Reason: "出现验证码"}})
+
+	var pausedErr *AccountPausedError
+	require.True(t, errors.As(service.checkPolicy(ctx, ActionBrowse), &pausedErr))
+	assert.Equal(t, "alice", pausedErr.Account)
+	require.NoError(t, service.checkPolicy(context.Background(), ActionBrowse))
+
+	// 其他错误不触发熔断
+	service.recordRiskControl(context.Background(), errors.New("timeout"))
+	require.NoError(t, service.checkPolicy(context.Background(), ActionBrowse))
+}
+
+func TestAccountStatusAPI(t *testing.T) {
+	service := NewXiaohongshuService()
+	service.SetCircuitBreaker(NewCircuitBreaker(time.Hour))
+	service.breaker.Trip("alice", &xiaohongshu.RiskControlError{Reason: "出现验证码"})
+	service.breaker.Trip("", &xiaohongshu.RiskControlError{Reason: "出现验证码"})
+
+	appServer := NewAppServer(service)
+	appServer.EnableAuth(testAuthConfig)
+	router := setupRoutes(appServer)
+
+	request := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+key)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// reader 对应 alice 账号，只能看到自己的状态
+	recorder := request(http.MethodGet, "/api/v1/accounts/status", "read-key", "")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var response struct {
+		Data AccountStatusResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	require.Len(t, response.Data.Accounts, 1)
+	assert.Equal(t, "alice", response.Data.Accounts[0].Account)
+	assert.Equal(t, 3600, response.Data.CooldownSeconds)
+
+	// 暂停期内的操作返回 503
+	recorder = request(http.MethodGet, "/api/v1/feeds/list", "read-key", "")
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "ACCOUNT_PAUSED")
+	assert.Equal(t, "3600", recorder.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPost, "/api/v1/accounts/resume", "read-key", `{"account":"alice"}`).Code)
+
+	recorder = request(http.MethodPost, "/api/v1/accounts/resume", "admin-key", `{"account":"alice"}`)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"resumed":true`)
+	require.NoError(t, service.breaker.Check("alice"))
+
+	recorder = request(http.MethodGet, "/api/v1/accounts/status", "admin-key", "")
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Len(t, response.Data.Accounts, 2)
+}
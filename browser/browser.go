@@ -1,27 +1,214 @@
 package browser
 
 import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/launcher"
+	"github.com/go-rod/rod/lib/launcher/flags"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/go-rod/stealth"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
-	"github.com/xpzouying/headless_browser"
 	"github.com/xpzouying/xiaohongshu-mcp/cookies"
 )
 
-func NewBrowser(headless bool) *headless_browser.Browser {
+// Browser 开启了反检测的浏览器，新页面按启动配置设置 User-Agent、视口、语言和时区
+type Browser struct {
+	browser  *rod.Browser
+	launcher *launcher.Launcher
+	config   Config
+	// unlock 释放持久化的浏览器数据目录
+	unlock func()
+}
+
+// NewBrowser 使用默认账号的 cookies 和启动配置创建浏览器，启动失败时 panic，与 rod 的 Must* 方法一致
+func NewBrowser(headless bool) *Browser {
+	return NewBrowserWithCookies(headless, cookies.GetCookiesFilePath())
+}
+
+// NewBrowserWithCookies 使用指定的 cookies 文件和默认账号的启动配置创建浏览器，启动失败时 panic。
+func NewBrowserWithCookies(headless bool, cookiePath string) *Browser {
+	b, err := newBrowser(context.Background(), headless, cookiePath, ConfigFor(""))
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// NewAccountBrowser 使用账号的 cookies 和启动配置创建浏览器，用于多账号场景。
+// 账号的浏览器数据目录正在被其他浏览器使用时等待其释放，ctx 结束时不再等待并返回错误
+func NewAccountBrowser(ctx context.Context, headless bool, account string) (*Browser, error) {
+	return newBrowser(ctx, headless, cookies.GetAccountCookiesFilePath(account), ConfigFor(account))
+}
+
+func newBrowser(ctx context.Context, headless bool, cookiePath string, config Config) (*Browser, error) {
+	if config.UserAgent == "" {
+		config.UserAgent = defaultUserAgent
+	}
 
-	opts := []headless_browser.Option{
-		headless_browser.WithHeadless(headless),
+	unlock, err := lockUserDataDir(ctx, config.UserDataDir)
+	if err != nil {
+		return nil, err
 	}
+	launched := false
+	defer func() {
+		if !launched {
+			unlock()
+		}
+	}()
+
+	l := newLauncher(headless, config)
+	controlURL, err := l.Context(ctx).Launch()
+	if err != nil {
+		l.Kill()
+		return nil, errors.Wrap(err, "launch browser")
+	}
+	b := rod.New().ControlURL(controlURL).Trace(true)
+	if err := b.Connect(); err != nil {
+		l.Kill()
+		return nil, errors.Wrap(err, "connect browser")
+	}
+	launched = true
 
 	// 加载 cookies
-	cookiePath := cookies.GetCookiesFilePath()
 	cookieLoader := cookies.NewLoadCookie(cookiePath)
 
 	if data, err := cookieLoader.LoadCookies(); err == nil {
-		opts = append(opts, headless_browser.WithCookies(string(data)))
-		logrus.Debugf("loaded cookies from filesuccessfully")
+		var cks []*proto.NetworkCookie
+		if err := json.Unmarshal(data, &cks); err != nil {
+			logrus.Warnf("failed to unmarshal cookies: %v", err)
+		} else {
+			if err := b.SetCookies(proto.CookiesToParams(cks)); err != nil {
+				logrus.Warnf("failed to set cookies: %v", err)
+			} else {
+				logrus.Debugf("loaded cookies from filesuccessfully")
+			}
+		}
 	} else {
 		logrus.Warnf("failed to load cookies: %v", err)
 	}
 
-	return headless_browser.New(opts...)
+	return &Browser{browser: b, launcher: l, config: config, unlock: unlock}, nil
+}
+
+func newLauncher(headless bool, config Config) *launcher.Launcher {
+	l := launcher.New().
+		Headless(headless).
+		Set("--no-sandbox").
+		Set("user-agent", config.UserAgent)
+
+	if config.Bin != "" {
+		l = l.Bin(config.Bin)
+	}
+	if config.Proxy != "" {
+		l = l.Proxy(config.Proxy)
+	}
+	if config.Locale != "" {
+		l = l.Set("lang", config.Locale)
+	}
+	if config.Timezone != "" {
+		l = l.Env(append(os.Environ(), "TZ="+config.Timezone)...)
+	}
+	if config.Viewport != nil {
+		l = l.Set("window-size", strconv.Itoa(config.Viewport.Width)+","+strconv.Itoa(config.Viewport.Height))
+	}
+	if config.UserDataDir != "" {
+		l = l.UserDataDir(config.UserDataDir)
+	}
+	for _, flag := range config.Flags {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(flag, "-"), "=")
+		if hasValue {
+			l = l.Set(flags.Flag(name), value)
+		} else {
+			l = l.Set(flags.Flag(name))
+		}
+	}
+	return l
+}
+
+// Close 关闭浏览器。临时的浏览器数据目录会被删除，持久化的目录等浏览器退出后释放
+func (b *Browser) Close() {
+	defer b.unlock()
+
+	b.browser.MustClose()
+	if b.config.UserDataDir == "" {
+		b.launcher.Cleanup()
+		return
+	}
+	waitProfileUnlocked(b.config.UserDataDir)
+}
+
+// NewPage 创建开启反检测的页面
+func (b *Browser) NewPage() *rod.Page {
+	page := stealth.MustPage(b.browser)
+
+	if b.config.Locale != "" {
+		page.MustSetUserAgent(&proto.NetworkSetUserAgentOverride{
+			UserAgent:      b.config.UserAgent,
+			AcceptLanguage: b.config.Locale,
+		})
+		if err := (proto.EmulationSetLocaleOverride{Locale: b.config.Locale}).Call(page); err != nil {
+			logrus.Warnf("failed to set locale: %v", err)
+		}
+	}
+	if b.config.Timezone != "" {
+		if err := (proto.EmulationSetTimezoneOverride{TimezoneID: b.config.Timezone}).Call(page); err != nil {
+			logrus.Warnf("failed to set timezone: %v", err)
+		}
+	}
+	if b.config.Viewport != nil {
+		page.MustSetViewport(b.config.Viewport.Width, b.config.Viewport.Height, 1, false)
+	}
+	return page
+}
+
+// userDataDirLocks 正在使用的浏览器数据目录，值为容量 1 的 chan struct{}。
+// 同一目录同时只能被一个浏览器使用，同一账号的并发操作在这里排队
+var userDataDirLocks sync.Map
+
+// lockUserDataDir 等待独占数据目录，返回释放函数。ctx 结束时放弃等待
+func lockUserDataDir(ctx context.Context, dir string) (func(), error) {
+	if dir == "" {
+		return func() {}, nil
+	}
+	lock, _ := userDataDirLocks.LoadOrStore(filepath.Clean(dir), make(chan struct{}, 1))
+	sem := lock.(chan struct{})
+
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, errors.Wrapf(ctx.Err(), "wait for user data dir %s", dir)
+	}
+}
+
+// profileUnlockTimeout 等待浏览器退出并释放数据目录的最长时间
+const profileUnlockTimeout = 10 * time.Second
+
+// waitProfileUnlocked 等待 Chrome 删除数据目录中的单例锁，即浏览器已经退出，
+// 避免下一次启动时新进程把请求交给还未退出的旧进程
+func waitProfileUnlocked(dir string) {
+	deadline := time.Now().Add(profileUnlockTimeout)
+	for time.Now().Before(deadline) {
+		if !profileLocked(dir) {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	logrus.Warnf("browser did not release user data dir %s in %s", dir, profileUnlockTimeout)
+}
+
+// profileLocked 数据目录是否被运行中的 Chrome 锁定。Linux/macOS 上 Chrome 退出时删除 SingletonLock，
+// Windows 上锁文件退出后仍然存在，不做等待
+func profileLocked(dir string) bool {
+	_, err := os.Lstat(filepath.Join(dir, "SingletonLock"))
+	return err == nil
 }
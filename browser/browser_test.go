@@ -0,0 +1,33 @@
+package browser
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockUserDataDir(t *testing.T) {
+	dir := t.TempDir()
+
+	unlock, err := lockUserDataDir(context.Background(), dir)
+	require.NoError(t, err)
+
+	// 目录被占用时等待，ctx 结束后放弃
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = lockUserDataDir(ctx, dir+"/")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// 其他目录不受影响
+	unlockOther, err := lockUserDataDir(context.Background(), t.TempDir())
+	require.NoError(t, err)
+	unlockOther()
+
+	unlock()
+	unlock, err = lockUserDataDir(context.Background(), dir)
+	require.NoError(t, err)
+	unlock()
+}
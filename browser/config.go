@@ -0,0 +1,166 @@
+package browser
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// configFileEnv 未通过 -browser-config 指定时读取的配置文件环境变量
+const configFileEnv = "XHS_MCP_BROWSER_CONFIG"
+
+// defaultUserAgent 未配置时使用的 User-Agent
+const defaultUserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+
+// Viewport 页面视口大小
+type Viewport struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// Config 浏览器启动配置，未设置的字段使用默认值
+type Config struct {
+	// Bin Chromium/Chrome 可执行文件路径，为空时自动查找，找不到时下载
+	Bin string `json:"bin,omitempty"`
+	// Proxy 代理地址，如 http://127.0.0.1:8080 或 socks5://127.0.0.1:1080。
+	// Chrome 不支持在代理地址中携带用户名和密码
+	Proxy     string    `json:"proxy,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Viewport  *Viewport `json:"viewport,omitempty"`
+	// Locale 浏览器语言，如 zh-CN，同时用于 Accept-Language
+	Locale string `json:"locale,omitempty"`
+	// Timezone 时区，如 Asia/Shanghai
+	Timezone string `json:"timezone,omitempty"`
+	// Flags 额外的启动参数，如 --disable-gpu 或 --window-position=0,0
+	Flags []string `json:"flags,omitempty"`
+	// UserDataDir 持久化的浏览器数据目录，为空时每次使用临时目录并在关闭后删除
+	UserDataDir string `json:"user_data_dir,omitempty"`
+}
+
+// merge 用 override 中设置了的字段覆盖 c，Flags 追加在后面
+func (c Config) merge(override Config) Config {
+	if override.Bin != "" {
+		c.Bin = override.Bin
+	}
+	if override.Proxy != "" {
+		c.Proxy = override.Proxy
+	}
+	if override.UserAgent != "" {
+		c.UserAgent = override.UserAgent
+	}
+	if override.Viewport != nil {
+		c.Viewport = override.Viewport
+	}
+	if override.Locale != "" {
+		c.Locale = override.Locale
+	}
+	if override.Timezone != "" {
+		c.Timezone = override.Timezone
+	}
+	if override.UserDataDir != "" {
+		c.UserDataDir = override.UserDataDir
+	}
+	c.Flags = append(append([]string(nil), c.Flags...), override.Flags...)
+	return c
+}
+
+func (c Config) validate() error {
+	if c.Proxy != "" {
+		u, err := url.Parse(c.Proxy)
+		if err != nil {
+			return errors.Wrap(err, "invalid proxy")
+		}
+		switch u.Scheme {
+		case "http", "https", "socks4", "socks5":
+		default:
+			return errors.Errorf("invalid proxy %q: scheme must be http, https, socks4 or socks5", c.Proxy)
+		}
+		if u.User != nil {
+			return errors.Errorf("invalid proxy %q: chrome does not support proxy credentials, use an IP allowlist or a local forwarding proxy", u.Redacted())
+		}
+	}
+	if c.Viewport != nil && (c.Viewport.Width <= 0 || c.Viewport.Height <= 0) {
+		return errors.Errorf("invalid viewport %dx%d", c.Viewport.Width, c.Viewport.Height)
+	}
+	for _, flag := range c.Flags {
+		if strings.TrimLeft(flag, "-") == "" {
+			return errors.Errorf("invalid flag %q", flag)
+		}
+	}
+	return nil
+}
+
+// ConfigFile 启动配置文件，顶层为所有账号的默认配置，accounts 中按账号覆盖。
+// 默认配置的 user_data_dir 是父目录，每个账号使用其中以账号名命名的子目录（默认账号为 default），
+// 保证不同账号的浏览器数据互相隔离
+type ConfigFile struct {
+	Config
+	Accounts map[string]Config `json:"accounts,omitempty"`
+}
+
+// LoadConfigFile 加载启动配置：path 为空时读取 XHS_MCP_BROWSER_CONFIG 指定的文件，都没有时返回空配置
+func LoadConfigFile(path string) (*ConfigFile, error) {
+	if path == "" {
+		path = os.Getenv(configFileEnv)
+	}
+	if path == "" {
+		return &ConfigFile{}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read browser config")
+	}
+
+	file := &ConfigFile{}
+	if err := json.Unmarshal(data, file); err != nil {
+		return nil, errors.Wrap(err, "parse browser config")
+	}
+
+	if err := file.Config.validate(); err != nil {
+		return nil, err
+	}
+	for account := range file.Accounts {
+		if err := file.ForAccount(account).validate(); err != nil {
+			return nil, errors.Wrapf(err, "account %s", account)
+		}
+	}
+	return file, nil
+}
+
+// ForAccount 账号的启动配置，account 为空表示默认账号
+func (f *ConfigFile) ForAccount(account string) Config {
+	config := f.Config
+	if config.UserDataDir != "" {
+		name := account
+		if name == "" {
+			name = "default"
+		}
+		config.UserDataDir = filepath.Join(config.UserDataDir, name)
+	}
+	return config.merge(f.Accounts[account])
+}
+
+var (
+	configMu   sync.RWMutex
+	configFile = &ConfigFile{}
+)
+
+// SetConfigFile 设置之后启动的浏览器使用的配置
+func SetConfigFile(file *ConfigFile) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	configFile = file
+}
+
+// ConfigFor 账号当前的启动配置
+func ConfigFor(account string) Config {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return configFile.ForAccount(account)
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
+)
+
+// RunCanary 巡检页面选择器和 __INITIAL_STATE__ 数据路径，不会提交任何内容
+func (s *XiaohongshuService) RunCanary(ctx context.Context) (*xiaohongshu.CanaryReport, error) {
+	if err := s.checkPolicy(ctx, ActionBrowse); err != nil {
+		return nil, err
+	}
+
+	var report *xiaohongshu.CanaryReport
+	err := s.withBrowserPage(ctx, func(ctx context.Context, driver xiaohongshu.Driver) error {
+		var err error
+		report, err = xiaohongshu.NewCanaryAction(driver).Run(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// canaryFunc 执行一次巡检
+type canaryFunc func(ctx context.Context) (*xiaohongshu.CanaryReport, error)
+
+// CanaryStatus 最近一次巡检的状态
+type CanaryStatus struct {
+	Healthy   bool                      `json:"healthy"`
+	LastRunAt *time.Time                `json:"last_run_at,omitempty"`
+	Error     string                    `json:"error,omitempty" description:"巡检无法完成的原因，如遇到风控页面"`
+	Failed    []xiaohongshu.CanaryCheck `json:"failed,omitempty"`
+	Report    *xiaohongshu.CanaryReport `json:"-"`
+}
+
+// CanaryRunner 定期巡检页面，记录最近一次结果供健康检查和监控指标使用
+type CanaryRunner struct {
+	mu       sync.Mutex
+	run      canaryFunc
+	interval time.Duration
+	report   *xiaohongshu.CanaryReport
+	err      error
+	lastRun  time.Time
+	now      func() time.Time
+}
+
+// NewCanaryRunner 创建巡检器
+func NewCanaryRunner(run canaryFunc, interval time.Duration) *CanaryRunner {
+	return &CanaryRunner{
+		run:      run,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// Run 立即巡检一次，之后每隔 interval 巡检，直到 ctx 取消
+func (r *CanaryRunner) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.runOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *CanaryRunner) runOnce(ctx context.Context) {
+	report, err := r.run(ctx)
+	if ctx.Err() != nil {
+		return
+	}
+
+	switch {
+	case err != nil:
+		logrus.WithError(err).Warn("页面巡检失败")
+	case !report.Healthy():
+		for _, check := range report.Failed() {
+			logrus.WithFields(logrus.Fields{
+				"page":   check.Page,
+				"kind":   check.Kind,
+				"target": check.Target,
+			}).Warnf("页面巡检未通过: %s", check.Detail)
+		}
+	default:
+		logrus.Infof("页面巡检通过，共 %d 项", len(report.Checks))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report, r.err, r.lastRun = report, err, r.now()
+}
+
+// Status 最近一次巡检的状态，还没有巡检过时视为健康
+func (r *CanaryRunner) Status() CanaryStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := CanaryStatus{Healthy: r.err == nil, Report: r.report}
+	if !r.lastRun.IsZero() {
+		lastRun := r.lastRun
+		status.LastRunAt = &lastRun
+	}
+	if r.err != nil {
+		status.Error = r.err.Error()
+	}
+	if r.report != nil {
+		status.Failed = r.report.Failed()
+		status.Healthy = status.Healthy && r.report.Healthy()
+	}
+	return status
+}
+
+// EnableCanary 开启定期巡检，服务启动后开始执行
+func (s *AppServer) EnableCanary(interval time.Duration) {
+	s.canary = NewCanaryRunner(s.xiaohongshuService.RunCanary, interval)
+}
+
+// healthHandler 健康检查。巡检未通过时状态为 degraded，但仍返回 200，避免负载均衡摘除服务
+func (s *AppServer) healthHandler(c *gin.Context) {
+	data := map[string]any{
+		"status":    "healthy",
+		"service":   "xiaohongshu-mcp",
+		"timestamp": time.Now().Format(time.RFC3339),
+		"selectors": xiaohongshu.GetSelectorReport().Version,
+	}
+	message := "服务正常"
+
+	if s.canary != nil {
+		status := s.canary.Status()
+		data["canary"] = status
+		if !status.Healthy {
+			data["status"] = "degraded"
+			message = "页面巡检未通过，小红书页面可能已改版"
+		}
+	}
+
+	respondSuccess(c, data, message)
+}
+
+// metricsHandler 以 Prometheus 文本格式输出巡检和页面数据结构校验指标
+func (s *AppServer) metricsHandler(c *gin.Context) {
+	var b strings.Builder
+
+	if s.canary != nil {
+		status := s.canary.Status()
+
+		b.WriteString("# HELP xhs_mcp_canary_healthy 最近一次页面巡检是否全部通过\n")
+		b.WriteString("# TYPE xhs_mcp_canary_healthy gauge\n")
+		fmt.Fprintf(&b, "xhs_mcp_canary_healthy %d\n", boolGauge(status.Healthy))
+
+		if status.LastRunAt != nil {
+			b.WriteString("# HELP xhs_mcp_canary_last_run_timestamp_seconds 最近一次页面巡检的时间\n")
+			b.WriteString("# TYPE xhs_mcp_canary_last_run_timestamp_seconds gauge\n")
+			fmt.Fprintf(&b, "xhs_mcp_canary_last_run_timestamp_seconds %d\n", status.LastRunAt.Unix())
+		}
+
+		if status.Report != nil {
+			checks := append([]xiaohongshu.CanaryCheck(nil), status.Report.Checks...)
+			sort.SliceStable(checks, func(i, j int) bool {
+				return checks[i].Page+checks[i].Kind+checks[i].Target < checks[j].Page+checks[j].Kind+checks[j].Target
+			})
+
+			b.WriteString("# HELP xhs_mcp_canary_check 页面巡检检查项是否通过\n")
+			b.WriteString("# TYPE xhs_mcp_canary_check gauge\n")
+			for _, check := range checks {
+				fmt.Fprintf(&b, "xhs_mcp_canary_check{page=%q,kind=%q,target=%q} %d\n",
+					check.Page, check.Kind, check.Target, boolGauge(check.OK))
+			}
+
+			b.WriteString("# HELP xhs_mcp_canary_selector_fallback 选择器命中的规则序号，大于 0 表示主规则已失效\n")
+			b.WriteString("# TYPE xhs_mcp_canary_selector_fallback gauge\n")
+			for _, check := range checks {
+				if check.Kind == xiaohongshu.CanaryKindSelector && check.OK {
+					fmt.Fprintf(&b, "xhs_mcp_canary_selector_fallback{page=%q,target=%q} %d\n",
+						check.Page, check.Target, check.Fallback)
+				}
+			}
+		}
+	}
+
+	if schemas := xiaohongshu.GetSchemaStatus(); len(schemas) > 0 {
+		b.WriteString("# HELP xhs_mcp_state_schema_checks_total 页面数据结构校验次数\n")
+		b.WriteString("# TYPE xhs_mcp_state_schema_checks_total counter\n")
+		for _, status := range schemas {
+			fmt.Fprintf(&b, "xhs_mcp_state_schema_checks_total{schema=%q} %d\n", status.Schema, status.Checks)
+		}
+
+		b.WriteString("# HELP xhs_mcp_state_schema_drift_total 页面数据结构与预期不符的次数，kind 为 missing、type 或 unknown\n")
+		b.WriteString("# TYPE xhs_mcp_state_schema_drift_total counter\n")
+		for _, status := range schemas {
+			for _, kind := range []string{xiaohongshu.DriftMissing, xiaohongshu.DriftType, xiaohongshu.DriftUnknown} {
+				fmt.Fprintf(&b, "xhs_mcp_state_schema_drift_total{schema=%q,kind=%q} %d\n", status.Schema, kind, status.Drifted[kind])
+			}
+		}
+	}
+
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
+
+func boolGauge(ok bool) int {
+	if ok {
+		return 1
+	}
+	return 0
+}
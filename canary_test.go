@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
+)
+
+func TestCanaryRunnerStatus(t *testing.T) {
+	report := &xiaohongshu.CanaryReport{Checks: []xiaohongshu.CanaryCheck{
+		{Page: "explore", Kind: xiaohongshu.CanaryKindState, Target: "feed.feeds._value", OK: true},
+		{Page: "publish", Kind: xiaohongshu.CanaryKindSelector, Target: "publish.image_tab", OK: true, Fallback: 1},
+	}}
+	var runErr error
+	runner := NewCanaryRunner(func(ctx context.Context) (*xiaohongshu.CanaryReport, error) {
+		return report, runErr
+	}, time.Hour)
+
+	// 还没有巡检过时视为健康
+	status := runner.Status()
+	assert.True(t, status.Healthy)
+	assert.Nil(t, status.LastRunAt)
+
+	runner.runOnce(context.Background())
+	status = runner.Status()
+	assert.True(t, status.Healthy)
+	assert.NotNil(t, status.LastRunAt)
+
+	report = &xiaohongshu.CanaryReport{Checks: []xiaohongshu.CanaryCheck{
+		{Page: "search", Kind: xiaohongshu.CanaryKindState, Target: "search.feeds._value", Detail: "__INITIAL_STATE__ 中没有该路径"},
+	}}
+	runner.runOnce(context.Background())
+	status = runner.Status()
+	assert.False(t, status.Healthy)
+	require.Len(t, status.Failed, 1)
+	assert.Equal(t, "search.feeds._value", status.Failed[0].Target)
+
+	report, runErr = nil, &xiaohongshu.RiskControlError{Reason: "安全验证"}
+	runner.runOnce(context.Background())
+	status = runner.Status()
+	assert.False(t, status.Healthy)
+	assert.NotEmpty(t, status.Error)
+}
+
+func TestHealthAndMetrics(t *testing.T) {
+	appServer := NewAppServer(NewXiaohongshuService())
+	appServer.EnableAuth(testAuthConfig)
+	appServer.EnableCanary(time.Hour)
+	appServer.canary.run = func(ctx context.Context) (*xiaohongshu.CanaryReport, error) {
+		return &xiaohongshu.CanaryReport{Checks: []xiaohongshu.CanaryCheck{
+			{Page: "publish", Kind: xiaohongshu.CanaryKindSelector, Target: "publish.image_tab", OK: true, Fallback: 1},
+			{Page: "search", Kind: xiaohongshu.CanaryKindState, Target: "search.feeds._value"},
+		}}, nil
+	}
+	appServer.canary.runOnce(context.Background())
+	router := setupRoutes(appServer)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+	// 巡检未通过时仍返回 200，只在状态中体现
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var health struct {
+		Data struct {
+			Status string       `json:"status"`
+			Canary CanaryStatus `json:"canary"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &health))
+	assert.Equal(t, "degraded", health.Data.Status)
+	require.Len(t, health.Data.Canary.Failed, 1)
+	assert.Equal(t, "search", health.Data.Canary.Failed[0].Page)
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	body := recorder.Body.String()
+	assert.Contains(t, body, "xhs_mcp_canary_healthy 0\n")
+	assert.Contains(t, body, `xhs_mcp_canary_check{page="publish",kind="selector",target="publish.image_tab"} 1`)
+	assert.Contains(t, body, `xhs_mcp_canary_check{page="search",kind="state",target="search.feeds._value"} 0`)
+	assert.Contains(t, body, `xhs_mcp_canary_selector_fallback{page="publish",target="publish.image_tab"} 1`)
+	assert.Contains(t, body, "xhs_mcp_canary_last_run_timestamp_seconds ")
+}
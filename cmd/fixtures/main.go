@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xpzouying/xiaohongshu-mcp/browser"
+	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
+)
+
+// 录制首页、搜索页、笔记详情页和用户主页，或者在本地回放录制的页面：
+//
+//	go run ./cmd/fixtures -dir xiaohongshu/testdata/fixtures
+//	go run ./cmd/fixtures -dir xiaohongshu/testdata/fixtures -replay :18070
+func main() {
+	var (
+		dir      string
+		account  string
+		keyword  string
+		headless bool
+		replay   string
+		config   string
+	)
+	flag.StringVar(&dir, "dir", "fixtures", "录制页面的保存目录")
+	flag.StringVar(&account, "account", "", "录制使用的账号名，为空时使用默认账号")
+	flag.StringVar(&keyword, "keyword", "穿搭", "录制搜索页使用的关键词")
+	flag.BoolVar(&headless, "headless", true, "是否无头模式")
+	flag.StringVar(&replay, "replay", "", "回放模式：在该地址上提供录制的页面，如 :18070，为空时录制")
+	flag.StringVar(&config, "browser-config", "", "浏览器启动配置文件（JSON），与服务使用同一份配置")
+	flag.Parse()
+
+	if replay != "" {
+		handler, err := xiaohongshu.NewReplayHandler(dir)
+		if err != nil {
+			logrus.Fatalf("failed to load fixtures: %v", err)
+		}
+		logrus.Infof("回放 %s 中的页面: %s", dir, replay)
+		logrus.Fatal(http.ListenAndServe(replay, handler))
+	}
+
+	browserConfig, err := browser.LoadConfigFile(config)
+	if err != nil {
+		logrus.Fatalf("failed to load browser config: %v", err)
+	}
+	browser.SetConfigFile(browserConfig)
+
+	b, err := browser.NewAccountBrowser(context.Background(), headless, account)
+	if err != nil {
+		logrus.Fatalf("failed to launch browser: %v", err)
+	}
+	defer b.Close()
+
+	page := b.NewPage()
+	defer page.Close()
+
+	if err := record(context.Background(), xiaohongshu.NewRecordingDriver(xiaohongshu.NewRodDriver(page), dir), keyword); err != nil {
+		logrus.Fatalf("录制失败: %v", err)
+	}
+	logrus.Infof("录制完成，页面保存在 %s", dir)
+}
+
+// record 依次打开首页、搜索页，以及首页第一条笔记的详情页和作者主页
+func record(ctx context.Context, driver xiaohongshu.Driver, keyword string) error {
+	action, err := xiaohongshu.NewFeedsListAction(ctx, driver)
+	if err != nil {
+		return err
+	}
+	feeds, err := action.GetFeedsList(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := xiaohongshu.NewSearchAction(driver).Search(ctx, keyword); err != nil {
+		return err
+	}
+
+	if len(feeds) == 0 {
+		logrus.Warn("首页没有笔记，跳过详情页和用户主页")
+		return nil
+	}
+	feed := feeds[0]
+
+	if _, err := xiaohongshu.NewFeedDetailAction(driver).GetFeedDetail(ctx, feed.ID, feed.XsecToken); err != nil {
+		return err
+	}
+
+	user := feed.NoteCard.User
+	_, err = xiaohongshu.NewUserProfileAction(driver).UserProfile(ctx, user.UserID, user.XsecToken)
+	return err
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"flag"
 
 	"github.com/go-rod/rod"
 	"github.com/sirupsen/logrus"
@@ -12,15 +13,30 @@ import (
 )
 
 func main() {
+	var account, config string
+	flag.StringVar(&account, "account", "", "登录的账号名，与认证配置中 API Key 的 account 对应，为空时使用默认账号")
+	flag.StringVar(&config, "browser-config", "", "浏览器启动配置文件（JSON），登录使用与服务相同的代理和浏览器数据目录")
+	flag.Parse()
+
+	browserConfig, err := browser.LoadConfigFile(config)
+	if err != nil {
+		logrus.Fatalf("failed to load browser config: %v", err)
+	}
+	browser.SetConfigFile(browserConfig)
+
+	cookiePath := cookies.GetAccountCookiesFilePath(account)
 
 	// 登录的时候，需要界面，所以不能无头模式
-	b := browser.NewBrowser(false)
+	b, err := browser.NewAccountBrowser(context.Background(), false, account)
+	if err != nil {
+		logrus.Fatalf("failed to launch browser: %v", err)
+	}
 	defer b.Close()
 
 	page := b.NewPage()
 	defer page.Close()
 
-	action := xiaohongshu.NewLogin(page)
+	action := xiaohongshu.NewLogin(xiaohongshu.NewRodDriver(page))
 
 	status, err := action.CheckLoginStatus(context.Background())
 	if err != nil {
@@ -38,7 +54,7 @@ func main() {
 	if err = action.Login(context.Background()); err != nil {
 		logrus.Fatalf("登录失败: %v", err)
 	} else {
-		if err := saveCookies(page); err != nil {
+		if err := saveCookies(page, cookiePath); err != nil {
 			logrus.Fatalf("failed to save cookies: %v", err)
 		}
 	}
@@ -57,7 +73,7 @@ func main() {
 
 }
 
-func saveCookies(page *rod.Page) error {
+func saveCookies(page *rod.Page, cookiePath string) error {
 	cks, err := page.Browser().GetCookies()
 	if err != nil {
 		return err
@@ -68,6 +84,6 @@ func saveCookies(page *rod.Page) error {
 		return err
 	}
 
-	cookieLoader := cookies.NewLoadCookie(cookies.GetCookiesFilePath())
+	cookieLoader := cookies.NewLoadCookie(cookiePath)
 	return cookieLoader.SaveCookies(data)
 }
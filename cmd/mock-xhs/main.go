@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/mockxhs"
+)
+
+// 本地模拟的创作者中心发布页，发布流程可以在不登录、不真实发布的情况下运行：
+//
+//	go run ./cmd/mock-xhs -addr :18070
+//	go run . -creator-url http://localhost:18070
+//
+// 收到的提交见 GET /api/submissions，运行中可以通过 PUT /api/options 切换模拟的失败。
+func main() {
+	var (
+		addr string
+		opts mockxhs.Options
+	)
+	flag.StringVar(&addr, "addr", ":18070", "监听地址")
+	flag.DurationVar(&opts.UploadDelay, "upload-delay", 0, "模拟每个文件的上传耗时")
+	flag.StringVar(&opts.Reject, "reject", "", "拒绝所有发布，页面弹出该提示")
+	flag.BoolVar(&opts.Captcha, "captcha", false, "打开发布页时显示滑块验证码")
+	flag.Parse()
+
+	logrus.Infof("模拟创作者中心: http://localhost%s%s", addr, mockxhs.PublishPath)
+	logrus.Fatal(http.ListenAndServe(addr, mockxhs.New(opts)))
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// confirmationTokenParam 确认模式下用于确认执行的参数名
+	confirmationTokenParam = "confirmation_token"
+	// defaultConfirmationTTL 确认令牌的有效期
+	defaultConfirmationTTL = 5 * time.Minute
+)
+
+// PendingConfirmation 确认模式下写操作的预览，需要携带 confirmation_token 再次调用才会真正执行
+type PendingConfirmation struct {
+	Status            string    `json:"status" description:"固定为 pending_confirmation"`
+	Tool              string    `json:"tool"`
+	ConfirmationToken string    `json:"confirmation_token" description:"确认令牌，携带相同参数和该令牌再次调用即可执行"`
+	ExpiresAt         time.Time `json:"expires_at"`
+	Preview           any       `json:"preview" description:"将要执行的参数"`
+}
+
+type pendingToolCall struct {
+	tool      string
+	args      string
+	expiresAt time.Time
+}
+
+// ConfirmationStore 保存已签发、尚未使用的确认令牌
+type ConfirmationStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	pending map[string]pendingToolCall
+	now     func() time.Time
+}
+
+// NewConfirmationStore 创建确认令牌存储
+func NewConfirmationStore(ttl time.Duration) *ConfirmationStore {
+	return &ConfirmationStore{
+		ttl:     ttl,
+		pending: make(map[string]pendingToolCall),
+		now:     time.Now,
+	}
+}
+
+// Issue 为一次工具调用签发确认令牌，令牌只对相同的工具和参数有效
+func (c *ConfirmationStore) Issue(tool string, args map[string]any) (*PendingConfirmation, error) {
+	canonical, err := canonicalArguments(args)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, errors.Wrap(err, "generate confirmation token")
+	}
+	token := hex.EncodeToString(buf)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for t, p := range c.pending {
+		if now.After(p.expiresAt) {
+			delete(c.pending, t)
+		}
+	}
+
+	expiresAt := now.Add(c.ttl)
+	c.pending[token] = pendingToolCall{tool: tool, args: canonical, expiresAt: expiresAt}
+
+	return &PendingConfirmation{
+		Status:            "pending_confirmation",
+		Tool:              tool,
+		ConfirmationToken: token,
+		ExpiresAt:         expiresAt,
+	}, nil
+}
+
+// Consume 校验并使用确认令牌，令牌只能使用一次
+func (c *ConfirmationStore) Consume(token, tool string, args map[string]any) error {
+	canonical, err := canonicalArguments(args)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.pending[token]
+	if !ok {
+		return errors.New("确认令牌无效或已使用")
+	}
+	if c.now().After(p.expiresAt) {
+		delete(c.pending, token)
+		return errors.New("确认令牌已过期，请重新调用获取新的令牌")
+	}
+	if p.tool != tool || p.args != canonical {
+		return errors.New("确认令牌与本次调用的工具或参数不一致")
+	}
+
+	delete(c.pending, token)
+	return nil
+}
+
+// canonicalArguments 参数的规范化表示（json.Marshal 对 map 的键排序）
+func canonicalArguments(args map[string]any) (string, error) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid arguments")
+	}
+	return string(data), nil
+}
+
+// EnableConfirmMode 开启确认模式：需要确认的写操作先返回预览和确认令牌，携带令牌再次调用才会执行
+func (s *AppServer) EnableConfirmMode(ttl time.Duration) {
+	s.confirmations = NewConfirmationStore(ttl)
+}
+
+// requiresConfirmation 当前调用是否需要确认
+func (s *AppServer) requiresConfirmation(tool *Tool) bool {
+	return s.confirmations != nil && tool.RequiresConfirmation
+}
+
+// confirmToolCall 确认模式下处理写操作：
+// 没有携带令牌时返回预览，携带令牌时校验令牌，校验通过返回 nil 表示可以执行
+func (s *AppServer) confirmToolCall(tool *Tool, args map[string]any, input any) (*PendingConfirmation, error) {
+	token, _ := args[confirmationTokenParam].(string)
+
+	params := make(map[string]any, len(args))
+	for k, v := range args {
+		if k != confirmationTokenParam {
+			params[k] = v
+		}
+	}
+
+	if token == "" {
+		pending, err := s.confirmations.Issue(tool.Name, params)
+		if err != nil {
+			return nil, err
+		}
+		pending.Preview = input
+		return pending, nil
+	}
+
+	return nil, s.confirmations.Consume(token, tool.Name, params)
+}
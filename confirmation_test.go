@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfirmationStore(t *testing.T) {
+	store := NewConfirmationStore(time.Minute)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	args := map[string]any{"title": "标题", "content": "正文"}
+	pending, err := store.Issue("publish_longtext", args)
+	require.NoError(t, err)
+	require.NotEmpty(t, pending.ConfirmationToken)
+
+	// 参数不一致时不能使用
+	assert.Error(t, store.Consume(pending.ConfirmationToken, "publish_longtext", map[string]any{"title": "改过的标题", "content": "正文"}))
+	assert.Error(t, store.Consume(pending.ConfirmationToken, "publish_content", args))
+
+	require.NoError(t, store.Consume(pending.ConfirmationToken, "publish_longtext", args))
+	// 只能使用一次
+	assert.Error(t, store.Consume(pending.ConfirmationToken, "publish_longtext", args))
+
+	pending, err = store.Issue("publish_longtext", args)
+	require.NoError(t, err)
+	now = now.Add(2 * time.Minute)
+	assert.Error(t, store.Consume(pending.ConfirmationToken, "publish_longtext", args))
+}
+
+func TestConfirmModeToolCall(t *testing.T) {
+	appServer := NewAppServer(NewXiaohongshuService())
+	appServer.EnableConfirmMode(time.Minute)
+
+	list := appServer.processToolsList(context.Background(), &JSONRPCRequest{JSONRPC: "2.0", ID: 1})
+	for _, tool := range list.Result.(map[string]interface{})["tools"].([]map[string]interface{}) {
+		annotations := tool["annotations"].(ToolAnnotations)
+		properties := tool["inputSchema"].(map[string]any)["properties"].(map[string]any)
+		if tool["name"] == "publish_content" {
+			assert.False(t, annotations.ReadOnlyHint)
+			assert.Contains(t, properties, confirmationTokenParam)
+		}
+		if tool["name"] == "search_feeds" {
+			assert.True(t, annotations.ReadOnlyHint)
+			assert.NotContains(t, properties, confirmationTokenParam)
+		}
+	}
+
+	args := map[string]any{"title": "标题", "content": "正文", "images": []any{"a.jpg"}}
+	response := appServer.processJSONRPCRequest(&JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "tools/call",
+		Params:  map[string]any{"name": "publish_content", "arguments": args},
+		ID:      2,
+	}, context.Background())
+	require.Nil(t, response.Error)
+
+	result := response.Result.(*MCPToolResult)
+	pending := result.StructuredContent.(*PendingConfirmation)
+	assert.Equal(t, "pending_confirmation", pending.Status)
+	assert.Equal(t, &PublishRequest{Title: "标题", Content: "正文", Images: []string{"a.jpg"}}, pending.Preview)
+
+	// 修改参数后令牌失效
+	response = appServer.processJSONRPCRequest(&JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "tools/call",
+		Params: map[string]any{"name": "publish_content", "arguments": map[string]any{
+			"title": "别的标题", "content": "正文", "images": []any{"a.jpg"},
+			confirmationTokenParam: pending.ConfirmationToken,
+		}},
+		ID: 3,
+	}, context.Background())
+	require.NotNil(t, response.Error)
+	assert.Equal(t, -32602, response.Error.Code)
+}
@@ -52,3 +52,11 @@ func GetCookiesFilePath() string {
 	filePath := filepath.Join(tmpDir, "cookies.json")
 	return filePath
 }
+
+// GetAccountCookiesFilePath 获取指定账号的 cookies 文件路径，account 为空时使用默认路径。
+func GetAccountCookiesFilePath(account string) string {
+	if account == "" {
+		return GetCookiesFilePath()
+	}
+	return filepath.Join(os.TempDir(), "cookies-"+account+".json")
+}
@@ -1,18 +1,24 @@
 package main
 
 import (
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
 )
 
 // respondError 返回错误响应
 func respondError(c *gin.Context, statusCode int, code, message string, details any) {
 	response := ErrorResponse{
-		Error:   message,
-		Code:    code,
-		Details: details,
+		Error:      message,
+		Code:       code,
+		Details:    details,
+		ArtifactID: c.GetString("artifact_id"),
 	}
 
 	logrus.Errorf("%s %s %s %d", c.Request.Method, c.Request.URL.Path,
@@ -35,121 +41,115 @@ func respondSuccess(c *gin.Context, data any, message string) {
 	c.JSON(http.StatusOK, response)
 }
 
-// checkLoginStatusHandler 检查登录状态
-func (s *AppServer) checkLoginStatusHandler(c *gin.Context) {
-	status, err := s.xiaohongshuService.CheckLoginStatus(c.Request.Context())
-	if err != nil {
-		respondError(c, http.StatusInternalServerError, "STATUS_CHECK_FAILED",
-			"检查登录状态失败", err.Error())
-		return
-	}
-
-	c.Set("account", "ai-report")
-	respondSuccess(c, status, "检查登录状态成功")
-}
-
-// publishHandler 发布内容
-func (s *AppServer) publishHandler(c *gin.Context) {
-	var req PublishRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		respondError(c, http.StatusBadRequest, "INVALID_REQUEST",
-			"请求参数错误", err.Error())
-		return
+// toolHandler 将工具暴露为 REST 接口：GET 请求从查询参数读取参数，其余从 JSON 请求体读取
+func (s *AppServer) toolHandler(tool *Tool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := checkScope(c.Request.Context(), tool.Scope); err != nil {
+			respondError(c, http.StatusForbidden, "FORBIDDEN", "权限不足", err.Error())
+			return
+		}
+
+		args := map[string]any{}
+		if c.Request.Method == http.MethodGet {
+			args = tool.queryArguments(c.Request.URL.Query())
+		} else if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&args); err != nil {
+				respondError(c, http.StatusBadRequest, "INVALID_REQUEST",
+					"请求参数错误", err.Error())
+				return
+			}
+		}
+
+		input, err := tool.Decode(args)
+		if err != nil {
+			respondInvalidArguments(c, tool, err)
+			return
+		}
+
+		// 确认模式下，写操作先返回预览和确认令牌
+		if s.requiresConfirmation(tool) {
+			pending, err := s.confirmToolCall(tool, args, input)
+			if err != nil {
+				respondError(c, http.StatusBadRequest, "INVALID_CONFIRMATION",
+					"确认失败", err.Error())
+				return
+			}
+			if pending != nil {
+				c.JSON(http.StatusAccepted, SuccessResponse{
+					Success: true,
+					Data:    pending,
+					Message: "等待确认，请携带 confirmation_token 再次请求",
+				})
+				return
+			}
+		}
+
+		result, err := tool.Call(c.Request.Context(), s, input)
+		if err != nil {
+			respondToolError(c, tool, err)
+			return
+		}
+
+		respondSuccess(c, result, tool.SuccessMessage)
 	}
-
-	// 执行发布
-	result, err := s.xiaohongshuService.PublishContent(c.Request.Context(), &req)
-	if err != nil {
-		respondError(c, http.StatusInternalServerError, "PUBLISH_FAILED",
-			"发布失败", err.Error())
-		return
-	}
-
-	respondSuccess(c, result, "发布成功")
 }
 
-// publishLongTextHandler 发布长文
-func (s *AppServer) publishLongTextHandler(c *gin.Context) {
-	var req PublishLongTextRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+// respondInvalidArguments 返回参数校验失败的响应，第一个不合法的参数在工具的 ArgumentErrors 中时使用其错误码
+func respondInvalidArguments(c *gin.Context, tool *Tool, err error) {
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
 		respondError(c, http.StatusBadRequest, "INVALID_REQUEST",
 			"请求参数错误", err.Error())
 		return
 	}
 
-	// 执行长文发布
-	result, err := s.xiaohongshuService.PublishLongText(c.Request.Context(), &req)
-	if err != nil {
-		respondError(c, http.StatusInternalServerError, "PUBLISH_LONGTEXT_FAILED",
-			"长文发布失败", err.Error())
-		return
-	}
-
-	respondSuccess(c, result, "长文发布成功")
-}
-
-// listFeedsHandler 获取Feeds列表
-func (s *AppServer) listFeedsHandler(c *gin.Context) {
-	// 获取 Feeds 列表
-	result, err := s.xiaohongshuService.ListFeeds(c.Request.Context())
-	if err != nil {
-		respondError(c, http.StatusInternalServerError, "LIST_FEEDS_FAILED",
-			"获取Feeds列表失败", err.Error())
-		return
-	}
-
-	c.Set("account", "ai-report")
-	respondSuccess(c, result, "获取Feeds列表成功")
-}
-
-// searchFeedsHandler 搜索Feeds
-func (s *AppServer) searchFeedsHandler(c *gin.Context) {
-	keyword := c.Query("keyword")
-	if keyword == "" {
-		respondError(c, http.StatusBadRequest, "MISSING_KEYWORD",
-			"缺少关键词参数", "keyword parameter is required")
-		return
-	}
-
-	// 搜索 Feeds
-	result, err := s.xiaohongshuService.SearchFeeds(c.Request.Context(), keyword)
-	if err != nil {
-		respondError(c, http.StatusInternalServerError, "SEARCH_FEEDS_FAILED",
-			"搜索Feeds失败", err.Error())
-		return
+	if len(validationErr.Fields) > 0 {
+		if argErr, ok := tool.ArgumentErrors[validationErr.Fields[0].Field]; ok {
+			respondError(c, http.StatusBadRequest, argErr.Code,
+				argErr.Message, validationErr.Fields)
+			return
+		}
 	}
-
-	c.Set("account", "ai-report")
-	respondSuccess(c, result, "搜索Feeds成功")
+	respondError(c, http.StatusBadRequest, "INVALID_REQUEST",
+		"请求参数错误", validationErr.Fields)
 }
 
-// getFeedDetailHandler 获取Feed详情
-func (s *AppServer) getFeedDetailHandler(c *gin.Context) {
-	var req FeedDetailRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		respondError(c, http.StatusBadRequest, "INVALID_REQUEST",
-			"请求参数错误", err.Error())
-		return
+// respondToolError 返回工具执行失败的响应，频率限制、风控和发布被拒绝返回专门的错误码，
+// 保存了失败现场时在响应中附带其 ID
+func respondToolError(c *gin.Context, tool *Tool, err error) {
+	var (
+		rateLimitErr *RateLimitError
+		pausedErr    *AccountPausedError
+		riskErr      *xiaohongshu.RiskControlError
+		rejectedErr  *xiaohongshu.PublishRejectedError
+		actionErr    *BrowserActionError
+	)
+	if errors.As(err, &actionErr) && actionErr.ArtifactID != "" {
+		c.Set("artifact_id", actionErr.ArtifactID)
 	}
 
-	// 获取 Feed 详情
-	result, err := s.xiaohongshuService.GetFeedDetail(c.Request.Context(), req.FeedID, req.XsecToken)
-	if err != nil {
-		respondError(c, http.StatusInternalServerError, "GET_FEED_DETAIL_FAILED",
-			"获取Feed详情失败", err.Error())
-		return
+	switch {
+	case errors.As(err, &rateLimitErr):
+		setRetryAfter(c, rateLimitErr.RetryAfter)
+		respondError(c, http.StatusTooManyRequests, "RATE_LIMITED",
+			"操作过于频繁", err.Error())
+	case errors.As(err, &pausedErr):
+		setRetryAfter(c, pausedErr.RetryAfter)
+		respondError(c, http.StatusServiceUnavailable, "ACCOUNT_PAUSED",
+			"账号已暂停", err.Error())
+	case errors.As(err, &riskErr):
+		respondError(c, http.StatusServiceUnavailable, "RISK_CONTROL",
+			"触发小红书风控", err.Error())
+	case errors.As(err, &rejectedErr):
+		respondError(c, http.StatusUnprocessableEntity, "PUBLISH_REJECTED",
+			"发布被拒绝", err.Error())
+	default:
+		respondError(c, http.StatusInternalServerError, tool.ErrorCode,
+			tool.ErrorMessage, err.Error())
 	}
-
-	c.Set("account", "ai-report")
-	respondSuccess(c, result, "获取Feed详情成功")
 }
 
-// healthHandler 健康检查
-func healthHandler(c *gin.Context) {
-	respondSuccess(c, map[string]any{
-		"status":    "healthy",
-		"service":   "xiaohongshu-mcp",
-		"account":   "ai-report",
-		"timestamp": "now",
-	}, "服务正常")
+// setRetryAfter 设置 Retry-After 响应头（秒）
+func setRetryAfter(c *gin.Context, d time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
 }
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// 根据 Go 结构体生成 JSON Schema，工具的 inputSchema 和 outputSchema 都由此生成。
+//
+// 字段名取自 json tag，字段说明取自 description tag，
+// binding tag 中的 required 会生成 required 列表，min=N、max=N 会生成对应的长度或取值约束，
+// oneof=a b 会生成 enum。与 gin 的 binding:"required" 一致，必填的字符串不能为空，生成 minLength: 1。
+
+// schemaFor 生成类型 T 的 JSON Schema
+func schemaFor[T any]() map[string]any {
+	return typeSchema(reflect.TypeOf((*T)(nil)).Elem(), map[reflect.Type]bool{})
+}
+
+func typeSchema(t reflect.Type, visiting map[reflect.Type]bool) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem(), visiting)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem(), visiting)}
+	case reflect.Struct:
+		// 递归类型（例如子评论）不再展开
+		if visiting[t] {
+			return map[string]any{"type": "object"}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		return structSchema(t, visiting)
+	default:
+		// interface 等无法确定类型的字段不做约束
+		return map[string]any{}
+	}
+}
+
+func structSchema(t reflect.Type, visiting map[reflect.Type]bool) map[string]any {
+	properties := map[string]any{}
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, ok := jsonFieldName(field)
+		if !ok {
+			continue
+		}
+
+		schema := typeSchema(field.Type, visiting)
+		if description := field.Tag.Get("description"); description != "" {
+			schema["description"] = description
+		}
+
+		for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+			key, value, _ := strings.Cut(rule, "=")
+			switch key {
+			case "required":
+				required = append(required, name)
+				if _, ok := schema["minLength"]; !ok && schema["type"] == "string" {
+					schema["minLength"] = 1
+				}
+			case "min", "max":
+				applyRangeRule(schema, key, value)
+			case "oneof":
+				schema["enum"] = strings.Fields(value)
+			}
+		}
+
+		properties[name] = schema
+	}
+
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// applyRangeRule 将 binding 的 min、max 规则转换为 JSON Schema 约束
+func applyRangeRule(schema map[string]any, rule, value string) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return
+	}
+
+	keys := map[string][2]string{
+		"array":   {"minItems", "maxItems"},
+		"string":  {"minLength", "maxLength"},
+		"integer": {"minimum", "maximum"},
+		"number":  {"minimum", "maximum"},
+	}
+	pair, ok := keys[schema["type"].(string)]
+	if !ok {
+		return
+	}
+
+	if rule == "min" {
+		schema[pair[0]] = n
+	} else {
+		schema[pair[1]] = n
+	}
+}
+
+// jsonFieldName 字段序列化后的名称，json:"-" 的字段返回 false
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MCP 日志级别，按严重程度从低到高排列（RFC 5424）
+var mcpLogLevels = []string{"debug", "info", "notice", "warning", "error", "critical", "alert", "emergency"}
+
+// mcpLoggerName notifications/message 中的 logger 名称
+const mcpLoggerName = "xiaohongshu-mcp"
+
+// setupLogging 统一日志出口：slog 的日志转发给 logrus 输出，
+// logrus 的日志再通过 hook 发送给触发该操作的 MCP 客户端，并写入失败现场的步骤日志。
+// logrus 记录所有级别的日志，由各会话通过 logging/setLevel 自行过滤，服务日志只输出不低于 level 的日志
+func setupLogging(level logrus.Level) {
+	configureLogger(logrus.StandardLogger(), level)
+	slog.SetDefault(slog.New(newLogrusHandler(logrus.StandardLogger())))
+}
+
+// configureLogger 设置 logger 的输出级别和 hook
+func configureLogger(logger *logrus.Logger, level logrus.Level) {
+	logger.SetFormatter(&levelFilterFormatter{Formatter: logger.Formatter, level: level})
+	logger.SetLevel(logrus.DebugLevel)
+	logger.AddHook(&mcpLogHook{})
+	logger.AddHook(newArtifactStepHook())
+}
+
+// levelFilterFormatter 只格式化不低于 level 的日志，低于 level 的日志不写入服务日志，但仍然交给 hook 处理
+type levelFilterFormatter struct {
+	logrus.Formatter
+	level logrus.Level
+}
+
+// Format 实现 logrus.Formatter
+func (f *levelFilterFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if entry.Level > f.level {
+		return nil, nil
+	}
+	return f.Formatter.Format(entry)
+}
+
+// isValidLogLevel 是否为合法的 MCP 日志级别
+func isValidLogLevel(level string) bool {
+	return logLevelSeverity(level) >= 0
+}
+
+func logLevelSeverity(level string) int {
+	for i, l := range mcpLogLevels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// logLevelEnabled 日志级别 level 是否达到会话设置的最低级别 minLevel
+func logLevelEnabled(minLevel, level string) bool {
+	return logLevelSeverity(level) >= logLevelSeverity(minLevel)
+}
+
+// mcpLogLevel 将 logrus 级别转换为 MCP 日志级别
+func mcpLogLevel(level logrus.Level) string {
+	switch level {
+	case logrus.PanicLevel:
+		return "emergency"
+	case logrus.FatalLevel:
+		return "critical"
+	case logrus.ErrorLevel:
+		return "error"
+	case logrus.WarnLevel:
+		return "warning"
+	case logrus.InfoLevel:
+		return "info"
+	default:
+		return "debug"
+	}
+}
+
+// mcpLogHook 将携带 MCP 会话 context 的日志以 notifications/message 发送给客户端
+type mcpLogHook struct{}
+
+// Levels 实现 logrus.Hook
+func (h *mcpLogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire 实现 logrus.Hook
+func (h *mcpLogHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	notifyLog(entry.Context, entry.Level, entry.Message, entry.Data)
+	return nil
+}
+
+// notifyLog 只以 notifications/message 发送给 ctx 所属会话的客户端，不写入服务日志，
+// 低于会话日志级别时不发送
+func notifyLog(ctx context.Context, level logrus.Level, message string, fields logrus.Fields) {
+	session, ok := sessionFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	mcpLevel := mcpLogLevel(level)
+	if !logLevelEnabled(session.LogLevel(), mcpLevel) {
+		return
+	}
+
+	data := make(map[string]any, len(fields)+1)
+	for k, v := range fields {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		data[k] = v
+	}
+	data["message"] = message
+
+	notifyClient(ctx, "notifications/message", map[string]any{
+		"level":  mcpLevel,
+		"logger": mcpLoggerName,
+		"data":   data,
+	})
+}
+
+// logrusHandler 将 slog 日志写入 logrus，context 随日志一起传递
+type logrusHandler struct {
+	logger *logrus.Logger
+	attrs  []slog.Attr
+	group  string
+}
+
+func newLogrusHandler(logger *logrus.Logger) *logrusHandler {
+	return &logrusHandler{logger: logger}
+}
+
+// Enabled 实现 slog.Handler
+func (h *logrusHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.IsLevelEnabled(logrusLevel(level))
+}
+
+// Handle 实现 slog.Handler
+func (h *logrusHandler) Handle(ctx context.Context, record slog.Record) error {
+	fields := make(logrus.Fields, len(h.attrs)+record.NumAttrs())
+	for _, attr := range h.attrs {
+		addSlogAttr(fields, "", attr)
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		addSlogAttr(fields, h.group, attr)
+		return true
+	})
+
+	entry := h.logger.WithFields(fields).WithTime(record.Time)
+	if ctx != nil {
+		entry = entry.WithContext(ctx)
+	}
+	entry.Log(logrusLevel(record.Level), record.Message)
+	return nil
+}
+
+// WithAttrs 实现 slog.Handler
+func (h *logrusHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	prefixed := make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	prefixed = append(prefixed, h.attrs...)
+	for _, attr := range attrs {
+		if h.group != "" {
+			attr.Key = h.group + "." + attr.Key
+		}
+		prefixed = append(prefixed, attr)
+	}
+	return &logrusHandler{logger: h.logger, attrs: prefixed, group: h.group}
+}
+
+// WithGroup 实现 slog.Handler
+func (h *logrusHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	group := name
+	if h.group != "" {
+		group = h.group + "." + name
+	}
+	return &logrusHandler{logger: h.logger, attrs: h.attrs, group: group}
+}
+
+// addSlogAttr 将 slog 属性展开为 logrus 字段，分组使用 . 连接
+func addSlogAttr(fields logrus.Fields, prefix string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+
+	key := attr.Key
+	if prefix != "" && key != "" {
+		key = prefix + "." + key
+	} else if key == "" {
+		// 没有名称的分组直接展开到上一级
+		key = prefix
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		for _, a := range attr.Value.Group() {
+			addSlogAttr(fields, key, a)
+		}
+		return
+	}
+
+	value := attr.Value.Any()
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	} else if stringer, ok := value.(fmt.Stringer); ok {
+		value = stringer.String()
+	}
+	fields[key] = value
+}
+
+// logrusLevel 将 slog 级别转换为 logrus 级别
+func logrusLevel(level slog.Level) logrus.Level {
+	switch {
+	case level >= slog.LevelError:
+		return logrus.ErrorLevel
+	case level >= slog.LevelWarn:
+		return logrus.WarnLevel
+	case level >= slog.LevelInfo:
+		return logrus.InfoLevel
+	default:
+		return logrus.DebugLevel
+	}
+}
+
+// processLoggingSetLevel 处理 logging/setLevel 请求，设置当前会话接收日志的最低级别
+func (s *AppServer) processLoggingSetLevel(ctx context.Context, request *JSONRPCRequest) *JSONRPCResponse {
+	var params struct {
+		Level string `json:"level"`
+	}
+	if err := request.BindParams(&params); err != nil || !isValidLogLevel(params.Level) {
+		return errorResponse(request, -32602, "Invalid params: level must be one of "+strings.Join(mcpLogLevels, ", "), nil)
+	}
+
+	session, ok := sessionFromContext(ctx)
+	if !ok {
+		return errorResponse(request, -32600, "logging/setLevel requires a session", nil)
+	}
+
+	session.SetLogLevel(params.Level)
+	logrus.WithFields(logrus.Fields{
+		"session": session.ID,
+		"level":   params.Level,
+	}).Info("MCP: 设置日志级别")
+
+	return &JSONRPCResponse{JSONRPC: "2.0", Result: map[string]interface{}{}, ID: request.ID}
+}
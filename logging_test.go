@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogNotifications(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.AddHook(&mcpLogHook{})
+	slogger := slog.New(newLogrusHandler(logger))
+
+	appServer := NewAppServer(NewXiaohongshuService())
+	session := newMCPSession("test")
+	ctx := withSession(context.Background(), session)
+
+	// 没有会话的日志不会发送给客户端
+	slogger.InfoContext(context.Background(), "no session")
+	assert.Empty(t, session.stream)
+
+	slogger.InfoContext(ctx, "打开创作者发布页面", "step", 1)
+	require.Len(t, session.stream, 1)
+
+	var notification struct {
+		Method string `json:"method"`
+		Params struct {
+			Level  string         `json:"level"`
+			Logger string         `json:"logger"`
+			Data   map[string]any `json:"data"`
+		} `json:"params"`
+	}
+	require.NoError(t, json.Unmarshal(<-session.stream, &notification))
+	assert.Equal(t, "notifications/message", notification.Method)
+	assert.Equal(t, "info", notification.Params.Level)
+	assert.Equal(t, "打开创作者发布页面", notification.Params.Data["message"])
+	assert.Equal(t, float64(1), notification.Params.Data["step"])
+
+	// 提高会话日志级别后，info 日志不再发送
+	response := appServer.processJSONRPCRequest(&JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "logging/setLevel",
+		Params:  map[string]any{"level": "warning"},
+		ID:      1,
+	}, ctx)
+	require.Nil(t, response.Error)
+	assert.Equal(t, "warning", session.LogLevel())
+
+	logger.WithContext(ctx).Info("filtered")
+	assert.Empty(t, session.stream)
+	logger.WithContext(ctx).Warn("sent")
+	assert.Len(t, session.stream, 1)
+
+	response = appServer.processJSONRPCRequest(&JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "logging/setLevel",
+		Params:  map[string]any{"level": "verbose"},
+		ID:      2,
+	}, ctx)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, -32602, response.Error.Code)
+}
+
+func TestDebugLogNotifications(t *testing.T) {
+	var out bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&out)
+	configureLogger(logger, logrus.InfoLevel)
+	slogger := slog.New(newLogrusHandler(logger))
+
+	appServer := NewAppServer(NewXiaohongshuService())
+	session := newMCPSession("test")
+	ctx := withSession(context.Background(), session)
+
+	// 会话默认级别为 info，debug 日志不发送
+	slogger.DebugContext(ctx, "hidden")
+	assert.Empty(t, session.stream)
+
+	response := appServer.processJSONRPCRequest(&JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "logging/setLevel",
+		Params:  map[string]any{"level": "debug"},
+		ID:      1,
+	}, ctx)
+	require.Nil(t, response.Error)
+
+	slogger.DebugContext(ctx, "滚动后没有加载更多", "page", 1)
+	require.Len(t, session.stream, 1)
+
+	var notification struct {
+		Method string `json:"method"`
+		Params struct {
+			Level string         `json:"level"`
+			Data  map[string]any `json:"data"`
+		} `json:"params"`
+	}
+	require.NoError(t, json.Unmarshal(<-session.stream, &notification))
+	assert.Equal(t, "notifications/message", notification.Method)
+	assert.Equal(t, "debug", notification.Params.Level)
+	assert.Equal(t, "滚动后没有加载更多", notification.Params.Data["message"])
+
+	// 服务日志仍然按启动时的级别过滤
+	assert.NotContains(t, out.String(), "滚动后没有加载更多")
+	assert.NotContains(t, out.String(), "hidden")
+	slogger.InfoContext(context.Background(), "shown")
+	assert.Contains(t, out.String(), "shown")
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// loginStartFunc 打开登录页面获取二维码。需要等待扫码时在后台等待，等待结束后调用 done
+type loginStartFunc func(ctx context.Context, done func()) (*LoginQrcodeResponse, error)
+
+// loginSession 一个账号正在进行的扫码登录
+type loginSession struct {
+	ready    chan struct{}
+	resp     *LoginQrcodeResponse
+	err      error
+	deadline time.Time
+}
+
+// loginSessions 按账号合并扫码登录：同一账号同时只启动一个浏览器，
+// 等待扫码期间重复获取二维码返回同一个二维码，避免浏览器堆积并长时间占用账号的浏览器数据目录
+type loginSessions struct {
+	mu       sync.Mutex
+	sessions map[string]*loginSession
+}
+
+// get 返回账号正在等待扫码的二维码，没有时调用 start 开始新的登录
+func (l *loginSessions) get(ctx context.Context, account string, start loginStartFunc) (*LoginQrcodeResponse, error) {
+	l.mu.Lock()
+	session, ok := l.sessions[account]
+	if !ok {
+		session = &loginSession{ready: make(chan struct{})}
+		if l.sessions == nil {
+			l.sessions = make(map[string]*loginSession)
+		}
+		l.sessions[account] = session
+	}
+	l.mu.Unlock()
+
+	if !ok {
+		deadline := time.Now().Add(loginQrcodeTimeout)
+		resp, err := start(ctx, func() { l.remove(account, session) })
+		session.resp, session.err, session.deadline = resp, err, deadline
+		close(session.ready)
+
+		// 获取失败或已经登录时不需要等待扫码
+		if err != nil || resp.IsLoggedIn {
+			l.remove(account, session)
+		}
+	}
+
+	select {
+	case <-session.ready:
+	case <-ctx.Done():
+		return nil, errors.Wrap(ctx.Err(), "操作已取消")
+	}
+
+	if session.err != nil {
+		return nil, session.err
+	}
+	resp := *session.resp
+	if !resp.IsLoggedIn {
+		resp.Timeout = time.Until(session.deadline).Round(time.Second).String()
+	}
+	return &resp, nil
+}
+
+func (l *loginSessions) remove(account string, session *loginSession) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.sessions[account] == session {
+		delete(l.sessions, account)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginSessionsSingleFlight(t *testing.T) {
+	var logins loginSessions
+	var starts atomic.Int32
+	var finish func()
+	started := make(chan struct{}, 3)
+	release := make(chan struct{})
+
+	start := func(ctx context.Context, done func()) (*LoginQrcodeResponse, error) {
+		starts.Add(1)
+		started <- struct{}{}
+		<-release
+		finish = done
+		return &LoginQrcodeResponse{Img: "qrcode"}, nil
+	}
+
+	// 并发获取只启动一次登录，都返回同一个二维码
+	var wg sync.WaitGroup
+	results := make([]*LoginQrcodeResponse, 3)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := logins.get(context.Background(), "alice", start)
+			assert.NoError(t, err)
+			results[i] = resp
+		}()
+	}
+	<-started
+	close(release)
+	wg.Wait()
+
+	assert.EqualValues(t, 1, starts.Load())
+	for _, resp := range results {
+		require.NotNil(t, resp)
+		assert.Equal(t, "qrcode", resp.Img)
+		assert.NotEmpty(t, resp.Timeout)
+	}
+
+	// 等待扫码期间再次获取不会重新启动
+	_, err := logins.get(context.Background(), "alice", start)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, starts.Load())
+
+	// 其他账号独立登录
+	_, err = logins.get(context.Background(), "bob", start)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, starts.Load())
+
+	// 等待结束后重新开始登录
+	finish()
+	_, err = logins.get(context.Background(), "bob", start)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, starts.Load())
+}
+
+func TestLoginSessionsNotCachedOnFailure(t *testing.T) {
+	var logins loginSessions
+	calls := 0
+	start := func(ctx context.Context, done func()) (*LoginQrcodeResponse, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("launch browser failed")
+		}
+		return &LoginQrcodeResponse{IsLoggedIn: true}, nil
+	}
+
+	_, err := logins.get(context.Background(), "", start)
+	assert.Error(t, err)
+
+	resp, err := logins.get(context.Background(), "", start)
+	require.NoError(t, err)
+	assert.True(t, resp.IsLoggedIn)
+	assert.Empty(t, resp.Timeout)
+
+	// 已登录时不保留
+	_, err = logins.get(context.Background(), "", start)
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
@@ -1,27 +1,179 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/xpzouying/xiaohongshu-mcp/browser"
 	"github.com/xpzouying/xiaohongshu-mcp/configs"
+	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
 )
 
+// selectorReloadInterval 检查选择器覆盖文件是否修改的间隔
+const selectorReloadInterval = 5 * time.Second
+
 func main() {
 	var (
-		headless bool
+		headless  bool
+		transport string
+		confirm   bool
+		authFile  string
+		policy    string
+		cooldown  time.Duration
+		takeover  bool
+		publicURL string
+		artifacts string
+		selectors string
+		canary    time.Duration
+		creator   string
+		strict    bool
+		scroll    int
+		block     string
+		browserCf string
+		logLevel  string
 	)
 	flag.BoolVar(&headless, "headless", true, "是否无头模式")
+	flag.StringVar(&transport, "transport", "http", "MCP 传输方式：http 或 stdio")
+	flag.BoolVar(&confirm, "confirm", false, "确认模式：发布等写操作需要携带确认令牌再次调用才会执行")
+	flag.StringVar(&authFile, "auth", "", "API Key 认证配置文件（JSON），也可以通过 XHS_MCP_AUTH_FILE、XHS_MCP_API_KEY 环境变量配置")
+	flag.StringVar(&policy, "policy", "", "防封号策略配置文件（JSON），为空时使用默认策略（每个账号每小时最多发布 2 次、每天 5 次）")
+	flag.DurationVar(&cooldown, "risk-cooldown", defaultRiskCooldown, "检测到风控页面后暂停账号的时长，0 表示不暂停")
+	flag.BoolVar(&takeover, "takeover", false, "人工接管：遇到风控页面时等待操作员通过实时画面处理，而不是直接返回错误（仅 http 模式）")
+	flag.StringVar(&publicURL, "public-url", "http://localhost:18060", "操作员访问本服务的地址，用于生成人工接管页面的链接")
+	flag.StringVar(&artifacts, "artifacts-dir", "", "失败现场目录：浏览器操作失败时保存截图、HTML、控制台和网络日志，为空表示不保存")
+	flag.StringVar(&selectors, "selectors", "", "页面元素选择器覆盖文件（YAML），修改后自动重新加载，为空时使用内置选择器")
+	flag.DurationVar(&canary, "canary-interval", 0, "页面巡检间隔：定期检查选择器和页面数据是否仍然有效，结果见 /health 和 /metrics，0 表示不巡检（仅 http 模式）")
+	flag.StringVar(&creator, "creator-url", "", "创作者中心地址，可以指向本地模拟服务（cmd/mock-xhs）测试发布流程，为空时使用小红书创作者中心")
+	flag.BoolVar(&strict, "strict-schema", false, "严格模式：页面数据缺少必需字段或类型与预期不符时请求失败，而不是返回缺失字段的结果")
+	flag.IntVar(&scroll, "scroll-pages", 0, "搜索、笔记详情和用户主页滚动加载更多的页数，每页多请求一次接口，0 表示只使用首屏数据")
+	flag.StringVar(&block, "block-resources", "", "只读操作（feeds、search、feed_detail、user_profile）拦截的资源：image、media、font、analytics，如 \"image,font;search=none\"，为空时全部拦截，发布和登录始终不拦截")
+	flag.StringVar(&browserCf, "browser-config", "", "浏览器启动配置文件（JSON）：可执行文件、代理、User-Agent、视口、语言时区、启动参数和数据目录，可按账号覆盖，也可以通过 XHS_MCP_BROWSER_CONFIG 环境变量配置")
+	flag.StringVar(&logLevel, "log-level", "info", "服务日志的级别：debug、info、warning、error。MCP 客户端通过 logging/setLevel 单独设置接收的级别")
 	flag.Parse()
 
 	configs.InitHeadless(headless)
 
+	// 统一 slog、logrus 的日志输出，并转发给 MCP 客户端
+	level, err := logrus.ParseLevel(logLevel)
+	if err != nil {
+		logrus.Fatalf("invalid log level: %v", err)
+	}
+	setupLogging(level)
+
+	browserConfig, err := browser.LoadConfigFile(browserCf)
+	if err != nil {
+		logrus.Fatalf("failed to load browser config: %v", err)
+	}
+	browser.SetConfigFile(browserConfig)
+
+	if selectors != "" {
+		if err := xiaohongshu.LoadSelectorOverrides(selectors); err != nil {
+			logrus.Fatalf("failed to load selectors: %v", err)
+		}
+		go xiaohongshu.WatchSelectorOverrides(context.Background(), selectors, selectorReloadInterval)
+		logrus.Infof("已加载选择器覆盖文件 %s，版本 %s", selectors, xiaohongshu.GetSelectorReport().Version)
+	}
+
+	if creator != "" {
+		xiaohongshu.SetCreatorBaseURL(creator)
+		logrus.Warnf("发布使用的创作者中心地址为 %s", creator)
+	}
+
+	xiaohongshu.SetStrictSchema(strict)
+	xiaohongshu.SetAPIScrollPages(scroll)
+
+	if block != "" {
+		policies, err := xiaohongshu.ParseResourcePolicies(block)
+		if err != nil {
+			logrus.Fatalf("invalid -block-resources: %v", err)
+		}
+		for action, policy := range policies {
+			if err := xiaohongshu.SetResourcePolicy(action, policy); err != nil {
+				logrus.Fatalf("invalid -block-resources: %v", err)
+			}
+		}
+	}
+
 	// 初始化服务
 	xiaohongshuService := NewXiaohongshuService()
 
-	// 创建并启动应用服务器
+	policyConfig, err := LoadPolicyConfig(policy)
+	if err != nil {
+		logrus.Fatalf("failed to load policy config: %v", err)
+	}
+	policyEngine, err := NewPolicyEngine(policyConfig)
+	if err != nil {
+		logrus.Fatalf("invalid policy config: %v", err)
+	}
+	xiaohongshuService.SetPolicy(policyEngine)
+	if cooldown > 0 {
+		xiaohongshuService.SetCircuitBreaker(NewCircuitBreaker(cooldown))
+	}
+	if takeover {
+		if transport == "http" {
+			xiaohongshuService.SetTakeover(NewTakeoverManager(strings.TrimSuffix(publicURL, "/"), defaultTakeoverTimeout))
+		} else {
+			logrus.Warn("人工接管需要 HTTP 服务，stdio 模式下不开启")
+		}
+	}
+
+	if artifacts != "" {
+		store, err := NewArtifactStore(artifacts)
+		if err != nil {
+			logrus.Fatalf("failed to create artifacts dir: %v", err)
+		}
+		xiaohongshuService.SetArtifactStore(store)
+		logrus.Infof("已开启失败现场记录，保存在 %s", artifacts)
+	}
+
+	// 创建应用服务器
 	appServer := NewAppServer(xiaohongshuService)
-	if err := appServer.Start(":18060"); err != nil {
-		logrus.Fatalf("failed to run server: %v", err)
+	if confirm {
+		appServer.EnableConfirmMode(defaultConfirmationTTL)
+	}
+
+	if canary > 0 {
+		if transport == "http" {
+			appServer.EnableCanary(canary)
+			logrus.Infof("已开启页面巡检，间隔 %s", canary)
+		} else {
+			logrus.Warn("页面巡检结果通过 HTTP 接口查看，stdio 模式下不开启")
+		}
+	}
+
+	authConfig, err := LoadAuthConfig(authFile)
+	if err != nil {
+		logrus.Fatalf("failed to load auth config: %v", err)
+	}
+	if authConfig != nil {
+		appServer.EnableAuth(authConfig)
+		logrus.Infof("已开启 API Key 认证，共 %d 个 Key", len(authConfig.Keys))
+	} else if transport == "http" {
+		logrus.Warn("未配置 API Key，HTTP 接口不需要认证即可访问")
+	}
+
+	switch transport {
+	case "stdio":
+		// stdout 只能用于协议消息，日志全部输出到 stderr
+		stdout := redirectStdoutToStderr()
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		if err := appServer.ServeStdio(ctx, os.Stdin, stdout); err != nil {
+			logrus.Fatalf("failed to serve stdio: %v", err)
+		}
+	case "http":
+		if err := appServer.Start(":18060"); err != nil {
+			logrus.Fatalf("failed to run server: %v", err)
+		}
+	default:
+		logrus.Fatalf("unknown transport: %s", transport)
 	}
 }
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
+)
+
+const (
+	// maxCoverThumbnails 结果中最多附带的封面缩略图数量
+	maxCoverThumbnails = 4
+	// maxThumbnailSize 单张缩略图的最大字节数
+	maxThumbnailSize = 2 * 1024 * 1024
+)
+
+// thumbnailClient 下载封面缩略图使用的 HTTP 客户端
+var thumbnailClient = &http.Client{Timeout: 10 * time.Second}
+
+// textContent 文本内容块
+func textContent(text string) MCPContent {
+	return MCPContent{Type: "text", Text: text}
+}
+
+// imageContent 图片内容块，data 为原始图片数据
+func imageContent(data []byte, mimeType string) MCPContent {
+	return MCPContent{
+		Type:     "image",
+		Data:     base64.StdEncoding.EncodeToString(data),
+		MimeType: mimeType,
+	}
+}
+
+// resourceLinkContent 资源链接内容块
+func resourceLinkContent(uri, name, description, mimeType string) MCPContent {
+	return MCPContent{
+		Type:        "resource_link",
+		URI:         uri,
+		Name:        name,
+		Description: description,
+		MimeType:    mimeType,
+	}
+}
+
+// embeddedResourceContent 内嵌资源内容块，data 序列化为 JSON
+func embeddedResourceContent(uri string, data any) (MCPContent, error) {
+	jsonData, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return MCPContent{}, errors.Wrap(err, "marshal resource")
+	}
+
+	return MCPContent{
+		Type: "resource",
+		Resource: &MCPResourceContents{
+			URI:      uri,
+			MimeType: resourceMimeType,
+			Text:     string(jsonData),
+		},
+	}, nil
+}
+
+// structuredResult 构建带 structuredContent 的工具结果，
+// 同时附带序列化后的 JSON 文本，兼容不支持结构化输出的客户端
+func structuredResult(data any, extra ...MCPContent) *MCPToolResult {
+	jsonData, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return errorResult("结果序列化失败", err)
+	}
+
+	return &MCPToolResult{
+		Content:           append([]MCPContent{textContent(string(jsonData))}, extra...),
+		StructuredContent: data,
+	}
+}
+
+// pendingConfirmationResult 确认模式下写操作的预览结果
+func pendingConfirmationResult(pending *PendingConfirmation) *MCPToolResult {
+	return structuredResult(pending, textContent(fmt.Sprintf(
+		"操作尚未执行。请向用户确认上面的内容，确认无误后携带相同参数和 %s=%s 再次调用 %s（%s 前有效）",
+		confirmationTokenParam, pending.ConfirmationToken, pending.Tool, pending.ExpiresAt.Format(time.DateTime),
+	)))
+}
+
+// errorResult 构建错误结果，浏览器操作失败时附带失败截图
+func errorResult(message string, err error) *MCPToolResult {
+	result := &MCPToolResult{
+		Content: []MCPContent{textContent(message + ": " + err.Error())},
+		IsError: true,
+	}
+
+	var actionErr *BrowserActionError
+	if !errors.As(err, &actionErr) {
+		return result
+	}
+	if len(actionErr.Screenshot) > 0 {
+		result.Content = append(result.Content, imageContent(actionErr.Screenshot, "image/png"))
+	}
+	if actionErr.ArtifactID != "" {
+		result.Content = append(result.Content, textContent(
+			"失败现场已保存，ID: "+actionErr.ArtifactID+"，管理员可通过 GET /api/v1/artifacts/"+actionErr.ArtifactID+" 下载"))
+	}
+
+	return result
+}
+
+// feedResourceLinks 为每条笔记生成资源链接
+func feedResourceLinks(feeds []xiaohongshu.Feed) []MCPContent {
+	links := make([]MCPContent, 0, len(feeds))
+	for _, feed := range feeds {
+		if feed.ID == "" || feed.XsecToken == "" {
+			continue
+		}
+		links = append(links, resourceLinkContent(
+			noteResourceURI(feed.ID, feed.XsecToken),
+			feed.NoteCard.DisplayTitle,
+			"作者: "+feed.NoteCard.User.Nickname,
+			resourceMimeType,
+		))
+	}
+	return links
+}
+
+// feedCoverThumbnails 下载前几条笔记的封面作为图片内容块，下载失败的跳过
+func feedCoverThumbnails(ctx context.Context, feeds []xiaohongshu.Feed) []MCPContent {
+	var images []MCPContent
+	for _, feed := range feeds {
+		if len(images) >= maxCoverThumbnails {
+			break
+		}
+
+		cover := feed.NoteCard.Cover
+		coverURL := firstNonEmpty(cover.URLPre, cover.URLDefault, cover.URL)
+		if coverURL == "" {
+			continue
+		}
+
+		data, mimeType, err := fetchImage(ctx, coverURL)
+		if err != nil {
+			logrus.WithError(err).Warnf("下载封面失败: %s", coverURL)
+			continue
+		}
+		images = append(images, imageContent(data, mimeType))
+	}
+	return images
+}
+
+// fetchImage 下载图片并检测 MIME 类型
+func fetchImage(ctx context.Context, imageURL string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Referer", "https://www.xiaohongshu.com/")
+
+	resp, err := thumbnailClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", errors.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxThumbnailSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxThumbnailSize {
+		return nil, "", errors.New("image too large")
+	}
+
+	mimeType := http.DetectContentType(data)
+	if !strings.HasPrefix(mimeType, "image/") {
+		return nil, "", errors.Errorf("not an image: %s", mimeType)
+	}
+
+	return data, mimeType, nil
+}
+
+// decodeDataURL 解析 data:image/png;base64,... 格式的图片
+func decodeDataURL(dataURL string) ([]byte, string, error) {
+	meta, payload, ok := strings.Cut(strings.TrimPrefix(dataURL, "data:"), ",")
+	if !ok || !strings.HasSuffix(meta, ";base64") {
+		return nil, "", errors.New("invalid data url")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "invalid base64 data")
+	}
+
+	return data, strings.TrimSuffix(meta, ";base64"), nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
+)
+
+func TestStructuredResult(t *testing.T) {
+	feeds := []xiaohongshu.Feed{
+		{ID: "note-1", XsecToken: "token-1", NoteCard: xiaohongshu.NoteCard{DisplayTitle: "标题"}},
+		{ID: "note-2"}, // 缺少 xsecToken 时不生成链接
+	}
+	result := structuredResult(&FeedsListResponse{Feeds: feeds, Count: len(feeds)}, feedResourceLinks(feeds)...)
+
+	require.Len(t, result.Content, 2)
+	assert.Equal(t, "text", result.Content[0].Type)
+	assert.Equal(t, "resource_link", result.Content[1].Type)
+	assert.Contains(t, result.Content[1].URI, "xhs://note/note-1?xsec_token=token-1")
+	assert.Equal(t, "标题", result.Content[1].Name)
+
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	structured := decoded["structuredContent"].(map[string]any)
+	assert.Equal(t, float64(2), structured["count"])
+}
+
+func TestErrorResultWithScreenshot(t *testing.T) {
+	err := errors.Wrap(&BrowserActionError{Err: errors.New("找不到发布按钮"), Screenshot: []byte("png")}, "小红书发布失败")
+	result := errorResult("发布失败", err)
+
+	assert.True(t, result.IsError)
+	require.Len(t, result.Content, 2)
+	assert.Equal(t, "发布失败: 小红书发布失败: 找不到发布按钮", result.Content[0].Text)
+	assert.Equal(t, "image", result.Content[1].Type)
+	assert.Equal(t, "image/png", result.Content[1].MimeType)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("png")), result.Content[1].Data)
+}
+
+func TestDecodeDataURL(t *testing.T) {
+	data, mimeType, err := decodeDataURL("data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("qrcode")))
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", mimeType)
+	assert.Equal(t, []byte("qrcode"), data)
+
+	_, _, err = decodeDataURL("https://example.com/qrcode.png")
+	assert.Error(t, err)
+}
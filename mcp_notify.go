@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
+)
+
+// clientNotifier 向客户端发送 JSON-RPC 通知
+type clientNotifier interface {
+	notify(method string, params any)
+}
+
+type notifierContextKey struct{}
+
+// withNotifier 指定当前请求的通知通道（例如 POST 请求的 SSE 响应流）
+func withNotifier(ctx context.Context, notifier clientNotifier) context.Context {
+	return context.WithValue(ctx, notifierContextKey{}, notifier)
+}
+
+// notifyClient 向发起请求的客户端发送通知：
+// 优先写入当前请求的 SSE 响应流，否则通过会话的推送通道发送
+func notifyClient(ctx context.Context, method string, params any) {
+	if notifier, ok := ctx.Value(notifierContextKey{}).(clientNotifier); ok {
+		notifier.notify(method, params)
+		return
+	}
+
+	if session, ok := sessionFromContext(ctx); ok {
+		session.notify(method, params)
+		return
+	}
+
+	logrus.WithField("method", method).Debug("没有可用的通知通道，丢弃通知")
+}
+
+// notify 通过会话推送通道发送通知，通道已满时丢弃
+func (s *MCPSession) notify(method string, params any) {
+	data, err := json.Marshal(&JSONRPCNotification{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed to marshal notification")
+		return
+	}
+
+	select {
+	case <-s.done:
+	case s.stream <- data:
+	default:
+		logrus.WithFields(logrus.Fields{
+			"session": s.ID,
+			"method":  method,
+		}).Warn("会话推送通道已满，丢弃通知")
+	}
+}
+
+// progressReporter 将 action 的步骤进度转换为 notifications/progress 通知
+func progressReporter(ctx context.Context, progressToken any) xiaohongshu.ProgressReporter {
+	return xiaohongshu.ProgressFunc(func(progress, total float64, message string) {
+		params := map[string]any{
+			"progressToken": progressToken,
+			"progress":      progress,
+		}
+		if total > 0 {
+			params["total"] = total
+		}
+		if message != "" {
+			params["message"] = message
+		}
+
+		notifyClient(ctx, "notifications/progress", params)
+	})
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultResearchCount 关键词调研默认分析的笔记数量
+	defaultResearchCount = 10
+	// maxResearchCount 关键词调研最多分析的笔记数量
+	maxResearchCount = 20
+)
+
+// MCPPromptArgument 提示词参数
+type MCPPromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// MCPPrompt 提示词模板描述
+type MCPPrompt struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	Arguments   []MCPPromptArgument `json:"arguments,omitempty"`
+}
+
+// MCPPromptMessage 提示词消息
+type MCPPromptMessage struct {
+	Role    string     `json:"role"`
+	Content MCPContent `json:"content"`
+}
+
+// promptRenderer 根据参数生成提示词消息，可以通过 XiaohongshuService 获取实时数据
+type promptRenderer func(ctx context.Context, s *AppServer, args map[string]string) ([]MCPPromptMessage, error)
+
+// promptDefinition 提示词模板定义
+type promptDefinition struct {
+	MCPPrompt
+	render promptRenderer
+}
+
+// prompts 支持的提示词模板
+var prompts = []promptDefinition{
+	{
+		MCPPrompt: MCPPrompt{
+			Name:        "write_note",
+			Description: "按指定风格撰写一篇小红书图文笔记，可参考同话题的热门笔记",
+			Arguments: []MCPPromptArgument{
+				{Name: "topic", Description: "笔记主题", Required: true},
+				{Name: "style", Description: "写作风格，例如：干货分享、种草安利、真实测评，默认为种草安利"},
+				{Name: "reference", Description: "为 true 时搜索该主题并附上热门笔记作为参考"},
+			},
+		},
+		render: renderWriteNotePrompt,
+	},
+	{
+		MCPPrompt: MCPPrompt{
+			Name:        "analyze_comments",
+			Description: "分析一篇笔记的评论区：情绪倾向、主要观点和值得回复的评论",
+			Arguments: []MCPPromptArgument{
+				{Name: "feed_id", Description: "小红书笔记ID，从Feed列表或搜索结果获取", Required: true},
+				{Name: "xsec_token", Description: "访问令牌，从Feed列表或搜索结果的xsecToken字段获取", Required: true},
+			},
+		},
+		render: renderAnalyzeCommentsPrompt,
+	},
+	{
+		MCPPrompt: MCPPrompt{
+			Name:        "research_keyword",
+			Description: "调研一个关键词下的热门笔记，总结选题、标题和内容规律",
+			Arguments: []MCPPromptArgument{
+				{Name: "keyword", Description: "搜索关键词", Required: true},
+				{Name: "count", Description: fmt.Sprintf("分析的笔记数量，默认 %d，最多 %d", defaultResearchCount, maxResearchCount)},
+			},
+		},
+		render: renderResearchKeywordPrompt,
+	},
+}
+
+// findPrompt 根据名称查找提示词模板
+func findPrompt(name string) (*promptDefinition, bool) {
+	for i := range prompts {
+		if prompts[i].Name == name {
+			return &prompts[i], true
+		}
+	}
+	return nil, false
+}
+
+// processPromptsList 处理提示词列表请求
+func (s *AppServer) processPromptsList(request *JSONRPCRequest) *JSONRPCResponse {
+	list := make([]MCPPrompt, 0, len(prompts))
+	for _, prompt := range prompts {
+		list = append(list, prompt.MCPPrompt)
+	}
+
+	return &JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result: map[string]interface{}{
+			"prompts": list,
+		},
+		ID: request.ID,
+	}
+}
+
+// processPromptsGet 处理获取提示词请求
+func (s *AppServer) processPromptsGet(ctx context.Context, request *JSONRPCRequest) *JSONRPCResponse {
+	var params struct {
+		Name      string            `json:"name"`
+		Arguments map[string]string `json:"arguments"`
+	}
+	if err := request.BindParams(&params); err != nil {
+		return errorResponse(request, -32602, "Invalid params", err.Error())
+	}
+
+	prompt, ok := findPrompt(params.Name)
+	if !ok {
+		return errorResponse(request, -32602, "Unknown prompt: "+params.Name, nil)
+	}
+
+	for _, arg := range prompt.Arguments {
+		if arg.Required && strings.TrimSpace(params.Arguments[arg.Name]) == "" {
+			return errorResponse(request, -32602, "Missing required argument: "+arg.Name, nil)
+		}
+	}
+
+	logrus.WithContext(ctx).Infof("MCP: 获取提示词 - %s", params.Name)
+
+	messages, err := prompt.render(ctx, s, params.Arguments)
+	if err != nil {
+		return errorResponse(request, -32603, "生成提示词失败: "+err.Error(), nil)
+	}
+
+	return &JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result: map[string]interface{}{
+			"description": prompt.Description,
+			"messages":    messages,
+		},
+		ID: request.ID,
+	}
+}
+
+func userTextMessage(text string) MCPPromptMessage {
+	return MCPPromptMessage{Role: "user", Content: textContent(text)}
+}
+
+func userResourceMessage(uri string, data any) (MCPPromptMessage, error) {
+	content, err := embeddedResourceContent(uri, data)
+	if err != nil {
+		return MCPPromptMessage{}, err
+	}
+	return MCPPromptMessage{Role: "user", Content: content}, nil
+}
+
+func renderWriteNotePrompt(ctx context.Context, s *AppServer, args map[string]string) ([]MCPPromptMessage, error) {
+	topic := args["topic"]
+	style := firstNonEmpty(args["style"], "种草安利")
+
+	text := fmt.Sprintf(`请以「%s」的风格，写一篇关于「%s」的小红书图文笔记。
+
+要求：
+1. 标题不超过 20 个字，要有吸引力，可以适当使用 emoji
+2. 正文口语化、分段清晰，适当使用 emoji，结尾附上 3-5 个相关话题标签
+3. 给出 3-6 张配图的建议，说明每张图的画面内容
+4. 完成后可以使用 publish_content 工具发布`, style, topic)
+
+	messages := []MCPPromptMessage{userTextMessage(text)}
+
+	if reference, _ := strconv.ParseBool(args["reference"]); reference {
+		result, err := s.xiaohongshuService.SearchFeeds(ctx, topic)
+		if err != nil {
+			return nil, errors.Wrap(err, "搜索参考笔记失败")
+		}
+
+		message, err := userResourceMessage(searchResourceURI(topic), result)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages,
+			userTextMessage("以下是该主题下的热门笔记，请参考它们的标题和选题角度，但不要照抄："),
+			message,
+		)
+	}
+
+	return messages, nil
+}
+
+func renderAnalyzeCommentsPrompt(ctx context.Context, s *AppServer, args map[string]string) ([]MCPPromptMessage, error) {
+	feedID, xsecToken := args["feed_id"], args["xsec_token"]
+
+	result, err := s.xiaohongshuService.GetFeedDetail(ctx, feedID, xsecToken)
+	if err != nil {
+		return nil, errors.Wrap(err, "获取笔记详情失败")
+	}
+
+	message, err := userResourceMessage(noteResourceURI(feedID, xsecToken), result)
+	if err != nil {
+		return nil, err
+	}
+
+	return []MCPPromptMessage{
+		userTextMessage(`请分析下面这篇小红书笔记的评论区：
+
+1. 整体情绪倾向（正面/中性/负面的大致比例）
+2. 评论中的主要观点和高频问题
+3. 点赞最多的评论说明了什么
+4. 挑出 3-5 条值得作者回复的评论，并给出回复建议`),
+		message,
+	}, nil
+}
+
+func renderResearchKeywordPrompt(ctx context.Context, s *AppServer, args map[string]string) ([]MCPPromptMessage, error) {
+	keyword := args["keyword"]
+
+	count := defaultResearchCount
+	if raw := args["count"]; raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return nil, errors.Errorf("invalid count: %s", raw)
+		}
+		count = min(n, maxResearchCount)
+	}
+
+	result, err := s.xiaohongshuService.SearchFeeds(ctx, keyword)
+	if err != nil {
+		return nil, errors.Wrap(err, "搜索失败")
+	}
+
+	feeds := result.Feeds
+	if len(feeds) > count {
+		feeds = feeds[:count]
+	}
+
+	message, err := userResourceMessage(searchResourceURI(keyword), &FeedsListResponse{Feeds: feeds, Count: len(feeds)})
+	if err != nil {
+		return nil, err
+	}
+
+	return []MCPPromptMessage{
+		userTextMessage(fmt.Sprintf(`请调研小红书关键词「%s」，下面是搜索结果中排名靠前的 %d 篇笔记。请总结：
+
+1. 热门笔记的选题方向和内容类型
+2. 标题的常见写法和高互动标题的特点
+3. 点赞、收藏、评论数据反映出的用户关注点
+4. 如果要在这个关键词下发布新笔记，给出 3 个选题建议
+
+需要查看某篇笔记的详情时，可以使用 get_feed_detail 工具。`, keyword, len(feeds))),
+		message,
+	}, nil
+}
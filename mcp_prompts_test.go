@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptsGet(t *testing.T) {
+	appServer := NewAppServer(NewXiaohongshuService())
+
+	response := appServer.processJSONRPCRequest(&JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "prompts/get",
+		Params:  map[string]any{"name": "write_note", "arguments": map[string]any{"topic": "露营", "style": "干货分享"}},
+		ID:      1,
+	}, context.Background())
+	require.Nil(t, response.Error)
+
+	messages := response.Result.(map[string]interface{})["messages"].([]MCPPromptMessage)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "user", messages[0].Role)
+	assert.Contains(t, messages[0].Content.Text, "露营")
+	assert.Contains(t, messages[0].Content.Text, "干货分享")
+
+	// 缺少必填参数
+	response = appServer.processJSONRPCRequest(&JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "prompts/get",
+		Params:  map[string]any{"name": "research_keyword"},
+		ID:      2,
+	}, context.Background())
+	require.NotNil(t, response.Error)
+	assert.Equal(t, -32602, response.Error.Code)
+}
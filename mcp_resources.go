@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// MCP 资源：笔记、用户、搜索结果都可以通过 xhs:// URI 寻址
+//
+//	xhs://feeds                         首页推荐
+//	xhs://note/{feedId}?xsec_token=...  笔记详情（含评论）
+//	xhs://user/{userId}                 用户主页（可选 ?xsec_token=...）
+//	xhs://search/{keyword}              搜索结果
+const (
+	resourceScheme = "xhs"
+
+	resourceKindFeeds  = "feeds"
+	resourceKindNote   = "note"
+	resourceKindUser   = "user"
+	resourceKindSearch = "search"
+
+	resourceMimeType = "application/json"
+
+	// errCodeResourceNotFound MCP 约定的资源不存在错误码
+	errCodeResourceNotFound = -32002
+)
+
+// MCPResource 资源描述
+type MCPResource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// MCPResourceTemplate 资源模板
+type MCPResourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// MCPResourceContents 资源内容
+type MCPResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text"`
+}
+
+// resourceURI 解析后的资源 URI
+type resourceURI struct {
+	Kind      string
+	ID        string // feedId、userId 或搜索关键词
+	XsecToken string
+}
+
+// noteResourceURI 笔记资源 URI
+func noteResourceURI(feedID, xsecToken string) string {
+	uri := fmt.Sprintf("%s://%s/%s", resourceScheme, resourceKindNote, url.PathEscape(feedID))
+	if xsecToken != "" {
+		uri += "?xsec_token=" + url.QueryEscape(xsecToken)
+	}
+	return uri
+}
+
+// userResourceURI 用户资源 URI
+func userResourceURI(userID, xsecToken string) string {
+	uri := fmt.Sprintf("%s://%s/%s", resourceScheme, resourceKindUser, url.PathEscape(userID))
+	if xsecToken != "" {
+		uri += "?xsec_token=" + url.QueryEscape(xsecToken)
+	}
+	return uri
+}
+
+// searchResourceURI 搜索结果资源 URI
+func searchResourceURI(keyword string) string {
+	return fmt.Sprintf("%s://%s/%s", resourceScheme, resourceKindSearch, url.PathEscape(keyword))
+}
+
+// parseResourceURI 解析 xhs:// 资源 URI
+func parseResourceURI(raw string) (*resourceURI, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid resource uri")
+	}
+	if u.Scheme != resourceScheme {
+		return nil, errors.Errorf("unsupported scheme: %s", u.Scheme)
+	}
+
+	id := strings.Trim(u.Path, "/")
+	uri := &resourceURI{
+		Kind:      u.Host,
+		ID:        id,
+		XsecToken: u.Query().Get("xsec_token"),
+	}
+
+	switch uri.Kind {
+	case resourceKindFeeds:
+		return uri, nil
+	case resourceKindNote:
+		if id == "" || uri.XsecToken == "" {
+			return nil, errors.New("note uri requires feedId and xsec_token")
+		}
+	case resourceKindUser, resourceKindSearch:
+		if id == "" {
+			return nil, errors.Errorf("%s uri requires an id", uri.Kind)
+		}
+	default:
+		return nil, errors.Errorf("unknown resource kind: %s", uri.Kind)
+	}
+
+	return uri, nil
+}
+
+// resourceTemplates 支持的资源模板
+var resourceTemplates = []MCPResourceTemplate{
+	{
+		URITemplate: "xhs://note/{feedId}?xsec_token={xsecToken}",
+		Name:        "小红书笔记",
+		Description: "笔记详情，包括内容、图片、作者、互动数据及评论。feedId 和 xsecToken 可从 Feed 列表或搜索结果获取",
+		MimeType:    resourceMimeType,
+	},
+	{
+		URITemplate: "xhs://user/{userId}",
+		Name:        "小红书用户",
+		Description: "用户主页，包括基本信息、关注/粉丝/获赞数据及发布的笔记",
+		MimeType:    resourceMimeType,
+	},
+	{
+		URITemplate: "xhs://search/{keyword}",
+		Name:        "小红书搜索",
+		Description: "关键词搜索结果",
+		MimeType:    resourceMimeType,
+	},
+}
+
+// processResourcesList 处理资源列表请求：首页推荐以及当前会话订阅的笔记
+func (s *AppServer) processResourcesList(ctx context.Context, request *JSONRPCRequest) *JSONRPCResponse {
+	resources := []MCPResource{
+		{
+			URI:         fmt.Sprintf("%s://%s", resourceScheme, resourceKindFeeds),
+			Name:        "首页推荐",
+			Description: "小红书首页推荐的笔记列表",
+			MimeType:    resourceMimeType,
+		},
+	}
+
+	if session, ok := sessionFromContext(ctx); ok {
+		for _, uri := range s.resourceWatcher.subscriptions(session) {
+			resources = append(resources, MCPResource{
+				URI:         uri,
+				Name:        "已订阅的笔记",
+				Description: "互动数据变化时会发送 notifications/resources/updated",
+				MimeType:    resourceMimeType,
+			})
+		}
+	}
+
+	return &JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result: map[string]interface{}{
+			"resources": resources,
+		},
+		ID: request.ID,
+	}
+}
+
+// processResourceTemplatesList 处理资源模板列表请求
+func (s *AppServer) processResourceTemplatesList(request *JSONRPCRequest) *JSONRPCResponse {
+	return &JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result: map[string]interface{}{
+			"resourceTemplates": resourceTemplates,
+		},
+		ID: request.ID,
+	}
+}
+
+// processResourcesRead 处理资源读取请求
+func (s *AppServer) processResourcesRead(ctx context.Context, request *JSONRPCRequest) *JSONRPCResponse {
+	var params struct {
+		URI string `json:"uri"`
+	}
+	if err := request.BindParams(&params); err != nil || params.URI == "" {
+		return errorResponse(request, -32602, "Invalid params: uri is required", nil)
+	}
+
+	uri, err := parseResourceURI(params.URI)
+	if err != nil {
+		return errorResponse(request, errCodeResourceNotFound, "Resource not found", map[string]string{
+			"uri":    params.URI,
+			"reason": err.Error(),
+		})
+	}
+
+	logrus.WithContext(ctx).WithField("uri", params.URI).Info("MCP: 读取资源")
+
+	data, err := s.readResource(ctx, uri)
+	if err != nil {
+		return errorResponse(request, -32603, "读取资源失败: "+err.Error(), map[string]string{
+			"uri": params.URI,
+		})
+	}
+
+	jsonData, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return errorResponse(request, -32603, "资源序列化失败: "+err.Error(), nil)
+	}
+
+	return &JSONRPCResponse{
+		JSONRPC: "2.0",
+		Result: map[string]interface{}{
+			"contents": []MCPResourceContents{{
+				URI:      params.URI,
+				MimeType: resourceMimeType,
+				Text:     string(jsonData),
+			}},
+		},
+		ID: request.ID,
+	}
+}
+
+// readResource 根据资源类型调用对应的服务
+func (s *AppServer) readResource(ctx context.Context, uri *resourceURI) (any, error) {
+	switch uri.Kind {
+	case resourceKindFeeds:
+		return s.xiaohongshuService.ListFeeds(ctx)
+	case resourceKindNote:
+		return s.xiaohongshuService.GetFeedDetail(ctx, uri.ID, uri.XsecToken)
+	case resourceKindUser:
+		return s.xiaohongshuService.UserProfile(ctx, uri.ID, uri.XsecToken)
+	case resourceKindSearch:
+		return s.xiaohongshuService.SearchFeeds(ctx, uri.ID)
+	default:
+		return nil, errors.Errorf("unknown resource kind: %s", uri.Kind)
+	}
+}
+
+// processResourcesSubscribe 处理资源订阅请求，目前只支持订阅笔记
+func (s *AppServer) processResourcesSubscribe(ctx context.Context, request *JSONRPCRequest, subscribe bool) *JSONRPCResponse {
+	var params struct {
+		URI string `json:"uri"`
+	}
+	if err := request.BindParams(&params); err != nil || params.URI == "" {
+		return errorResponse(request, -32602, "Invalid params: uri is required", nil)
+	}
+
+	session, ok := sessionFromContext(ctx)
+	if !ok {
+		return errorResponse(request, -32600, "Subscriptions require a session", nil)
+	}
+
+	if !subscribe {
+		s.resourceWatcher.unsubscribe(session, params.URI)
+		return &JSONRPCResponse{JSONRPC: "2.0", Result: map[string]interface{}{}, ID: request.ID}
+	}
+
+	uri, err := parseResourceURI(params.URI)
+	if err != nil || uri.Kind != resourceKindNote {
+		reason := "only note resources can be subscribed"
+		if err != nil {
+			reason = err.Error()
+		}
+		return errorResponse(request, errCodeResourceNotFound, "Resource not found", map[string]string{
+			"uri":    params.URI,
+			"reason": reason,
+		})
+	}
+
+	if err := s.resourceWatcher.subscribe(session, params.URI, uri, accountFromContext(ctx)); err != nil {
+		return errorResponse(request, -32602, "Subscription limit reached", map[string]string{
+			"uri":    params.URI,
+			"reason": err.Error(),
+		})
+	}
+
+	return &JSONRPCResponse{JSONRPC: "2.0", Result: map[string]interface{}{}, ID: request.ID}
+}
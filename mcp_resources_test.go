@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
+)
+
+func TestParseResourceURI(t *testing.T) {
+	uri, err := parseResourceURI(noteResourceURI("note-1", "token+1"))
+	require.NoError(t, err)
+	assert.Equal(t, resourceKindNote, uri.Kind)
+	assert.Equal(t, "note-1", uri.ID)
+	assert.Equal(t, "token+1", uri.XsecToken)
+
+	uri, err = parseResourceURI(searchResourceURI("咖啡 探店"))
+	require.NoError(t, err)
+	assert.Equal(t, resourceKindSearch, uri.Kind)
+	assert.Equal(t, "咖啡 探店", uri.ID)
+
+	uri, err = parseResourceURI("xhs://user/user-1")
+	require.NoError(t, err)
+	assert.Equal(t, resourceKindUser, uri.Kind)
+	assert.Equal(t, "user-1", uri.ID)
+
+	for _, raw := range []string{
+		"https://www.xiaohongshu.com/explore/note-1",
+		"xhs://note/note-1", // 缺少 xsec_token
+		"xhs://search/",
+		"xhs://unknown/1",
+	} {
+		_, err := parseResourceURI(raw)
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestResourcesReadNotFound(t *testing.T) {
+	appServer := NewAppServer(NewXiaohongshuService())
+
+	response := appServer.processJSONRPCRequest(&JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "resources/read",
+		Params:  map[string]any{"uri": "xhs://unknown/1"},
+		ID:      1,
+	}, context.Background())
+
+	require.NotNil(t, response.Error)
+	assert.Equal(t, errCodeResourceNotFound, response.Error.Code)
+}
+
+func TestResourceWatcherNotifiesOnChange(t *testing.T) {
+	stats := xiaohongshu.InteractInfo{LikedCount: "1"}
+	var account string
+	watcher := NewResourceWatcher(func(ctx context.Context, feedID, xsecToken string) (xiaohongshu.InteractInfo, error) {
+		account = accountFromContext(ctx)
+		return stats, nil
+	}, resourceWatchInterval)
+
+	session := newMCPSession("test")
+	rawURI := noteResourceURI("note-1", "token-1")
+	uri, err := parseResourceURI(rawURI)
+	require.NoError(t, err)
+	require.NoError(t, watcher.subscribe(session, rawURI, uri, "alice"))
+	assert.Equal(t, []string{rawURI}, watcher.subscriptions(session))
+
+	// 第一次轮询只记录基准数据，使用订阅方的账号
+	watcher.poll(context.Background())
+	assert.Empty(t, session.stream)
+	assert.Equal(t, "alice", account)
+
+	stats.LikedCount = "2"
+	watcher.poll(context.Background())
+	require.Len(t, session.stream, 1)
+
+	var notification JSONRPCNotification
+	require.NoError(t, json.Unmarshal(<-session.stream, &notification))
+	assert.Equal(t, "notifications/resources/updated", notification.Method)
+	assert.Equal(t, rawURI, notification.Params.(map[string]any)["uri"])
+
+	// 会话结束后订阅被清理
+	session.close()
+	watcher.poll(context.Background())
+	assert.Empty(t, watcher.subscriptions(session))
+}
+
+func TestResourceWatcherSubscriptionLimits(t *testing.T) {
+	watcher := NewResourceWatcher(nil, resourceWatchInterval)
+	subscribe := func(session *MCPSession, id, account string) error {
+		rawURI := noteResourceURI(id, "token")
+		uri, err := parseResourceURI(rawURI)
+		require.NoError(t, err)
+		return watcher.subscribe(session, rawURI, uri, account)
+	}
+
+	first := newMCPSession("first")
+	for i := range maxSessionSubscriptions {
+		require.NoError(t, subscribe(first, fmt.Sprintf("note-%d", i), "alice"))
+	}
+	// 重复订阅不计入上限
+	assert.NoError(t, subscribe(first, "note-0", "alice"))
+	assert.Error(t, subscribe(first, "note-extra", "alice"))
+
+	// 账号的上限由所有会话共享，已被该账号轮询的笔记仍然可以订阅
+	second := newMCPSession("second")
+	assert.Error(t, subscribe(second, "note-extra", "alice"))
+	assert.NoError(t, subscribe(second, "note-0", "alice"))
+	assert.NoError(t, subscribe(second, "note-extra", "bob"))
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// mcpSessionHeader MCP 会话 ID 请求/响应头
+	mcpSessionHeader = "Mcp-Session-Id"
+	// mcpProtocolVersionHeader 初始化之后客户端携带的协议版本头
+	mcpProtocolVersionHeader = "Mcp-Protocol-Version"
+
+	// defaultSessionIdleTimeout 会话空闲过期时间
+	defaultSessionIdleTimeout = 30 * time.Minute
+	// sessionJanitorInterval 过期会话清理间隔
+	sessionJanitorInterval = time.Minute
+)
+
+// supportedProtocolVersions 支持的 MCP 协议版本，第一个为最新版本
+var supportedProtocolVersions = []string{
+	"2025-06-18",
+	"2025-03-26",
+	"2024-11-05",
+}
+
+// negotiateProtocolVersion 协商协议版本：客户端请求的版本受支持则沿用，否则返回服务端最新版本
+func negotiateProtocolVersion(requested string) string {
+	if isSupportedProtocolVersion(requested) {
+		return requested
+	}
+	return supportedProtocolVersions[0]
+}
+
+// isSupportedProtocolVersion 判断协议版本是否受支持
+func isSupportedProtocolVersion(version string) bool {
+	for _, v := range supportedProtocolVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// MCPClientInfo 客户端信息
+type MCPClientInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// MCPSession 单个 MCP 会话的状态
+type MCPSession struct {
+	ID string
+
+	mu              sync.Mutex
+	protocolVersion string
+	clientInfo      MCPClientInfo
+	logLevel        string
+	// owner 创建会话的 API Key 名称，开启认证时会话只能由同一个 Key 使用
+	owner       string
+	initialized bool
+	lastActive  time.Time
+
+	// inflight 进行中的请求，key 为 JSON 编码后的请求 id
+	inflight map[string]*inflightRequest
+
+	// stream 服务端主动推送给客户端的消息（通过 GET 建立的 SSE 连接发送）
+	stream    chan []byte
+	listening bool
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newMCPSession(id string) *MCPSession {
+	return &MCPSession{
+		ID:         id,
+		logLevel:   "info",
+		lastActive: time.Now(),
+		inflight:   make(map[string]*inflightRequest),
+		stream:     make(chan []byte, 64),
+		done:       make(chan struct{}),
+	}
+}
+
+// ProtocolVersion 协商后的协议版本
+func (s *MCPSession) ProtocolVersion() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.protocolVersion
+}
+
+// ClientInfo 客户端信息
+func (s *MCPSession) ClientInfo() MCPClientInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.clientInfo
+}
+
+// LogLevel 会话的日志级别
+func (s *MCPSession) LogLevel() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logLevel
+}
+
+// SetLogLevel 设置会话的日志级别
+func (s *MCPSession) SetLogLevel(level string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logLevel = level
+}
+
+// Initialized 客户端是否已发送 notifications/initialized
+func (s *MCPSession) Initialized() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.initialized
+}
+
+func (s *MCPSession) setInitializeParams(protocolVersion string, clientInfo MCPClientInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.protocolVersion = protocolVersion
+	s.clientInfo = clientInfo
+}
+
+// Owner 创建会话的 API Key 名称，未开启认证时为空
+func (s *MCPSession) Owner() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.owner
+}
+
+func (s *MCPSession) bindOwner(owner string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.owner = owner
+}
+
+func (s *MCPSession) markInitialized() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.initialized = true
+}
+
+func (s *MCPSession) touch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastActive = time.Now()
+}
+
+// idleSince 会话的空闲时长。保持着 SSE 连接的会话仍在接收推送，不算空闲
+func (s *MCPSession) idleSince(now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listening {
+		return 0
+	}
+	return now.Sub(s.lastActive)
+}
+
+// acquireStream 占用会话的推送通道，同一时间只允许一个 SSE 连接
+func (s *MCPSession) acquireStream() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listening {
+		return false
+	}
+	s.listening = true
+	return true
+}
+
+// releaseStream 释放推送通道，空闲时间从连接断开时开始计算
+func (s *MCPSession) releaseStream() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listening = false
+	s.lastActive = time.Now()
+}
+
+// Done 会话终止时关闭
+func (s *MCPSession) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *MCPSession) close() {
+	s.closeOnce.Do(func() { close(s.done) })
+
+	// 会话终止时取消所有进行中的请求
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, req := range s.inflight {
+		req.cancel()
+	}
+}
+
+// inflightRequest 进行中的请求
+type inflightRequest struct {
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+// requestKey 将 JSON-RPC 请求 id 统一编码为 map key
+func requestKey(id any) string {
+	data, _ := json.Marshal(id)
+	return string(data)
+}
+
+// trackRequest 登记进行中的请求，返回可被取消的 ctx，以及请求结束时调用的清理函数。
+// 清理函数返回该请求是否被客户端通过 notifications/cancelled 取消。
+func (s *MCPSession) trackRequest(ctx context.Context, id any) (context.Context, func() bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	key := requestKey(id)
+	req := &inflightRequest{cancel: cancel}
+
+	s.mu.Lock()
+	s.inflight[key] = req
+	s.mu.Unlock()
+
+	// 会话在登记前已终止
+	select {
+	case <-s.done:
+		cancel()
+	default:
+	}
+
+	return ctx, func() bool {
+		cancel()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.inflight[key] == req {
+			delete(s.inflight, key)
+		}
+		return req.cancelled
+	}
+}
+
+// cancelRequest 取消进行中的请求，请求不存在时返回 false
+func (s *MCPSession) cancelRequest(id any) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	req, ok := s.inflight[requestKey(id)]
+	if !ok {
+		return false
+	}
+
+	req.cancelled = true
+	req.cancel()
+	return true
+}
+
+// SessionManager 管理所有 MCP 会话
+type SessionManager struct {
+	mu          sync.RWMutex
+	sessions    map[string]*MCPSession
+	idleTimeout time.Duration
+}
+
+// NewSessionManager 创建会话管理器
+func NewSessionManager(idleTimeout time.Duration) *SessionManager {
+	if idleTimeout <= 0 {
+		idleTimeout = defaultSessionIdleTimeout
+	}
+
+	return &SessionManager{
+		sessions:    make(map[string]*MCPSession),
+		idleTimeout: idleTimeout,
+	}
+}
+
+// Create 创建新会话
+func (m *SessionManager) Create() *MCPSession {
+	session := newMCPSession(newSessionID())
+
+	m.mu.Lock()
+	m.sessions[session.ID] = session
+	m.mu.Unlock()
+
+	logrus.WithField("session", session.ID).Info("MCP 会话已创建")
+	return session
+}
+
+// Get 获取会话并刷新活跃时间，不存在或已过期时返回 false
+func (m *SessionManager) Get(id string) (*MCPSession, bool) {
+	m.mu.RLock()
+	session, ok := m.sessions[id]
+	m.mu.RUnlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	if session.idleSince(time.Now()) > m.idleTimeout {
+		m.Delete(id)
+		return nil, false
+	}
+
+	session.touch()
+	return session, true
+}
+
+// Delete 终止会话
+func (m *SessionManager) Delete(id string) bool {
+	m.mu.Lock()
+	session, ok := m.sessions[id]
+	delete(m.sessions, id)
+	m.mu.Unlock()
+
+	if ok {
+		session.close()
+		logrus.WithField("session", id).Info("MCP 会话已终止")
+	}
+	return ok
+}
+
+// Count 当前会话数量
+func (m *SessionManager) Count() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.sessions)
+}
+
+// RunJanitor 周期性清理空闲过期的会话，直到 ctx 结束
+func (m *SessionManager) RunJanitor(ctx context.Context) {
+	ticker := time.NewTicker(sessionJanitorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.expire(now)
+		}
+	}
+}
+
+// expire 删除空闲时间超过阈值的会话
+func (m *SessionManager) expire(now time.Time) {
+	var expired []string
+
+	m.mu.RLock()
+	for id, session := range m.sessions {
+		if session.idleSince(now) > m.idleTimeout {
+			expired = append(expired, id)
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, id := range expired {
+		m.Delete(id)
+	}
+}
+
+func newSessionID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+type sessionContextKey struct{}
+
+// withSession 将会话放入 context
+func withSession(ctx context.Context, session *MCPSession) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, session)
+}
+
+// sessionFromContext 从 context 获取会话
+func sessionFromContext(ctx context.Context) (*MCPSession, bool) {
+	session, ok := ctx.Value(sessionContextKey{}).(*MCPSession)
+	return session, ok && session != nil
+}
@@ -0,0 +1,183 @@
+// Package mockxhs 模拟小红书创作者中心的发布页，用于在本地对发布流程做集成测试。
+//
+// 页面按真实发布页的 DOM 结构实现「上传视频」「上传图文」「写长文」三个选项卡，
+// 提交的内容记录在 Server 中，不会发布到小红书。通过 Options 可以模拟上传缓慢、
+// 发布被拒绝和滑块验证码。
+package mockxhs
+
+import (
+	_ "embed"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 提交的内容类型，与发布页的选项卡对应
+const (
+	KindVideo    = "video"
+	KindImage    = "image"
+	KindLongText = "longtext"
+)
+
+// PublishPath 发布页路径，与创作者中心相同
+const PublishPath = "/publish/publish"
+
+//go:embed publish.html
+var publishHTML string
+
+var publishTemplate = template.Must(template.New("publish").Parse(publishHTML))
+
+// captchaHTML 滑块验证码页面，包含风控检测使用的 #red-captcha 和提示文字
+const captchaHTML = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>安全验证</title></head>
+<body><div id="red-captcha"><p>请完成验证</p><div class="slider"></div></div></body></html>`
+
+// Options 模拟的页面行为，可以在运行中通过 SetOptions 或 PUT /api/options 修改
+type Options struct {
+	// UploadDelay 每个文件的上传耗时，预览图逐个出现，JSON 中以纳秒表示
+	UploadDelay time.Duration `json:"upload_delay"`
+	// Reject 不为空时拒绝发布，页面弹出该提示
+	Reject string `json:"reject,omitempty"`
+	// Captcha 打开发布页时显示滑块验证码
+	Captcha bool `json:"captcha,omitempty"`
+}
+
+// Submission 一次发布提交
+type Submission struct {
+	Kind    string `json:"kind"`
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	// Files 上传的文件名，浏览器不提供本地路径
+	Files []string `json:"files,omitempty"`
+	// Visibility 可见范围，只有长文会设置
+	Visibility string `json:"visibility,omitempty"`
+	// Rejected 按 Options.Reject 拒绝了这次发布
+	Rejected    bool      `json:"rejected"`
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+// Server 模拟的创作者中心
+type Server struct {
+	mu          sync.Mutex
+	opts        Options
+	submissions []Submission
+	mux         *http.ServeMux
+}
+
+// New 创建模拟的创作者中心
+func New(opts Options) *Server {
+	s := &Server{opts: opts, mux: http.NewServeMux()}
+	s.mux.HandleFunc(PublishPath, s.handlePublishPage)
+	s.mux.HandleFunc("/api/publish", s.handlePublish)
+	s.mux.HandleFunc("/api/submissions", s.handleSubmissions)
+	s.mux.HandleFunc("/api/options", s.handleOptions)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Options 当前的页面行为
+func (s *Server) Options() Options {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.opts
+}
+
+// SetOptions 修改页面行为，对之后打开的页面和提交生效
+func (s *Server) SetOptions(opts Options) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.opts = opts
+}
+
+// Submissions 收到的发布提交，按时间顺序
+func (s *Server) Submissions() []Submission {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Submission(nil), s.submissions...)
+}
+
+// Reset 清空收到的发布提交
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.submissions = nil
+}
+
+func (s *Server) handlePublishPage(w http.ResponseWriter, r *http.Request) {
+	opts := s.Options()
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if opts.Captcha {
+		w.Write([]byte(captchaHTML))
+		return
+	}
+	publishTemplate.Execute(w, struct {
+		UploadDelayMs int64 `json:"uploadDelayMs"`
+	}{opts.UploadDelay.Milliseconds()})
+}
+
+// publishResult 发布接口的返回，与页面脚本约定
+type publishResult struct {
+	Success bool   `json:"success"`
+	Msg     string `json:"msg,omitempty"`
+}
+
+func (s *Server) handlePublish(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var sub Submission
+	if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sub.SubmittedAt = time.Now()
+
+	s.mu.Lock()
+	sub.Rejected = s.opts.Reject != ""
+	result := publishResult{Success: !sub.Rejected, Msg: s.opts.Reject}
+	s.submissions = append(s.submissions, sub)
+	s.mu.Unlock()
+
+	writeJSON(w, result)
+}
+
+func (s *Server) handleSubmissions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, s.Submissions())
+	case http.MethodDelete:
+		s.Reset()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleOptions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, s.Options())
+	case http.MethodPut:
+		var opts Options
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.SetOptions(opts)
+		writeJSON(w, opts)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,96 @@
+package mockxhs
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(body)
+}
+
+func send(t *testing.T, method, url, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(data)
+}
+
+func TestPublishPage(t *testing.T) {
+	s := New(Options{UploadDelay: 1500 * time.Millisecond})
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	code, body := get(t, server.URL+PublishPath+"?source=official")
+	assert.Equal(t, http.StatusOK, code)
+	for _, tab := range []string{"上传视频", "上传图文", "写长文"} {
+		assert.Contains(t, body, `class="creator-tab`)
+		assert.Contains(t, body, tab)
+	}
+	assert.Contains(t, body, `var config = {"uploadDelayMs":1500};`)
+	assert.NotContains(t, body, "red-captcha")
+
+	s.SetOptions(Options{Captcha: true})
+	code, body = get(t, server.URL+PublishPath)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `id="red-captcha"`)
+	assert.NotContains(t, body, "upload-content")
+}
+
+func TestSubmissions(t *testing.T) {
+	s := New(Options{})
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	code, body := send(t, http.MethodPost, server.URL+"/api/publish",
+		`{"kind":"image","title":"标题","content":"正文","files":["1.jpg","2.jpg"]}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"success":true}`, body)
+
+	code, _ = send(t, http.MethodPut, server.URL+"/api/options", `{"reject":"内容违规"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "内容违规", s.Options().Reject)
+
+	_, body = send(t, http.MethodPost, server.URL+"/api/publish", `{"kind":"longtext","title":"长文","content":"正文","visibility":"仅自己可见"}`)
+	assert.JSONEq(t, `{"success":false,"msg":"内容违规"}`, body)
+
+	subs := s.Submissions()
+	require.Len(t, subs, 2)
+	assert.Equal(t, KindImage, subs[0].Kind)
+	assert.Equal(t, []string{"1.jpg", "2.jpg"}, subs[0].Files)
+	assert.False(t, subs[0].Rejected)
+	assert.Equal(t, KindLongText, subs[1].Kind)
+	assert.Equal(t, "仅自己可见", subs[1].Visibility)
+	assert.True(t, subs[1].Rejected)
+
+	_, body = get(t, server.URL+"/api/submissions")
+	var listed []Submission
+	require.NoError(t, json.Unmarshal([]byte(body), &listed))
+	assert.Len(t, listed, 2)
+
+	code, _ = send(t, http.MethodDelete, server.URL+"/api/submissions", "")
+	assert.Equal(t, http.StatusNoContent, code)
+	assert.Empty(t, s.Submissions())
+
+	code, _ = get(t, server.URL+"/api/publish")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+}
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"sync"
+	"time"
+	_ "time/tzdata" // 精简的容器镜像中可能没有时区数据
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Action 受频率限制的操作类型
+type Action string
+
+const (
+	// ActionBrowse 浏览：首页推荐、笔记详情、用户主页。检查登录状态不计入
+	ActionBrowse Action = "browse"
+	// ActionSearch 搜索
+	ActionSearch Action = "search"
+	// ActionPublish 发布图文或长文，失败的发布不计入
+	ActionPublish Action = "publish"
+	// ActionWatch 服务端轮询订阅的笔记，与客户端主动浏览分开计算
+	ActionWatch Action = "watch"
+)
+
+// Duration 配置文件中的时长，使用 "30s"、"10m" 这样的字符串
+type Duration time.Duration
+
+// UnmarshalJSON 实现 json.Unmarshaler
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return errors.Wrap(err, "duration must be a string such as \"30s\"")
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return errors.Wrapf(err, "invalid duration %q", s)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalJSON 实现 json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// ActionLimit 单个账号某类操作的限制，值为 0 表示不限制
+type ActionLimit struct {
+	// RatePerMinute、Burst 令牌桶：每分钟补充的次数和最多可连续执行的次数
+	RatePerMinute float64 `json:"rate_per_minute,omitempty"`
+	Burst         int     `json:"burst,omitempty"`
+	// HourlyCap 最近一小时内的最大次数
+	HourlyCap int `json:"hourly_cap,omitempty"`
+	// DailyCap 每个自然日的最大次数（按 Timezone 计算）
+	DailyCap int `json:"daily_cap,omitempty"`
+	// MinGap、GapJitter 两次操作的最小间隔，实际间隔为 MinGap 加上 [0, GapJitter) 的随机值
+	MinGap    Duration `json:"min_gap,omitempty"`
+	GapJitter Duration `json:"gap_jitter,omitempty"`
+}
+
+// QuietHours 静默时段，[Start, End) 内不执行指定的操作，Start 大于 End 表示跨越午夜
+type QuietHours struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Timezone string `json:"timezone,omitempty"`
+	// Actions 静默时段内禁止的操作，为空表示全部操作
+	Actions []Action `json:"actions,omitempty"`
+}
+
+// PolicyConfig 防封号策略配置
+type PolicyConfig struct {
+	Actions    map[Action]ActionLimit `json:"actions"`
+	QuietHours *QuietHours            `json:"quiet_hours,omitempty"`
+	// Timezone 计算每日上限的时区，默认 Asia/Shanghai
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// DefaultPolicyConfig 默认策略，按普通用户的使用频率设置。未指定 -policy 时生效，
+// 发布每小时最多 2 次、每天 5 次，两次发布间隔 10 ~ 20 分钟
+func DefaultPolicyConfig() *PolicyConfig {
+	return &PolicyConfig{
+		Actions: map[Action]ActionLimit{
+			ActionBrowse: {
+				RatePerMinute: 10, Burst: 5, HourlyCap: 200, DailyCap: 1000,
+				MinGap: Duration(time.Second), GapJitter: Duration(2 * time.Second),
+			},
+			ActionSearch: {
+				RatePerMinute: 4, Burst: 3, HourlyCap: 60, DailyCap: 300,
+				MinGap: Duration(3 * time.Second), GapJitter: Duration(4 * time.Second),
+			},
+			ActionPublish: {
+				HourlyCap: 2, DailyCap: 5,
+				MinGap: Duration(10 * time.Minute), GapJitter: Duration(10 * time.Minute),
+			},
+			ActionWatch: {
+				RatePerMinute: 2, Burst: 2, HourlyCap: 60, DailyCap: 500,
+				MinGap: Duration(5 * time.Second), GapJitter: Duration(10 * time.Second),
+			},
+		},
+	}
+}
+
+// LoadPolicyConfig 从 JSON 文件加载策略，path 为空时使用默认策略
+func LoadPolicyConfig(path string) (*PolicyConfig, error) {
+	if path == "" {
+		return DefaultPolicyConfig(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read policy config")
+	}
+
+	config := &PolicyConfig{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, errors.Wrap(err, "parse policy config")
+	}
+	return config, nil
+}
+
+// RateLimitError 操作被策略拒绝，RetryAfter 后可以重试
+type RateLimitError struct {
+	Account    string
+	Action     Action
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("操作过于频繁（%s）：%s，请在 %s 后重试", e.Action, e.Reason, e.RetryAfter.Round(time.Second))
+}
+
+// actionState 单个账号某类操作的状态
+type actionState struct {
+	tokens      float64
+	refilledAt  time.Time
+	nextAllowed time.Time
+	// last、prevNextAllowed 最近一次操作和它之前的 nextAllowed，退还最近一次操作的额度时恢复
+	last            *Reservation
+	prevNextAllowed time.Time
+	recent          []*Reservation // 最近一小时内的操作
+	day             string
+	dailyCount      int
+}
+
+// Reservation Reserve 为一次操作记录的额度，操作没有完成时通过 Refund 退还
+type Reservation struct {
+	account string
+	action  Action
+	at      time.Time
+	day     string
+	// refunded 已经退还，由 PolicyEngine.mu 保护
+	refunded bool
+}
+
+// PolicyEngine 按账号、操作类型限制访问小红书的频率
+type PolicyEngine struct {
+	mu     sync.Mutex
+	config *PolicyConfig
+	loc    *time.Location
+	quiet  *quietWindow
+	states map[string]*actionState
+
+	now    func() time.Time
+	jitter func(max time.Duration) time.Duration
+}
+
+type quietWindow struct {
+	start, end int // 距午夜的分钟数
+	loc        *time.Location
+	actions    map[Action]bool
+}
+
+// NewPolicyEngine 创建策略引擎
+func NewPolicyEngine(config *PolicyConfig) (*PolicyEngine, error) {
+	for action := range config.Actions {
+		switch action {
+		case ActionBrowse, ActionSearch, ActionPublish, ActionWatch:
+		default:
+			return nil, errors.Errorf("unknown action %q", action)
+		}
+	}
+
+	loc, err := loadLocation(config.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	engine := &PolicyEngine{
+		config: config,
+		loc:    loc,
+		states: make(map[string]*actionState),
+		now:    time.Now,
+		jitter: func(max time.Duration) time.Duration {
+			return time.Duration(rand.Int64N(int64(max)))
+		},
+	}
+
+	if q := config.QuietHours; q != nil {
+		if engine.quiet, err = newQuietWindow(q, loc); err != nil {
+			return nil, err
+		}
+	}
+
+	return engine, nil
+}
+
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		name = "Asia/Shanghai"
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid timezone %q", name)
+	}
+	return loc, nil
+}
+
+func newQuietWindow(q *QuietHours, defaultLoc *time.Location) (*quietWindow, error) {
+	start, err := parseClock(q.Start)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClock(q.End)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := defaultLoc
+	if q.Timezone != "" {
+		if loc, err = loadLocation(q.Timezone); err != nil {
+			return nil, err
+		}
+	}
+
+	var actions map[Action]bool
+	if len(q.Actions) > 0 {
+		actions = make(map[Action]bool, len(q.Actions))
+		for _, a := range q.Actions {
+			actions[a] = true
+		}
+	}
+
+	return &quietWindow{start: start, end: end, loc: loc, actions: actions}, nil
+}
+
+// parseClock 解析 "HH:MM"，返回距午夜的分钟数
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid quiet hours time %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// remaining 当前处于静默时段时，返回距静默结束的时长
+func (q *quietWindow) remaining(action Action, now time.Time) (time.Duration, bool) {
+	if q.actions != nil && !q.actions[action] {
+		return 0, false
+	}
+
+	local := now.In(q.loc)
+	minute := local.Hour()*60 + local.Minute()
+
+	var inside bool
+	if q.start <= q.end {
+		inside = minute >= q.start && minute < q.end
+	} else {
+		inside = minute >= q.start || minute < q.end
+	}
+	if !inside {
+		return 0, false
+	}
+
+	end := time.Date(local.Year(), local.Month(), local.Day(), q.end/60, q.end%60, 0, 0, q.loc)
+	if !end.After(local) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end.Sub(local), true
+}
+
+// Allow 检查账号是否可以执行操作，允许时记录本次操作，否则返回 *RateLimitError
+func (p *PolicyEngine) Allow(account string, action Action) error {
+	_, err := p.Reserve(account, action)
+	return err
+}
+
+// Reserve 同 Allow，允许时返回本次记录的额度，用于之后退还。操作不受限制时返回 nil
+func (p *PolicyEngine) Reserve(account string, action Action) (*Reservation, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	deny := func(reason string, retryAfter time.Duration) (*Reservation, error) {
+		return nil, &RateLimitError{Account: account, Action: action, Reason: reason, RetryAfter: retryAfter}
+	}
+
+	if p.quiet != nil {
+		if retryAfter, ok := p.quiet.remaining(action, now); ok {
+			return deny("当前处于静默时段", retryAfter)
+		}
+	}
+
+	limit, ok := p.config.Actions[action]
+	if !ok {
+		return nil, nil
+	}
+
+	state := p.state(account, action, limit, now)
+
+	if now.Before(state.nextAllowed) {
+		return deny("距上次操作间隔过短", state.nextAllowed.Sub(now))
+	}
+
+	if limit.DailyCap > 0 && state.dailyCount >= limit.DailyCap {
+		local := now.In(p.loc)
+		tomorrow := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, p.loc)
+		return deny(fmt.Sprintf("已达到每日上限 %d 次", limit.DailyCap), tomorrow.Sub(now))
+	}
+
+	if limit.HourlyCap > 0 && len(state.recent) >= limit.HourlyCap {
+		return deny(fmt.Sprintf("已达到每小时上限 %d 次", limit.HourlyCap), state.recent[0].at.Add(time.Hour).Sub(now))
+	}
+
+	if limit.RatePerMinute > 0 && state.tokens < 1 {
+		wait := time.Duration((1 - state.tokens) / limit.RatePerMinute * float64(time.Minute))
+		return deny(fmt.Sprintf("超过每分钟 %g 次的速率限制", limit.RatePerMinute), wait)
+	}
+
+	// 全部检查通过后才记录，被拒绝的请求不占用额度
+	if limit.RatePerMinute > 0 {
+		state.tokens--
+	}
+	r := &Reservation{account: account, action: action, at: now, day: state.day}
+	state.recent = append(state.recent, r)
+	state.dailyCount++
+	state.last = r
+	state.prevNextAllowed = state.nextAllowed
+	state.nextAllowed = now.Add(time.Duration(limit.MinGap))
+	if limit.GapJitter > 0 {
+		state.nextAllowed = state.nextAllowed.Add(p.jitter(time.Duration(limit.GapJitter)))
+	}
+
+	return r, nil
+}
+
+// Refund 退还 Reserve 记录的额度，用于操作没有完成的情况，
+// 例如参数错误、图片下载失败、未登录或被平台拒绝的发布。
+// 只退还这一次记录的额度，同一账号并发的其他操作不受影响；r 为 nil 或已经退还时忽略
+func (p *PolicyEngine) Refund(r *Reservation) {
+	if r == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if r.refunded {
+		return
+	}
+	r.refunded = true
+
+	limit, ok := p.config.Actions[r.action]
+	if !ok {
+		return
+	}
+	state, ok := p.states[r.account+"/"+string(r.action)]
+	if !ok {
+		return
+	}
+
+	if limit.RatePerMinute > 0 {
+		state.tokens = math.Min(float64(max(limit.Burst, 1)), state.tokens+1)
+	}
+	// 超过一小时的记录已经被清理，不再影响每小时上限
+	if i := slices.Index(state.recent, r); i >= 0 {
+		state.recent = slices.Delete(state.recent, i, i+1)
+	}
+	if state.day == r.day && state.dailyCount > 0 {
+		state.dailyCount--
+	}
+	// 之后又有操作时，最小间隔从之后的操作算起，不恢复
+	if state.last == r {
+		state.nextAllowed = state.prevNextAllowed
+	}
+}
+
+// state 获取并刷新账号的操作状态：补充令牌、清理一小时前的记录、跨天重置计数
+func (p *PolicyEngine) state(account string, action Action, limit ActionLimit, now time.Time) *actionState {
+	key := account + "/" + string(action)
+	state, ok := p.states[key]
+	if !ok {
+		state = &actionState{tokens: float64(max(limit.Burst, 1)), refilledAt: now}
+		p.states[key] = state
+	}
+
+	if limit.RatePerMinute > 0 {
+		elapsed := now.Sub(state.refilledAt).Minutes()
+		state.tokens = math.Min(float64(max(limit.Burst, 1)), state.tokens+elapsed*limit.RatePerMinute)
+		state.refilledAt = now
+	}
+
+	cutoff := now.Add(-time.Hour)
+	i := 0
+	for i < len(state.recent) && !state.recent[i].at.After(cutoff) {
+		i++
+	}
+	state.recent = state.recent[i:]
+
+	if day := now.In(p.loc).Format(time.DateOnly); day != state.day {
+		state.day = day
+		state.dailyCount = 0
+	}
+
+	return state
+}
+
+// SetPolicy 设置防封号策略，为空表示不限制
+func (s *XiaohongshuService) SetPolicy(policy *PolicyEngine) {
+	s.policy = policy
+}
+
+// checkPolicy 按 context 中的账号检查操作是否被允许：账号未因风控暂停，且没有超出频率限制
+func (s *XiaohongshuService) checkPolicy(ctx context.Context, action Action) error {
+	_, err := s.reservePolicy(ctx, action)
+	return err
+}
+
+// reservePolicy 同 checkPolicy，返回本次记录的额度，操作没有完成时通过 refundPolicy 退还
+func (s *XiaohongshuService) reservePolicy(ctx context.Context, action Action) (*Reservation, error) {
+	if err := s.checkBreaker(ctx); err != nil {
+		return nil, err
+	}
+
+	if s.policy == nil {
+		return nil, nil
+	}
+
+	account := accountFromContext(ctx)
+
+	reservation, err := s.policy.Reserve(account, action)
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"account": account,
+			"action":  action,
+		}).Warn(err.Error())
+		return nil, err
+	}
+	return reservation, nil
+}
+
+// refundPolicy 退还 reservePolicy 记录的额度
+func (s *XiaohongshuService) refundPolicy(reservation *Reservation) {
+	if s.policy != nil {
+		s.policy.Refund(reservation)
+	}
+}
+
+// checkBreaker 检查 context 中的账号是否因风控暂停，不占用频率限制的额度
+func (s *XiaohongshuService) checkBreaker(ctx context.Context) error {
+	if s.breaker == nil {
+		return nil
+	}
+	return s.breaker.Check(accountFromContext(ctx))
+}
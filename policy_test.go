@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPolicyEngine(t *testing.T, config *PolicyConfig, now *time.Time) *PolicyEngine {
+	t.Helper()
+
+	engine, err := NewPolicyEngine(config)
+	require.NoError(t, err)
+	engine.now = func() time.Time { return *now }
+	engine.jitter = func(max time.Duration) time.Duration { return max / 2 }
+	return engine
+}
+
+func requireRateLimited(t *testing.T, err error) *RateLimitError {
+	t.Helper()
+
+	var rateLimitErr *RateLimitError
+	require.True(t, errors.As(err, &rateLimitErr), "expected RateLimitError, got %v", err)
+	return rateLimitErr
+}
+
+func TestPolicyTokenBucket(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	engine := newTestPolicyEngine(t, &PolicyConfig{Actions: map[Action]ActionLimit{
+		ActionSearch: {RatePerMinute: 2, Burst: 2},
+	}}, &now)
+
+	require.NoError(t, engine.Allow("alice", ActionSearch))
+	require.NoError(t, engine.Allow("alice", ActionSearch))
+
+	err := requireRateLimited(t, engine.Allow("alice", ActionSearch))
+	assert.Equal(t, ActionSearch, err.Action)
+	assert.Equal(t, 30*time.Second, err.RetryAfter)
+
+	// 每个账号独立计算
+	require.NoError(t, engine.Allow("bob", ActionSearch))
+	// 未配置的操作不限制
+	require.NoError(t, engine.Allow("alice", ActionBrowse))
+
+	now = now.Add(30 * time.Second)
+	require.NoError(t, engine.Allow("alice", ActionSearch))
+}
+
+func TestPolicyMinGapAndCaps(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	engine := newTestPolicyEngine(t, &PolicyConfig{
+		Timezone: "UTC",
+		Actions: map[Action]ActionLimit{
+			ActionPublish: {
+				HourlyCap: 2, DailyCap: 3,
+				MinGap: Duration(10 * time.Minute), GapJitter: Duration(10 * time.Minute),
+			},
+		},
+	}, &now)
+
+	require.NoError(t, engine.Allow("", ActionPublish))
+
+	// 间隔为 MinGap 加上随机值
+	err := requireRateLimited(t, engine.Allow("", ActionPublish))
+	assert.Equal(t, 15*time.Minute, err.RetryAfter)
+
+	now = now.Add(15 * time.Minute)
+	require.NoError(t, engine.Allow("", ActionPublish))
+
+	now = now.Add(20 * time.Minute)
+	err = requireRateLimited(t, engine.Allow("", ActionPublish))
+	assert.Contains(t, err.Reason, "每小时")
+	assert.Equal(t, 25*time.Minute, err.RetryAfter)
+
+	now = now.Add(25 * time.Minute)
+	require.NoError(t, engine.Allow("", ActionPublish))
+
+	now = now.Add(time.Hour)
+	err = requireRateLimited(t, engine.Allow("", ActionPublish))
+	assert.Contains(t, err.Reason, "每日")
+	assert.Equal(t, 10*time.Hour, err.RetryAfter)
+
+	// 第二天重新计数
+	now = now.Add(10 * time.Hour)
+	require.NoError(t, engine.Allow("", ActionPublish))
+}
+
+func TestPolicyQuietHours(t *testing.T) {
+	now := time.Date(2025, 1, 1, 23, 30, 0, 0, time.UTC)
+	engine := newTestPolicyEngine(t, &PolicyConfig{
+		QuietHours: &QuietHours{Start: "23:00", End: "07:00", Timezone: "UTC", Actions: []Action{ActionPublish}},
+	}, &now)
+
+	err := requireRateLimited(t, engine.Allow("", ActionPublish))
+	assert.Equal(t, 7*time.Hour+30*time.Minute, err.RetryAfter)
+	require.NoError(t, engine.Allow("", ActionSearch))
+
+	now = time.Date(2025, 1, 2, 6, 59, 0, 0, time.UTC)
+	err = requireRateLimited(t, engine.Allow("", ActionPublish))
+	assert.Equal(t, time.Minute, err.RetryAfter)
+
+	now = time.Date(2025, 1, 2, 7, 0, 0, 0, time.UTC)
+	require.NoError(t, engine.Allow("", ActionPublish))
+}
+
+func TestPolicyConfigValidation(t *testing.T) {
+	_, err := NewPolicyEngine(&PolicyConfig{Actions: map[Action]ActionLimit{"comment": {}}})
+	assert.Error(t, err)
+
+	_, err = NewPolicyEngine(&PolicyConfig{QuietHours: &QuietHours{Start: "25:00", End: "07:00"}})
+	assert.Error(t, err)
+
+	var config PolicyConfig
+	require.NoError(t, json.Unmarshal([]byte(`{"actions":{"publish":{"daily_cap":3,"min_gap":"10m"}}}`), &config))
+	assert.Equal(t, Duration(10*time.Minute), config.Actions[ActionPublish].MinGap)
+
+	_, err = NewPolicyEngine(DefaultPolicyConfig())
+	assert.NoError(t, err)
+}
+
+func TestRESTRateLimited(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	service := NewXiaohongshuService()
+	service.SetPolicy(newTestPolicyEngine(t, &PolicyConfig{
+		QuietHours: &QuietHours{Start: "11:00", End: "13:00", Timezone: "UTC"},
+	}, &now))
+
+	router := setupRoutes(NewAppServer(service))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/feeds/list", nil))
+
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "RATE_LIMITED")
+	assert.Equal(t, "3600", recorder.Header().Get("Retry-After"))
+}
+
+func TestPolicyRefund(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	engine := newTestPolicyEngine(t, &PolicyConfig{
+		Timezone: "UTC",
+		Actions: map[Action]ActionLimit{
+			ActionPublish: {RatePerMinute: 1, HourlyCap: 1, DailyCap: 1, MinGap: Duration(10 * time.Minute)},
+		},
+	}, &now)
+
+	reservation, err := engine.Reserve("", ActionPublish)
+	require.NoError(t, err)
+	requireRateLimited(t, engine.Allow("", ActionPublish))
+
+	// 退还后间隔、速率和上限都不受影响
+	engine.Refund(reservation)
+	require.NoError(t, engine.Allow("", ActionPublish))
+	requireRateLimited(t, engine.Allow("", ActionPublish))
+
+	// 重复退还和不受限制的操作不做任何事
+	engine.Refund(reservation)
+	requireRateLimited(t, engine.Allow("", ActionPublish))
+	unlimited, err := engine.Reserve("", ActionSearch)
+	require.NoError(t, err)
+	assert.Nil(t, unlimited)
+	engine.Refund(unlimited)
+}
+
+func TestPolicyRefundOwnReservation(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	engine := newTestPolicyEngine(t, &PolicyConfig{
+		Timezone: "UTC",
+		Actions: map[Action]ActionLimit{
+			ActionPublish: {HourlyCap: 2},
+		},
+	}, &now)
+
+	first, err := engine.Reserve("", ActionPublish)
+	require.NoError(t, err)
+	now = now.Add(10 * time.Minute)
+	_, err = engine.Reserve("", ActionPublish)
+	require.NoError(t, err)
+
+	// 退还较早的一次，较晚的一次仍然占用额度
+	engine.Refund(first)
+	require.NoError(t, engine.Allow("", ActionPublish))
+
+	err = engine.Allow("", ActionPublish)
+	var rateLimitErr *RateLimitError
+	require.ErrorAs(t, err, &rateLimitErr)
+	assert.Equal(t, time.Hour, rateLimitErr.RetryAfter)
+}
+
+func TestPublishFailureRefundsQuota(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	service := NewXiaohongshuService()
+	service.SetPolicy(newTestPolicyEngine(t, &PolicyConfig{Actions: map[Action]ActionLimit{
+		ActionPublish: {HourlyCap: 1, MinGap: Duration(10 * time.Minute)},
+	}}, &now))
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	// 图片下载失败发生在打开浏览器之前，不占用发布额度
+	req := &PublishRequest{Title: "标题", Content: "正文", Images: []string{server.URL + "/a.jpg"}}
+	for range 2 {
+		_, err := service.PublishContent(context.Background(), req)
+		require.Error(t, err)
+		var rateLimitErr *RateLimitError
+		assert.False(t, errors.As(err, &rateLimitErr), "unexpected rate limit: %v", err)
+	}
+}
+
+func TestNoteStatsUsesWatchPolicy(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	service := NewXiaohongshuService()
+	policy := newTestPolicyEngine(t, &PolicyConfig{Actions: map[Action]ActionLimit{
+		ActionBrowse: {HourlyCap: 1},
+		ActionWatch:  {HourlyCap: 1},
+	}}, &now)
+	service.SetPolicy(policy)
+
+	// 订阅轮询计入 watch 额度，已取消的 ctx 在打开浏览器之前返回
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := service.noteStats(ctx, "note-1", "token-1")
+	require.ErrorIs(t, err, context.Canceled)
+
+	_, err = service.noteStats(ctx, "note-1", "token-1")
+	var rateLimitErr *RateLimitError
+	require.ErrorAs(t, err, &rateLimitErr)
+	assert.Equal(t, ActionWatch, rateLimitErr.Action)
+
+	// 不占用客户端的浏览额度
+	assert.NoError(t, policy.Allow("", ActionBrowse))
+}
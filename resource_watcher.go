@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
+)
+
+// resourceWatchInterval 订阅笔记的轮询间隔
+const resourceWatchInterval = 5 * time.Minute
+
+const (
+	// maxSessionSubscriptions 每个会话最多订阅的笔记数
+	maxSessionSubscriptions = 5
+	// maxAccountSubscriptions 每个小红书账号最多轮询的笔记数，所有会话合计
+	maxAccountSubscriptions = 5
+)
+
+// noteStatsFetcher 获取笔记互动数据
+type noteStatsFetcher func(ctx context.Context, feedID, xsecToken string) (xiaohongshu.InteractInfo, error)
+
+// watchedNote 被订阅的笔记
+type watchedNote struct {
+	uri *resourceURI
+	// subscribers 订阅的会话，值为会话调用方对应的小红书账号
+	subscribers map[*MCPSession]string
+	stats       xiaohongshu.InteractInfo
+	hasStats    bool
+}
+
+// ResourceWatcher 定期轮询被订阅的笔记，互动数据变化时通知订阅的会话
+type ResourceWatcher struct {
+	mu       sync.Mutex
+	notes    map[string]*watchedNote // key 为资源 URI
+	fetch    noteStatsFetcher
+	interval time.Duration
+}
+
+// NewResourceWatcher 创建资源订阅管理器，由 Run 开始轮询
+func NewResourceWatcher(fetch noteStatsFetcher, interval time.Duration) *ResourceWatcher {
+	return &ResourceWatcher{
+		notes:    make(map[string]*watchedNote),
+		fetch:    fetch,
+		interval: interval,
+	}
+}
+
+// subscribe 会话订阅笔记资源，轮询时使用订阅方的小红书账号。
+// 超过会话或账号的订阅上限时返回错误，重复订阅同一笔记不计入
+func (w *ResourceWatcher) subscribe(session *MCPSession, rawURI string, uri *resourceURI, account string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	note, ok := w.notes[rawURI]
+	if ok {
+		if _, subscribed := note.subscribers[session]; subscribed {
+			return nil
+		}
+	}
+
+	var sessionCount, accountCount int
+	for _, n := range w.notes {
+		if _, subscribed := n.subscribers[session]; subscribed {
+			sessionCount++
+		}
+		if containsAccount(n.subscribers, account) {
+			accountCount++
+		}
+	}
+	if sessionCount >= maxSessionSubscriptions {
+		return errors.Errorf("each session can subscribe to at most %d notes", maxSessionSubscriptions)
+	}
+	// 其他会话已经用同一账号订阅了这篇笔记时不增加轮询
+	if accountCount >= maxAccountSubscriptions && !(ok && containsAccount(note.subscribers, account)) {
+		return errors.Errorf("each account can subscribe to at most %d notes", maxAccountSubscriptions)
+	}
+
+	if !ok {
+		note = &watchedNote{uri: uri, subscribers: make(map[*MCPSession]string)}
+		w.notes[rawURI] = note
+	}
+	note.subscribers[session] = account
+	return nil
+}
+
+func containsAccount(subscribers map[*MCPSession]string, account string) bool {
+	for _, a := range subscribers {
+		if a == account {
+			return true
+		}
+	}
+	return false
+}
+
+// unsubscribe 会话取消订阅
+func (w *ResourceWatcher) unsubscribe(session *MCPSession, rawURI string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	note, ok := w.notes[rawURI]
+	if !ok {
+		return
+	}
+	delete(note.subscribers, session)
+	if len(note.subscribers) == 0 {
+		delete(w.notes, rawURI)
+	}
+}
+
+// subscriptions 会话订阅的资源 URI
+func (w *ResourceWatcher) subscriptions(session *MCPSession) []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var uris []string
+	for rawURI, note := range w.notes {
+		if _, ok := note.subscribers[session]; ok {
+			uris = append(uris, rawURI)
+		}
+	}
+	return uris
+}
+
+// Run 周期性轮询被订阅的笔记，直到 ctx 结束。没有订阅时不打开浏览器
+func (w *ResourceWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.poll(ctx)
+		}
+	}
+}
+
+// poll 检查所有被订阅的笔记，并清理已结束会话的订阅
+func (w *ResourceWatcher) poll(ctx context.Context) {
+	w.mu.Lock()
+	type pendingNote struct {
+		uri     *resourceURI
+		account string
+	}
+	pending := make(map[string]pendingNote, len(w.notes))
+	for rawURI, note := range w.notes {
+		for session, account := range note.subscribers {
+			select {
+			case <-session.Done():
+				delete(note.subscribers, session)
+			default:
+				// 互动数据对所有账号相同，使用任一订阅方的账号即可
+				pending[rawURI] = pendingNote{uri: note.uri, account: account}
+			}
+		}
+		if len(note.subscribers) == 0 {
+			delete(w.notes, rawURI)
+		}
+	}
+	w.mu.Unlock()
+
+	for rawURI, p := range pending {
+		stats, err := w.fetchNote(withAccount(ctx, p.account), p.uri)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logrus.WithError(err).Warnf("轮询订阅笔记失败: %s", rawURI)
+			continue
+		}
+
+		for _, session := range w.update(rawURI, stats) {
+			session.notify("notifications/resources/updated", map[string]any{"uri": rawURI})
+		}
+	}
+}
+
+// fetchNote 获取笔记的互动数据。只是两次轮询间隔过短等很快就会放行的限制时等待后重试一次，
+// 其余限制和账号暂停留到下一轮
+func (w *ResourceWatcher) fetchNote(ctx context.Context, uri *resourceURI) (xiaohongshu.InteractInfo, error) {
+	stats, err := w.fetch(ctx, uri.ID, uri.XsecToken)
+
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) || rateLimitErr.RetryAfter >= w.interval {
+		return stats, err
+	}
+
+	timer := time.NewTimer(rateLimitErr.RetryAfter)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return xiaohongshu.InteractInfo{}, ctx.Err()
+	case <-timer.C:
+	}
+	return w.fetch(ctx, uri.ID, uri.XsecToken)
+}
+
+// update 记录最新的互动数据，数据变化时返回需要通知的会话
+func (w *ResourceWatcher) update(rawURI string, stats xiaohongshu.InteractInfo) []*MCPSession {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	note, ok := w.notes[rawURI]
+	if !ok {
+		return nil
+	}
+
+	changed := note.hasStats && !sameNoteStats(note.stats, stats)
+	note.stats = stats
+	note.hasStats = true
+	if !changed {
+		return nil
+	}
+
+	sessions := make([]*MCPSession, 0, len(note.subscribers))
+	for session := range note.subscribers {
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+func sameNoteStats(a, b xiaohongshu.InteractInfo) bool {
+	return a.LikedCount == b.LikedCount &&
+		a.CollectedCount == b.CollectedCount &&
+		a.CommentCount == b.CommentCount &&
+		a.SharedCount == b.SharedCount
+}
@@ -18,22 +18,29 @@ func setupRoutes(appServer *AppServer) *gin.Engine {
 	router.Use(corsMiddleware())
 
 	// 健康检查
-	router.GET("/health", healthHandler)
+	router.GET("/health", appServer.healthHandler)
+
+	// 巡检监控指标
+	router.GET("/metrics", appServer.metricsHandler)
 
 	// MCP 端点 - 使用 Streamable HTTP 协议
 	mcpHandler := appServer.StreamableHTTPHandler()
 	router.Any("/mcp", gin.WrapH(mcpHandler))
 	router.Any("/mcp/*path", gin.WrapH(mcpHandler))
 
+	// 人工接管页面
+	appServer.setupTakeoverRoutes(router)
+
 	// API 路由组
-	api := router.Group("/api/v1")
+	api := router.Group("/api/v1", appServer.authMiddleware())
 	{
-		api.GET("/login/status", appServer.checkLoginStatusHandler)
-		api.POST("/publish", appServer.publishHandler)
-		api.POST("/publish-longtext", appServer.publishLongTextHandler)
-		api.GET("/feeds/list", appServer.listFeedsHandler)
-		api.GET("/feeds/search", appServer.searchFeedsHandler)
-		api.POST("/feeds/detail", appServer.getFeedDetailHandler)
+		// 每个工具对应一个 REST 接口
+		for _, tool := range appServer.tools.List() {
+			api.Handle(tool.Method, tool.Path, appServer.toolHandler(tool))
+		}
+
+		// 下载失败现场
+		api.GET("/artifacts/:id", appServer.artifactDownloadHandler)
 	}
 
 	return router
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError 单个参数的校验错误
+type FieldError struct {
+	// Field 参数路径，例如 title、images[0]
+	Field string `json:"field"`
+	// Rule 未通过的 JSON Schema 规则，例如 required、type、maxLength
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationError 参数校验失败，包含所有不合法的参数
+type ValidationError struct {
+	Fields []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, field.Field+": "+field.Message)
+	}
+	return "参数校验失败: " + strings.Join(messages, "; ")
+}
+
+// validateSchema 按 JSON Schema 校验 JSON 解码后的值，返回所有不合法的字段
+//
+// 支持 type、required、properties、items、enum、minLength/maxLength、
+// minItems/maxItems、minimum/maximum，足够覆盖 schemaFor 生成的 schema
+func validateSchema(schema map[string]any, value any) []FieldError {
+	var errs []FieldError
+	validateValue(schema, value, "", &errs)
+	return errs
+}
+
+func validateValue(schema map[string]any, value any, path string, errs *[]FieldError) {
+	addError := func(rule, format string, args ...any) {
+		*errs = append(*errs, FieldError{Field: path, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if expected, ok := schema["type"].(string); ok && !matchesType(expected, value) {
+		addError("type", "类型应为 %s，实际为 %s", expected, jsonTypeName(value))
+		return
+	}
+
+	if enum, ok := schema["enum"].([]string); ok {
+		s, _ := value.(string)
+		if !containsString(enum, s) {
+			addError("enum", "取值应为 %s 之一", strings.Join(enum, ", "))
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if n, ok := schemaInt(schema, "minLength"); ok && length < n {
+			addError("minLength", "长度不能少于 %d 个字符", n)
+		}
+		if n, ok := schemaInt(schema, "maxLength"); ok && length > n {
+			addError("maxLength", "长度不能超过 %d 个字符，当前为 %d", n, length)
+		}
+	case float64:
+		if n, ok := schemaInt(schema, "minimum"); ok && v < float64(n) {
+			addError("minimum", "不能小于 %d", n)
+		}
+		if n, ok := schemaInt(schema, "maximum"); ok && v > float64(n) {
+			addError("maximum", "不能大于 %d", n)
+		}
+	case []any:
+		if n, ok := schemaInt(schema, "minItems"); ok && len(v) < n {
+			addError("minItems", "至少需要 %d 项", n)
+		}
+		if n, ok := schemaInt(schema, "maxItems"); ok && len(v) > n {
+			addError("maxItems", "最多 %d 项", n)
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				validateValue(items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	case map[string]any:
+		required, _ := schema["required"].([]string)
+		for _, name := range required {
+			if field, ok := v[name]; !ok || field == nil {
+				*errs = append(*errs, FieldError{Field: joinFieldPath(path, name), Rule: "required", Message: "缺少必填参数"})
+			}
+		}
+
+		properties, _ := schema["properties"].(map[string]any)
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := properties[name].(map[string]any)
+			if !ok || v[name] == nil {
+				continue
+			}
+			validateValue(property, v[name], joinFieldPath(path, name), errs)
+		}
+	}
+}
+
+// matchesType 判断 JSON 解码后的值是否符合 JSON Schema 类型
+func matchesType(expected string, value any) bool {
+	switch expected {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		v, ok := value.(float64)
+		return ok && v == math.Trunc(v)
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	}
+	return true
+}
+
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return reflect.TypeOf(value).String()
+}
+
+func schemaInt(schema map[string]any, key string) (int, bool) {
+	n, ok := schema[key].(int)
+	return n, ok
+}
+
+func joinFieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
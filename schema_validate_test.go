@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateSchema(t *testing.T) {
+	schema := schemaFor[PublishRequest]()
+
+	errs := validateSchema(schema, map[string]any{
+		"title":  strings.Repeat("长", 21),
+		"images": []any{"a.jpg", float64(1)},
+	})
+
+	assert.ElementsMatch(t, []FieldError{
+		{Field: "content", Rule: "required", Message: "缺少必填参数"},
+		{Field: "title", Rule: "maxLength", Message: "长度不能超过 20 个字符，当前为 21"},
+		{Field: "images[1]", Rule: "type", Message: "类型应为 string，实际为 number"},
+	}, errs)
+
+	assert.Empty(t, validateSchema(schema, map[string]any{
+		"title":   "标题",
+		"content": "正文",
+		"images":  []any{"a.jpg"},
+	}))
+
+	errs = validateSchema(schema, map[string]any{"title": "标题", "content": "正文", "images": []any{}})
+	require.Len(t, errs, 1)
+	assert.Equal(t, "minItems", errs[0].Rule)
+}
+
+func TestValidateSchemaEnumAndInteger(t *testing.T) {
+	type input struct {
+		Sort  string `json:"sort" binding:"oneof=general latest"`
+		Count int    `json:"count" binding:"min=1,max=20"`
+	}
+	schema := schemaFor[input]()
+
+	errs := validateSchema(schema, map[string]any{"sort": "hot", "count": 1.5})
+	require.Len(t, errs, 2)
+	assert.Equal(t, "count", errs[0].Field)
+	assert.Equal(t, "type", errs[0].Rule)
+	assert.Equal(t, "sort", errs[1].Field)
+	assert.Equal(t, "enum", errs[1].Rule)
+
+	errs = validateSchema(schema, map[string]any{"sort": "latest", "count": float64(30)})
+	require.Len(t, errs, 1)
+	assert.Equal(t, "maximum", errs[0].Rule)
+}
+
+func TestToolCallInvalidParams(t *testing.T) {
+	appServer := NewAppServer(NewXiaohongshuService())
+
+	response := appServer.processJSONRPCRequest(&JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "tools/call",
+		Params: map[string]any{
+			"name":      "publish_content",
+			"arguments": map[string]any{"content": "正文", "images": []any{1}},
+		},
+		ID: 1,
+	}, context.Background())
+
+	require.NotNil(t, response.Error)
+	assert.Equal(t, -32602, response.Error.Code)
+
+	data, err := json.Marshal(response.Error.Data)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"errors":[
+		{"field":"title","rule":"required","message":"缺少必填参数"},
+		{"field":"images[0]","rule":"type","message":"类型应为 string，实际为 number"}
+	]}`, string(data))
+}
@@ -2,15 +2,32 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
+	"github.com/go-rod/rod"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 	"github.com/xpzouying/xiaohongshu-mcp/browser"
 	"github.com/xpzouying/xiaohongshu-mcp/configs"
+	"github.com/xpzouying/xiaohongshu-mcp/cookies"
 	"github.com/xpzouying/xiaohongshu-mcp/pkg/downloader"
 	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
 )
 
 // XiaohongshuService 小红书业务服务
-type XiaohongshuService struct{}
+type XiaohongshuService struct {
+	// policy 防封号策略，为空表示不限制
+	policy *PolicyEngine
+	// breaker 账号熔断器，为空表示检测到风控时不暂停账号
+	breaker *CircuitBreaker
+	// takeover 人工接管，为空表示遇到风控页面时直接返回错误
+	takeover *TakeoverManager
+	// artifacts 失败现场存储，为空表示失败时不保存现场
+	artifacts *ArtifactStore
+	// logins 等待扫码的登录，每个账号最多一个
+	logins loginSessions
+}
 
 // NewXiaohongshuService 创建小红书服务实例
 func NewXiaohongshuService() *XiaohongshuService {
@@ -19,28 +36,38 @@ func NewXiaohongshuService() *XiaohongshuService {
 
 // PublishRequest 发布请求
 type PublishRequest struct {
-	Title   string   `json:"title" binding:"required"`
-	Content string   `json:"content" binding:"required"`
-	Images  []string `json:"images" binding:"required,min=1"`
+	Title   string   `json:"title" binding:"required,max=20" description:"内容标题，小红书要求不超过 20 个字"`
+	Content string   `json:"content" binding:"required" description:"正文内容，支持话题标签"`
+	Images  []string `json:"images" binding:"required,min=1" description:"图片路径列表，支持本地路径或URL"`
 }
 
 // PublishLongTextRequest 长文发布请求
 type PublishLongTextRequest struct {
-	Title   string `json:"title" binding:"required"`
-	Content string `json:"content" binding:"required"`
+	Title   string `json:"title" binding:"required" description:"长文标题"`
+	Content string `json:"content" binding:"required" description:"长文内容"`
 }
 
 // LoginStatusResponse 登录状态响应
 type LoginStatusResponse struct {
-	IsLoggedIn bool   `json:"is_logged_in"`
-	Username   string `json:"username,omitempty"`
+	IsLoggedIn bool   `json:"is_logged_in" description:"是否已登录"`
+	Username   string `json:"username,omitempty" description:"账号名称"`
+}
+
+// LoginQrcodeResponse 登录二维码响应
+type LoginQrcodeResponse struct {
+	IsLoggedIn bool   `json:"is_logged_in" description:"是否已登录，已登录时不返回二维码"`
+	Timeout    string `json:"timeout,omitempty" description:"二维码有效时间"`
+	Img        string `json:"img,omitempty" description:"data URL 格式的二维码图片"`
 }
 
+// loginQrcodeTimeout 等待扫码登录的最长时间
+const loginQrcodeTimeout = 4 * time.Minute
+
 // PublishResponse 发布响应
 type PublishResponse struct {
 	Title   string `json:"title"`
 	Content string `json:"content"`
-	Images  int    `json:"images"`
+	Images  int    `json:"images" description:"发布的图片数量"`
 	Status  string `json:"status"`
 	PostID  string `json:"post_id,omitempty"`
 }
@@ -51,17 +78,103 @@ type FeedsListResponse struct {
 	Count int                `json:"count"`
 }
 
-// CheckLoginStatus 检查登录状态
-func (s *XiaohongshuService) CheckLoginStatus(ctx context.Context) (*LoginStatusResponse, error) {
-	b := browser.NewBrowser(configs.IsHeadless())
-	defer b.Close()
+// BrowserActionError 浏览器操作失败，附带失败时的页面截图和失败现场 ID
+type BrowserActionError struct {
+	Err        error
+	Screenshot []byte
+	ArtifactID string
+}
 
-	page := b.NewPage()
+func (e *BrowserActionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *BrowserActionError) Unwrap() error {
+	return e.Err
+}
+
+// withBrowserPage 启动浏览器并打开新页面执行 fn，结束后关闭页面和浏览器。
+// rod 的 Must* 方法出错时会 panic，这里统一转换为 error；请求被取消时返回取消原因，
+// 其他失败会截取当前页面，以 *BrowserActionError 返回。fn 可能在人工接管后被重新执行。
+// 开启失败现场记录时，fn 收到的 ctx 关联了记录器，使用它输出的日志会写入步骤日志。
+// fn 只通过 driver 操作页面，截图、失败现场等需要 rod 页面的功能在这里处理。
+func (s *XiaohongshuService) withBrowserPage(ctx context.Context, fn func(ctx context.Context, driver xiaohongshu.Driver) error) (err error) {
+	if err := ctx.Err(); err != nil {
+		return errors.Wrap(err, "操作已取消")
+	}
+
+	b, page, err := openAccountPage(ctx)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
 	defer page.Close()
 
-	loginAction := xiaohongshu.NewLogin(page)
+	var recorder *artifactRecorder
+	if s.artifacts != nil {
+		recorder = startArtifactRecorder(page)
+		defer recorder.stop()
+		ctx = withArtifactRecorder(ctx, recorder)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.WithContext(ctx).Errorf("浏览器操作异常: %v", r)
+			err = errors.Errorf("浏览器操作失败: %v", r)
+		}
+
+		if err == nil {
+			return
+		}
+
+		if ctx.Err() != nil {
+			err = errors.Wrap(ctx.Err(), "操作已取消")
+			return
+		}
+
+		s.recordRiskControl(ctx, err)
+		err = &BrowserActionError{
+			Err:        err,
+			Screenshot: captureScreenshot(page),
+			ArtifactID: s.saveArtifacts(ctx, page, recorder, err),
+		}
+	}()
+
+	driver := xiaohongshu.NewRodDriver(page)
+
+	// 开启人工接管时，遇到风控页面等待操作员处理后在同一页面上重新执行
+	for attempt := 0; ; attempt++ {
+		err = fn(ctx, driver)
+		if err == nil || !s.awaitTakeover(ctx, page, err, attempt) {
+			return err
+		}
+	}
+}
+
+// captureScreenshot 截取页面当前可见区域，失败时返回 nil
+func captureScreenshot(page *rod.Page) []byte {
+	data, err := page.Timeout(5*time.Second).Screenshot(false, nil)
+	if err != nil {
+		logrus.WithError(err).Warn("截取失败页面截图失败")
+		return nil
+	}
+	return data
+}
+
+// CheckLoginStatus 检查登录状态
+func (s *XiaohongshuService) CheckLoginStatus(ctx context.Context) (*LoginStatusResponse, error) {
+	if err := s.checkBreaker(ctx); err != nil {
+		return nil, err
+	}
+
+	var isLoggedIn bool
+	err := s.withBrowserPage(ctx, func(ctx context.Context, driver xiaohongshu.Driver) error {
+		loginAction := xiaohongshu.NewLogin(driver)
 
-	isLoggedIn, err := loginAction.CheckLoginStatus(ctx)
+		var err error
+		isLoggedIn, err = loginAction.CheckLoginStatus(ctx)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -74,8 +187,121 @@ func (s *XiaohongshuService) CheckLoginStatus(ctx context.Context) (*LoginStatus
 	return response, nil
 }
 
-// PublishContent 发布内容
-func (s *XiaohongshuService) PublishContent(ctx context.Context, req *PublishRequest) (*PublishResponse, error) {
+// GetLoginQrcode 获取登录二维码。未登录时浏览器会在后台保持打开，
+// 等待用户扫码（最长 loginQrcodeTimeout），登录成功后保存 cookies。
+// 同一账号已经在等待扫码时直接返回同一个二维码，不会再启动浏览器。
+func (s *XiaohongshuService) GetLoginQrcode(ctx context.Context) (*LoginQrcodeResponse, error) {
+	return s.logins.get(ctx, accountFromContext(ctx), s.startLogin)
+}
+
+// startLogin 打开登录页面获取二维码，未登录时在后台等待扫码，结束后调用 done
+func (s *XiaohongshuService) startLogin(ctx context.Context, done func()) (resp *LoginQrcodeResponse, err error) {
+	b, page, err := openAccountPage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cookiePath := cookies.GetAccountCookiesFilePath(accountFromContext(ctx))
+
+	keepOpen := false
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Errorf("获取登录二维码异常: %v", r)
+			err = errors.Errorf("获取登录二维码失败: %v", r)
+		}
+		if err != nil || !keepOpen {
+			page.Close()
+			b.Close()
+		}
+	}()
+
+	loginAction := xiaohongshu.NewLogin(xiaohongshu.NewRodDriver(page))
+
+	img, isLoggedIn, err := loginAction.FetchQrcodeImage(ctx)
+	if err != nil {
+		s.recordRiskControl(ctx, err)
+		return nil, err
+	}
+
+	if isLoggedIn {
+		return &LoginQrcodeResponse{IsLoggedIn: true}, nil
+	}
+
+	// 后台等待扫码登录
+	keepOpen = true
+	go func() {
+		defer done()
+		defer b.Close()
+		defer page.Close()
+
+		waitCtx, cancel := context.WithTimeout(context.Background(), loginQrcodeTimeout)
+		defer cancel()
+
+		if !loginAction.WaitForLogin(waitCtx) {
+			logrus.Warn("等待扫码登录超时")
+			return
+		}
+
+		if err := saveCookies(page, cookiePath); err != nil {
+			logrus.Errorf("保存 cookies 失败: %v", err)
+			return
+		}
+		logrus.Info("扫码登录成功，cookies 已保存")
+	}()
+
+	return &LoginQrcodeResponse{
+		IsLoggedIn: false,
+		Timeout:    loginQrcodeTimeout.String(),
+		Img:        img,
+	}, nil
+}
+
+// openAccountPage 使用 context 中账号对应的 cookies 和启动配置启动浏览器并打开新页面，
+// 启动失败或等待账号的浏览器数据目录时请求被取消都返回 error
+func openAccountPage(ctx context.Context) (b *browser.Browser, page *rod.Page, err error) {
+	b, err = browser.NewAccountBrowser(ctx, configs.IsHeadless(), accountFromContext(ctx))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "启动浏览器失败")
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			b.Close()
+			err = errors.Errorf("打开页面失败: %v", r)
+		}
+	}()
+	return b, b.NewPage(), nil
+}
+
+// saveCookies 保存浏览器当前的 cookies
+func saveCookies(page *rod.Page, cookiePath string) error {
+	cks, err := page.Browser().GetCookies()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(cks)
+	if err != nil {
+		return err
+	}
+
+	cookieLoader := cookies.NewLoadCookie(cookiePath)
+	return cookieLoader.SaveCookies(data)
+}
+
+// PublishContent 发布内容，确定没有发出的失败退还发布额度
+func (s *XiaohongshuService) PublishContent(ctx context.Context, req *PublishRequest) (resp *PublishResponse, err error) {
+	reservation, err := s.reservePolicy(ctx, ActionPublish)
+	if err != nil {
+		return nil, err
+	}
+	tracker := &xiaohongshu.SubmitTracker{}
+	ctx = xiaohongshu.WithSubmitTracker(ctx, tracker)
+	defer func() {
+		if err != nil && publishNotPosted(err, tracker) {
+			s.refundPolicy(reservation)
+		}
+	}()
+
 	// 处理图片：下载URL图片或使用本地路径
 	imagePaths, err := s.processImages(req.Images)
 	if err != nil {
@@ -104,8 +330,27 @@ func (s *XiaohongshuService) PublishContent(ctx context.Context, req *PublishReq
 	return response, nil
 }
 
-// PublishLongText 发布长文
-func (s *XiaohongshuService) PublishLongText(ctx context.Context, req *PublishLongTextRequest) (*PublishResponse, error) {
+// publishNotPosted 失败的发布是否确定没有发出：还没有点击发布按钮，或者点击后被平台拒绝。
+// 点击之后超时、取消等失败时笔记可能已经发出，不退还额度
+func publishNotPosted(err error, tracker *xiaohongshu.SubmitTracker) bool {
+	var rejectedErr *xiaohongshu.PublishRejectedError
+	return !tracker.Submitted() || errors.As(err, &rejectedErr)
+}
+
+// PublishLongText 发布长文，确定没有发出的失败退还发布额度
+func (s *XiaohongshuService) PublishLongText(ctx context.Context, req *PublishLongTextRequest) (resp *PublishResponse, err error) {
+	reservation, err := s.reservePolicy(ctx, ActionPublish)
+	if err != nil {
+		return nil, err
+	}
+	tracker := &xiaohongshu.SubmitTracker{}
+	ctx = xiaohongshu.WithSubmitTracker(ctx, tracker)
+	defer func() {
+		if err != nil && publishNotPosted(err, tracker) {
+			s.refundPolicy(reservation)
+		}
+	}()
+
 	// 构建长文发布内容
 	content := xiaohongshu.PublishLongTextContent{
 		Title:   req.Title,
@@ -129,19 +374,15 @@ func (s *XiaohongshuService) PublishLongText(ctx context.Context, req *PublishLo
 
 // publishLongTextContent 执行长文发布
 func (s *XiaohongshuService) publishLongTextContent(ctx context.Context, content xiaohongshu.PublishLongTextContent) error {
-	b := browser.NewBrowser(configs.IsHeadless())
-	defer b.Close()
-
-	page := b.NewPage()
-	defer page.Close()
-
-	action, err := xiaohongshu.NewPublishLongTextAction(page)
-	if err != nil {
-		return err
-	}
-
-	// 执行长文发布
-	return action.PublishLongText(ctx, content)
+	return s.withBrowserPage(ctx, func(ctx context.Context, driver xiaohongshu.Driver) error {
+		action, err := xiaohongshu.NewPublishLongTextAction(ctx, driver)
+		if err != nil {
+			return err
+		}
+
+		// 执行长文发布
+		return action.PublishLongText(ctx, content)
+	})
 }
 
 // processImages 处理图片列表，支持URL下载和本地路径
@@ -152,34 +393,35 @@ func (s *XiaohongshuService) processImages(images []string) ([]string, error) {
 
 // publishContent 执行内容发布
 func (s *XiaohongshuService) publishContent(ctx context.Context, content xiaohongshu.PublishImageContent) error {
-	b := browser.NewBrowser(configs.IsHeadless())
-	defer b.Close()
-
-	page := b.NewPage()
-	defer page.Close()
-
-	action, err := xiaohongshu.NewPublishImageAction(page)
-	if err != nil {
-		return err
-	}
-
-	// 执行发布
-	return action.Publish(ctx, content)
+	return s.withBrowserPage(ctx, func(ctx context.Context, driver xiaohongshu.Driver) error {
+		action, err := xiaohongshu.NewPublishImageAction(ctx, driver)
+		if err != nil {
+			return err
+		}
+
+		// 执行发布
+		return action.Publish(ctx, content)
+	})
 }
 
 // ListFeeds 获取Feeds列表
 func (s *XiaohongshuService) ListFeeds(ctx context.Context) (*FeedsListResponse, error) {
-	b := browser.NewBrowser(configs.IsHeadless())
-	defer b.Close()
-
-	page := b.NewPage()
-	defer page.Close()
+	if err := s.checkPolicy(ctx, ActionBrowse); err != nil {
+		return nil, err
+	}
 
-	// 创建 Feeds 列表 action
-	action := xiaohongshu.NewFeedsListAction(page)
+	var feeds []xiaohongshu.Feed
+	err := s.withBrowserPage(ctx, func(ctx context.Context, driver xiaohongshu.Driver) error {
+		// 创建 Feeds 列表 action
+		action, err := xiaohongshu.NewFeedsListAction(ctx, driver)
+		if err != nil {
+			return err
+		}
 
-	// 获取 Feeds 列表
-	feeds, err := action.GetFeedsList(ctx)
+		// 获取 Feeds 列表
+		feeds, err = action.GetFeedsList(ctx)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -193,15 +435,18 @@ func (s *XiaohongshuService) ListFeeds(ctx context.Context) (*FeedsListResponse,
 }
 
 func (s *XiaohongshuService) SearchFeeds(ctx context.Context, keyword string) (*FeedsListResponse, error) {
-	b := browser.NewBrowser(configs.IsHeadless())
-	defer b.Close()
-
-	page := b.NewPage()
-	defer page.Close()
+	if err := s.checkPolicy(ctx, ActionSearch); err != nil {
+		return nil, err
+	}
 
-	action := xiaohongshu.NewSearchAction(page)
+	var feeds []xiaohongshu.Feed
+	err := s.withBrowserPage(ctx, func(ctx context.Context, driver xiaohongshu.Driver) error {
+		action := xiaohongshu.NewSearchAction(driver)
 
-	feeds, err := action.Search(ctx, keyword)
+		var err error
+		feeds, err = action.Search(ctx, keyword)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -216,17 +461,24 @@ func (s *XiaohongshuService) SearchFeeds(ctx context.Context, keyword string) (*
 
 // GetFeedDetail 获取Feed详情
 func (s *XiaohongshuService) GetFeedDetail(ctx context.Context, feedID, xsecToken string) (*FeedDetailResponse, error) {
-	b := browser.NewBrowser(configs.IsHeadless())
-	defer b.Close()
-
-	page := b.NewPage()
-	defer page.Close()
+	if err := s.checkPolicy(ctx, ActionBrowse); err != nil {
+		return nil, err
+	}
+	return s.feedDetail(ctx, feedID, xsecToken)
+}
 
-	// 创建 Feed 详情 action
-	action := xiaohongshu.NewFeedDetailAction(page)
+// feedDetail 打开笔记详情页读取数据，不检查频率限制
+func (s *XiaohongshuService) feedDetail(ctx context.Context, feedID, xsecToken string) (*FeedDetailResponse, error) {
+	var result *xiaohongshu.FeedDetailResponse
+	err := s.withBrowserPage(ctx, func(ctx context.Context, driver xiaohongshu.Driver) error {
+		// 创建 Feed 详情 action
+		action := xiaohongshu.NewFeedDetailAction(driver)
 
-	// 获取 Feed 详情
-	result, err := action.GetFeedDetail(ctx, feedID, xsecToken)
+		// 获取 Feed 详情
+		var err error
+		result, err = action.GetFeedDetail(ctx, feedID, xsecToken)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -238,3 +490,37 @@ func (s *XiaohongshuService) GetFeedDetail(ctx context.Context, feedID, xsecToke
 
 	return response, nil
 }
+
+// noteStats 获取笔记的互动数据，用于资源订阅的变化检测。
+// 轮询按 watch 操作计入频率限制，不占用客户端的浏览额度，账号因风控暂停时跳过
+func (s *XiaohongshuService) noteStats(ctx context.Context, feedID, xsecToken string) (xiaohongshu.InteractInfo, error) {
+	if err := s.checkPolicy(ctx, ActionWatch); err != nil {
+		return xiaohongshu.InteractInfo{}, err
+	}
+	detail, err := s.feedDetail(ctx, feedID, xsecToken)
+	if err != nil {
+		return xiaohongshu.InteractInfo{}, err
+	}
+	return detail.Data.Note.InteractInfo, nil
+}
+
+// UserProfile 获取用户主页信息
+func (s *XiaohongshuService) UserProfile(ctx context.Context, userID, xsecToken string) (*xiaohongshu.UserProfileResponse, error) {
+	if err := s.checkPolicy(ctx, ActionBrowse); err != nil {
+		return nil, err
+	}
+
+	var result *xiaohongshu.UserProfileResponse
+	err := s.withBrowserPage(ctx, func(ctx context.Context, driver xiaohongshu.Driver) error {
+		action := xiaohongshu.NewUserProfileAction(driver)
+
+		var err error
+		result, err = action.UserProfile(ctx, userID, xsecToken)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
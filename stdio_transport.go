@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/go-rod/rod"
+	"github.com/sirupsen/logrus"
+)
+
+// maxStdioMessageSize 单条 stdio 消息的最大长度
+const maxStdioMessageSize = 16 * 1024 * 1024
+
+// redirectStdoutToStderr stdio 模式下 stdout 专用于协议消息：
+// 将 os.Stdout 以及各类默认日志输出重定向到 stderr，返回真正的 stdout 供协议使用
+func redirectStdoutToStderr() *os.File {
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+
+	logrus.SetOutput(os.Stderr)
+	log.SetOutput(os.Stderr)
+	rod.DefaultLogger.SetOutput(os.Stderr)
+
+	return stdout
+}
+
+// stdioWriter 串行写出以换行分隔的 JSON-RPC 消息
+type stdioWriter struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func (w *stdioWriter) write(v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to marshal stdio message")
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, err := w.out.Write(append(data, '\n')); err != nil {
+		logrus.WithError(err).Error("Failed to write stdio message")
+	}
+}
+
+// notify 实现 clientNotifier：请求处理过程中的通知直接按顺序写出，保证先于该请求的响应且不会被丢弃
+func (w *stdioWriter) notify(method string, params any) {
+	w.write(&JSONRPCNotification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// ServeStdio 通过标准输入输出提供 MCP 服务，每行一条 JSON-RPC 消息（或批量数组）。
+// stdin 关闭或 ctx 结束时返回。
+func (s *AppServer) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	writer := &stdioWriter{out: out}
+
+	// stdio 只有一个客户端，对应一个固定会话
+	session := s.sessions.Create()
+	defer s.sessions.Delete(session.ID)
+	ctx = withSession(ctx, session)
+	go s.resourceWatcher.Run(ctx)
+
+	// 转发服务端主动推送的消息（如资源更新），请求处理中的通知由 serveStdioMessages 直接写出
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-session.Done():
+				return
+			case data := <-session.stream:
+				writer.write(json.RawMessage(data))
+			}
+		}
+	}()
+
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 64*1024), maxStdioMessageSize)
+		for scanner.Scan() {
+			line := append([]byte(nil), scanner.Bytes()...)
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		readErr <- scanner.Err()
+	}()
+
+	logrus.Info("MCP stdio 传输已启动")
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-readErr:
+			logrus.Info("stdin 已关闭，退出 stdio 传输")
+			return err
+		case line := <-lines:
+			if len(line) == 0 {
+				continue
+			}
+
+			requests, invalid, isBatch, err := parseJSONRPCMessages(line)
+			if err != nil {
+				writer.write(&JSONRPCResponse{
+					JSONRPC: "2.0",
+					Error:   &JSONRPCError{Code: -32700, Message: "Parse error"},
+				})
+				continue
+			}
+			if len(requests) == 0 {
+				if isBatch && len(invalid) > 0 {
+					writer.write(invalid)
+				} else {
+					writer.write(&JSONRPCResponse{
+						JSONRPC: "2.0",
+						Error:   &JSONRPCError{Code: -32600, Message: "Invalid Request"},
+					})
+				}
+				continue
+			}
+
+			// 初始化和通知需要按顺序处理，其余请求并发执行，避免长耗时工具阻塞后续消息
+			if !isBatch && (requests[0].Method == "initialize" || requests[0].IsNotification()) {
+				s.serveStdioMessages(ctx, writer, requests, invalid, isBatch)
+				continue
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// 请求处理中的 panic 已转换为错误响应，这里兜底，保证 stdio 进程不会退出
+				defer func() {
+					if r := recover(); r != nil {
+						logrus.Errorf("处理 stdio 消息异常: %v", r)
+						response := &JSONRPCResponse{
+							JSONRPC: "2.0",
+							Error:   &JSONRPCError{Code: -32603, Message: "Internal error"},
+						}
+						if !isBatch {
+							response.ID = requests[0].ID
+						}
+						writer.write(response)
+					}
+				}()
+				s.serveStdioMessages(ctx, writer, requests, invalid, isBatch)
+			}()
+		}
+	}
+}
+
+// serveStdioMessages 处理一行中的消息并写回响应，invalid 为批量中不合法消息的错误响应。
+// 处理过程中的进度和日志通知通过同一个 writer 同步写出，先于响应到达客户端
+func (s *AppServer) serveStdioMessages(ctx context.Context, writer *stdioWriter, requests []*JSONRPCRequest, invalid []*JSONRPCResponse, isBatch bool) {
+	ctx = withNotifier(ctx, writer)
+	responses := invalid
+	for _, request := range requests {
+		logrus.WithField("method", request.Method).Info("Received stdio request")
+
+		if response := s.processJSONRPCRequest(request, ctx); response != nil {
+			responses = append(responses, response)
+		}
+	}
+
+	switch {
+	case len(responses) == 0:
+		return
+	case isBatch:
+		writer.write(responses)
+	default:
+		writer.write(responses[0])
+	}
+}
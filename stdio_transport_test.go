@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeStdio(t *testing.T) {
+	appServer := NewAppServer(NewXiaohongshuService())
+
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`not json`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+	}, "\n")
+
+	var out bytes.Buffer
+	require.NoError(t, appServer.ServeStdio(context.Background(), strings.NewReader(input), &out))
+
+	responses := map[string]JSONRPCResponse{}
+	scanner := bufio.NewScanner(&out)
+	scanner.Buffer(nil, maxStdioMessageSize)
+	for scanner.Scan() {
+		var response JSONRPCResponse
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &response))
+		key := "parse-error"
+		if response.ID != nil {
+			key = string(mustJSON(t, response.ID))
+		}
+		responses[key] = response
+	}
+
+	require.Len(t, responses, 3)
+	assert.Nil(t, responses["1"].Error)
+	assert.Equal(t, "2025-06-18", responses["1"].Result.(map[string]any)["protocolVersion"])
+	assert.Nil(t, responses["2"].Error)
+	assert.NotEmpty(t, responses["2"].Result.(map[string]any)["tools"])
+	require.NotNil(t, responses["parse-error"].Error)
+	assert.Equal(t, -32700, responses["parse-error"].Error.Code)
+
+	// stdio 会话在退出时清理
+	assert.Equal(t, 0, appServer.sessions.Count())
+}
+
+func TestServeStdioInvalidBatchElements(t *testing.T) {
+	appServer := NewAppServer(NewXiaohongshuService())
+
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`,
+		`[null,{"jsonrpc":"2.0","id":2,"method":"ping"}]`,
+		`null`,
+	}, "\n")
+
+	var out bytes.Buffer
+	require.NoError(t, appServer.ServeStdio(context.Background(), strings.NewReader(input), &out))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+
+	var batch []JSONRPCResponse
+	var single JSONRPCResponse
+	for _, line := range lines[1:] {
+		if strings.HasPrefix(line, "[") {
+			require.NoError(t, json.Unmarshal([]byte(line), &batch))
+		} else {
+			require.NoError(t, json.Unmarshal([]byte(line), &single))
+		}
+	}
+
+	require.Len(t, batch, 2)
+	assert.Equal(t, -32600, batch[0].Error.Code)
+	assert.Nil(t, batch[1].Error)
+	assert.Equal(t, float64(2), batch[1].ID)
+	require.NotNil(t, single.Error)
+	assert.Equal(t, -32600, single.Error.Code)
+}
+
+func TestServeStdioRecoversFromPanic(t *testing.T) {
+	appServer := NewAppServer(NewXiaohongshuService())
+	appServer.tools = NewToolRegistry(newTool(toolSpec[struct{}, struct{}]{
+		Name: "explode",
+		Handler: func(ctx context.Context, s *AppServer, in *struct{}) (*struct{}, error) {
+			panic("boom")
+		},
+	}))
+
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"explode","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"ping"}`,
+	}, "\n")
+
+	var out bytes.Buffer
+	require.NoError(t, appServer.ServeStdio(context.Background(), strings.NewReader(input), &out))
+
+	responses := map[string]JSONRPCResponse{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var response JSONRPCResponse
+		require.NoError(t, json.Unmarshal([]byte(line), &response))
+		responses[string(mustJSON(t, response.ID))] = response
+	}
+
+	require.Len(t, responses, 2)
+	require.NotNil(t, responses["1"].Error)
+	assert.Equal(t, -32603, responses["1"].Error.Code)
+	assert.Nil(t, responses["2"].Error)
+}
+
+func TestServeStdioNotificationsBeforeResponse(t *testing.T) {
+	appServer := NewAppServer(NewXiaohongshuService())
+	appServer.tools = NewToolRegistry(newTool(toolSpec[struct{}, struct{}]{
+		Name: "chatty",
+		Handler: func(ctx context.Context, s *AppServer, in *struct{}) (*struct{}, error) {
+			// 超过会话推送通道的容量，全部都要送达
+			for i := range 100 {
+				notifyClient(ctx, "notifications/progress", map[string]any{"progressToken": "p", "progress": i})
+			}
+			return &struct{}{}, nil
+		},
+	}))
+
+	input := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"chatty","arguments":{}}}`
+
+	var out bytes.Buffer
+	require.NoError(t, appServer.ServeStdio(context.Background(), strings.NewReader(input), &out))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 101)
+	for _, line := range lines[:100] {
+		var notification JSONRPCNotification
+		require.NoError(t, json.Unmarshal([]byte(line), &notification))
+		assert.Equal(t, "notifications/progress", notification.Method)
+	}
+
+	var response JSONRPCResponse
+	require.NoError(t, json.Unmarshal([]byte(lines[100]), &response))
+	assert.Equal(t, float64(1), response.ID)
+	assert.Nil(t, response.Error)
+}
+
+func mustJSON(t *testing.T, v any) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return data
+}
@@ -1,14 +1,19 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"runtime/debug"
 	"strings"
+	"sync"
 
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
 )
 
 // StreamableHTTPHandler 处理 Streamable HTTP 协议的 MCP 请求
@@ -16,8 +21,9 @@ func (s *AppServer) StreamableHTTPHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// 设置 CORS 头
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Accept, Mcp-Session-Id")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Accept, Authorization, X-API-Key, Mcp-Session-Id, Mcp-Protocol-Version")
+		w.Header().Set("Access-Control-Expose-Headers", "Mcp-Session-Id")
 
 		// 处理 OPTIONS 请求
 		if r.Method == "OPTIONS" {
@@ -25,46 +31,120 @@ func (s *AppServer) StreamableHTTPHandler() http.HandlerFunc {
 			return
 		}
 
+		// 开启认证时，每个请求都需要携带有效的 API Key
+		if s.auth != nil {
+			key, ok := s.auth.Authenticate(r)
+			if !ok {
+				writeUnauthorized(w)
+				return
+			}
+			r = r.WithContext(withAPIKey(r.Context(), key))
+		}
+
 		// 根据方法处理
 		switch r.Method {
 		case "GET":
-			// GET 请求用于建立 SSE 连接（可选功能）
+			// GET 请求用于建立 SSE 连接，接收服务端主动推送的消息
 			s.handleSSEConnection(w, r)
 		case "POST":
 			// POST 请求处理 JSON-RPC
 			s.handleJSONRPCRequest(w, r)
+		case "DELETE":
+			// DELETE 请求终止会话
+			s.handleSessionDelete(w, r)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	}
 }
 
-// handleSSEConnection 处理 SSE 连接（可选，用于服务器推送）
+// lookupSession 根据请求头查找会话，失败时直接写回错误响应
+func (s *AppServer) lookupSession(w http.ResponseWriter, r *http.Request) (*MCPSession, bool) {
+	sessionID := r.Header.Get(mcpSessionHeader)
+	if sessionID == "" {
+		http.Error(w, "Bad Request: Mcp-Session-Id header is required", http.StatusBadRequest)
+		return nil, false
+	}
+
+	session, ok := s.sessions.Get(sessionID)
+	if !ok {
+		// 按协议要求，未知或已过期的会话返回 404，客户端需重新初始化
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return nil, false
+	}
+
+	// 会话绑定创建它的 API Key，其他 Key 无法使用，也不暴露会话是否存在
+	if key, ok := apiKeyFromContext(r.Context()); ok && session.Owner() != key.Name {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return nil, false
+	}
+
+	if version := r.Header.Get(mcpProtocolVersionHeader); version != "" && !isSupportedProtocolVersion(version) {
+		http.Error(w, "Bad Request: unsupported protocol version "+version, http.StatusBadRequest)
+		return nil, false
+	}
+
+	return session, true
+}
+
+// handleSSEConnection 处理 SSE 连接，用于服务器推送
 func (s *AppServer) handleSSEConnection(w http.ResponseWriter, r *http.Request) {
 	// 检查是否支持 SSE
 	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
-		http.Error(w, "SSE not requested", http.StatusBadRequest)
+		http.Error(w, "SSE not requested", http.StatusNotAcceptable)
+		return
+	}
+
+	session, ok := s.lookupSession(w, r)
+	if !ok {
+		return
+	}
+
+	if !session.acquireStream() {
+		http.Error(w, "SSE stream already open for this session", http.StatusConflict)
 		return
 	}
+	defer session.releaseStream()
 
 	// 设置 SSE 响应头
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
 
-	// 发送初始化消息
-	fmt.Fprintf(w, "event: open\n")
-	fmt.Fprintf(w, "data: {\"type\":\"connection\",\"status\":\"connected\"}\n\n")
+	// 持续推送会话消息，直到客户端断开或会话终止
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-session.Done():
+			return
+		case data := <-session.stream:
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
 
-	if f, ok := w.(http.Flusher); ok {
-		f.Flush()
+// handleSessionDelete 处理客户端主动终止会话
+func (s *AppServer) handleSessionDelete(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.lookupSession(w, r)
+	if !ok {
+		return
 	}
 
-	// 保持连接打开（实际使用中可以在这里推送通知）
-	<-r.Context().Done()
+	s.sessions.Delete(session.ID)
+	w.WriteHeader(http.StatusOK)
 }
 
-// handleJSONRPCRequest 处理 JSON-RPC 请求
+// handleJSONRPCRequest 处理 JSON-RPC 请求，支持单条消息和批量数组
 func (s *AppServer) handleJSONRPCRequest(w http.ResponseWriter, r *http.Request) {
 	// 读取请求体
 	body, err := io.ReadAll(r.Body)
@@ -75,36 +155,208 @@ func (s *AppServer) handleJSONRPCRequest(w http.ResponseWriter, r *http.Request)
 	defer r.Body.Close()
 
 	// 解析 JSON-RPC 请求
-	var request JSONRPCRequest
-	if err := json.Unmarshal(body, &request); err != nil {
+	requests, invalid, isBatch, err := parseJSONRPCMessages(body)
+	if err != nil {
 		s.sendStreamableError(w, nil, -32700, "Parse error")
 		return
 	}
+	if len(requests) == 0 {
+		// 空批量或者所有消息都不是合法请求
+		if isBatch && len(invalid) > 0 {
+			s.sendJSONBatchResponse(w, invalid)
+		} else {
+			s.sendStreamableError(w, nil, -32600, "Invalid Request")
+		}
+		return
+	}
+
+	// 初始化请求创建新会话，其他请求必须携带有效的会话 ID
+	var session *MCPSession
+	if hasInitializeRequest(requests) {
+		if len(requests)+len(invalid) > 1 {
+			s.sendStreamableError(w, nil, -32600, "Invalid Request: initialize must not be batched")
+			return
+		}
+		session = s.sessions.Create()
+		if key, ok := apiKeyFromContext(r.Context()); ok {
+			session.bindOwner(key.Name)
+		}
+	} else {
+		var ok bool
+		if session, ok = s.lookupSession(w, r); !ok {
+			return
+		}
+	}
 
-	logrus.WithField("method", request.Method).Info("Received Streamable HTTP request")
+	ctx := withSession(r.Context(), session)
 
 	// 检查 Accept 头，判断客户端是否支持 SSE
 	acceptSSE := strings.Contains(r.Header.Get("Accept"), "text/event-stream")
 
-	// 处理请求
-	response := s.processJSONRPCRequest(&request, r.Context())
+	// 如果需要 SSE 且是支持流式的方法，使用 SSE 响应：
+	// 执行过程中的通知（如进度）会先于最终结果写入同一个响应流
+	if request := requests[0]; !isBatch && acceptSSE && !request.IsNotification() && s.isStreamableMethod(request.Method) {
+		logrus.WithFields(logrus.Fields{
+			"method":  request.Method,
+			"session": session.ID,
+		}).Info("Received Streamable HTTP request")
+
+		stream := newSSEStream(w)
+		// 请求被客户端取消时不发送响应
+		if response := s.processJSONRPCRequest(request, withNotifier(ctx, stream)); response != nil {
+			stream.send(response)
+		}
+		return
+	}
 
-	// 如果需要 SSE 且是支持流式的方法，使用 SSE 响应
-	if acceptSSE && s.isStreamableMethod(request.Method) {
-		s.sendSSEResponse(w, response)
+	responses := invalid
+	for _, request := range requests {
+		logrus.WithFields(logrus.Fields{
+			"method":  request.Method,
+			"session": session.ID,
+		}).Info("Received Streamable HTTP request")
+
+		// 通知消息没有响应
+		if response := s.processJSONRPCRequest(request, ctx); response != nil {
+			responses = append(responses, response)
+		}
+	}
+
+	if request := requests[0]; request.Method == "initialize" {
+		if len(responses) == 1 && responses[0].Error != nil {
+			s.sessions.Delete(session.ID)
+		} else {
+			w.Header().Set(mcpSessionHeader, session.ID)
+		}
+	}
+
+	// 只包含通知时返回 202 Accepted
+	if len(responses) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	// 否则使用普通 JSON 响应
+	if isBatch {
+		s.sendJSONBatchResponse(w, responses)
+	} else {
+		s.sendJSONResponse(w, responses[0])
+	}
+}
+
+// parseJSONRPCMessages 解析单条或批量 JSON-RPC 消息。不是合法请求对象的消息（如批量中的 null、数字）
+// 不影响其他消息，以 invalid 中的 -32600 响应返回；只有整体不是合法 JSON 时返回 err
+func parseJSONRPCMessages(body []byte) (requests []*JSONRPCRequest, invalid []*JSONRPCResponse, isBatch bool, err error) {
+	trimmed := bytes.TrimSpace(body)
+	var messages []json.RawMessage
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &messages); err != nil {
+			return nil, nil, true, err
+		}
+		isBatch = true
 	} else {
-		// 否则使用普通 JSON 响应
-		s.sendJSONResponse(w, response)
+		if !json.Valid(trimmed) {
+			return nil, nil, false, errors.New("invalid JSON")
+		}
+		messages = []json.RawMessage{trimmed}
+	}
+
+	for _, message := range messages {
+		if request, ok := decodeJSONRPCRequest(message); ok {
+			requests = append(requests, request)
+		} else {
+			invalid = append(invalid, &JSONRPCResponse{
+				JSONRPC: "2.0",
+				Error:   &JSONRPCError{Code: -32600, Message: "Invalid Request"},
+			})
+		}
+	}
+	return requests, invalid, isBatch, nil
+}
+
+// decodeJSONRPCRequest 解析一条消息，消息必须是 JSON 对象且字段类型正确
+func decodeJSONRPCRequest(message json.RawMessage) (*JSONRPCRequest, bool) {
+	message = bytes.TrimSpace(message)
+	if len(message) == 0 || message[0] != '{' {
+		return nil, false
+	}
+	var request JSONRPCRequest
+	if err := json.Unmarshal(message, &request); err != nil {
+		return nil, false
 	}
+	return &request, true
 }
 
-// processJSONRPCRequest 处理 JSON-RPC 请求并返回响应
+// hasInitializeRequest 判断消息中是否包含初始化请求
+func hasInitializeRequest(requests []*JSONRPCRequest) bool {
+	for _, request := range requests {
+		if request.Method == "initialize" {
+			return true
+		}
+	}
+	return false
+}
+
+// processJSONRPCRequest 处理 JSON-RPC 请求并返回响应，通知消息返回 nil
 func (s *AppServer) processJSONRPCRequest(request *JSONRPCRequest, ctx context.Context) *JSONRPCResponse {
+	if request.IsNotification() {
+		defer func() {
+			if r := recover(); r != nil {
+				logrus.WithField("method", request.Method).Errorf("处理通知异常: %v\n%s", r, debug.Stack())
+			}
+		}()
+		s.processNotification(ctx, request)
+		return nil
+	}
+
+	// 客户端对服务端请求的响应，目前无需处理
+	if request.Method == "" {
+		return nil
+	}
+
+	// 登记进行中的请求，以便通过 notifications/cancelled 或会话终止取消
+	if session, ok := sessionFromContext(ctx); ok {
+		var finish func() bool
+		ctx, finish = session.trackRequest(ctx, request.ID)
+
+		response := s.dispatchRecovered(ctx, request)
+		if cancelled := finish(); cancelled {
+			// 按协议要求，已被客户端取消的请求不再发送响应
+			logrus.WithField("method", request.Method).Info("请求已被客户端取消")
+			return nil
+		}
+		return response
+	}
+
+	return s.dispatchRecovered(ctx, request)
+}
+
+// dispatchRecovered 分发请求，处理过程中 panic 时返回 -32603 错误，
+// 避免 stdio 模式下整个进程退出，HTTP 模式下返回没有 JSON-RPC 内容的 500
+func (s *AppServer) dispatchRecovered(ctx context.Context, request *JSONRPCRequest) (response *JSONRPCResponse) {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.WithField("method", request.Method).Errorf("处理请求异常: %v\n%s", r, debug.Stack())
+			response = errorResponse(request, -32603, "Internal error", nil)
+		}
+	}()
+	return s.dispatchJSONRPCRequest(ctx, request)
+}
+
+// dispatchJSONRPCRequest 根据方法分发 JSON-RPC 请求
+func (s *AppServer) dispatchJSONRPCRequest(ctx context.Context, request *JSONRPCRequest) *JSONRPCResponse {
+	if scope, ok := methodScopes[request.Method]; ok {
+		if err := checkScope(ctx, scope); err != nil {
+			return errorResponse(request, errCodeForbidden, "Forbidden: "+err.Error(), nil)
+		}
+	}
+
 	switch request.Method {
 	case "initialize":
-		return s.processInitialize(request)
+		return s.processInitialize(ctx, request)
 	case "initialized":
-		// 客户端确认初始化完成
+		// 兼容旧客户端以请求形式发送的初始化确认
+		s.processNotification(ctx, request)
 		return &JSONRPCResponse{
 			JSONRPC: "2.0",
 			Result:  map[string]interface{}{},
@@ -118,9 +370,25 @@ func (s *AppServer) processJSONRPCRequest(request *JSONRPCRequest, ctx context.C
 			ID:      request.ID,
 		}
 	case "tools/list":
-		return s.processToolsList(request)
+		return s.processToolsList(ctx, request)
 	case "tools/call":
 		return s.processToolCall(ctx, request)
+	case "resources/list":
+		return s.processResourcesList(ctx, request)
+	case "resources/templates/list":
+		return s.processResourceTemplatesList(request)
+	case "resources/read":
+		return s.processResourcesRead(ctx, request)
+	case "resources/subscribe":
+		return s.processResourcesSubscribe(ctx, request, true)
+	case "resources/unsubscribe":
+		return s.processResourcesSubscribe(ctx, request, false)
+	case "prompts/list":
+		return s.processPromptsList(request)
+	case "prompts/get":
+		return s.processPromptsGet(ctx, request)
+	case "logging/setLevel":
+		return s.processLoggingSetLevel(ctx, request)
 	default:
 		return &JSONRPCResponse{
 			JSONRPC: "2.0",
@@ -133,12 +401,80 @@ func (s *AppServer) processJSONRPCRequest(request *JSONRPCRequest, ctx context.C
 	}
 }
 
-// processInitialize 处理初始化请求
-func (s *AppServer) processInitialize(request *JSONRPCRequest) *JSONRPCResponse {
+// processNotification 处理客户端通知
+func (s *AppServer) processNotification(ctx context.Context, request *JSONRPCRequest) {
+	switch request.Method {
+	case "notifications/initialized", "initialized":
+		// 客户端确认初始化完成
+		if session, ok := sessionFromContext(ctx); ok {
+			session.markInitialized()
+		}
+	case "notifications/cancelled":
+		// 客户端取消进行中的请求
+		var params struct {
+			RequestID any    `json:"requestId"`
+			Reason    string `json:"reason"`
+		}
+		if err := request.BindParams(&params); err != nil || params.RequestID == nil {
+			logrus.WithError(err).Warn("无效的取消通知")
+			return
+		}
+
+		session, ok := sessionFromContext(ctx)
+		if !ok || !session.cancelRequest(params.RequestID) {
+			logrus.WithField("requestId", params.RequestID).Debug("取消的请求不存在或已完成")
+			return
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"requestId": params.RequestID,
+			"reason":    params.Reason,
+		}).Info("已取消请求")
+	default:
+		logrus.WithField("method", request.Method).Debug("忽略未处理的通知")
+	}
+}
+
+// processInitialize 处理初始化请求，协商协议版本并记录客户端信息
+func (s *AppServer) processInitialize(ctx context.Context, request *JSONRPCRequest) *JSONRPCResponse {
+	var params struct {
+		ProtocolVersion string        `json:"protocolVersion"`
+		ClientInfo      MCPClientInfo `json:"clientInfo"`
+	}
+	if err := request.BindParams(&params); err != nil {
+		return &JSONRPCResponse{
+			JSONRPC: "2.0",
+			Error: &JSONRPCError{
+				Code:    -32602,
+				Message: "Invalid params",
+			},
+			ID: request.ID,
+		}
+	}
+
+	protocolVersion := negotiateProtocolVersion(params.ProtocolVersion)
+	if session, ok := sessionFromContext(ctx); ok {
+		session.setInitializeParams(protocolVersion, params.ClientInfo)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"client":    params.ClientInfo.Name,
+		"requested": params.ProtocolVersion,
+		"protocol":  protocolVersion,
+	}).Info("MCP 客户端初始化")
+
 	result := map[string]interface{}{
-		"protocolVersion": "2025-03-26", // 使用新的协议版本
+		"protocolVersion": protocolVersion,
 		"capabilities": map[string]interface{}{
 			"tools": map[string]interface{}{},
+			"resources": map[string]interface{}{
+				"subscribe":   true,
+				"listChanged": false,
+			},
+			"prompts": map[string]interface{}{
+				"listChanged": false,
+			},
+			"logging": map[string]interface{}{},
 		},
 		"serverInfo": map[string]interface{}{
 			"name":    "xiaohongshu-mcp",
@@ -153,104 +489,14 @@ func (s *AppServer) processInitialize(request *JSONRPCRequest) *JSONRPCResponse
 	}
 }
 
-// processToolsList 处理工具列表请求
-func (s *AppServer) processToolsList(request *JSONRPCRequest) *JSONRPCResponse {
-	tools := []map[string]interface{}{
-		{
-			"name":        "check_login_status",
-			"description": "检查小红书登录状态",
-			"inputSchema": map[string]interface{}{
-				"type":       "object",
-				"properties": map[string]interface{}{},
-			},
-		},
-		{
-			"name":        "publish_content",
-			"description": "发布小红书内容（支持图文或视频）",
-			"inputSchema": map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"title": map[string]interface{}{
-						"type":        "string",
-						"description": "内容标题",
-					},
-					"content": map[string]interface{}{
-						"type":        "string",
-						"description": "正文内容，支持话题标签",
-					},
-					"images": map[string]interface{}{
-						"type":        "array",
-						"description": "图片路径列表，支持本地路径或URL",
-						"items": map[string]interface{}{
-							"type": "string",
-						},
-					},
-					"video": map[string]interface{}{
-						"type":        "string",
-						"description": "视频文件路径（发布视频时使用）",
-					},
-				},
-				"required": []string{"title", "content"},
-			},
-		},
-		{
-			"name":        "publish_longtext",
-			"description": "发布长文到小红书",
-			"inputSchema": map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"title": map[string]interface{}{
-						"type":        "string",
-						"description": "长文标题",
-					},
-					"content": map[string]interface{}{
-						"type":        "string",
-						"description": "长文内容",
-					},
-				},
-				"required": []string{"title", "content"},
-			},
-		},
-		{
-			"name":        "list_feeds",
-			"description": "获取用户发布的内容列表",
-			"inputSchema": map[string]interface{}{
-				"type":       "object",
-				"properties": map[string]interface{}{},
-			},
-		},
-		{
-			"name":        "search_feeds",
-			"description": "搜索小红书内容（需要已登录）",
-			"inputSchema": map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"keyword": map[string]interface{}{
-						"type":        "string",
-						"description": "搜索关键词",
-					},
-				},
-				"required": []string{"keyword"},
-			},
-		},
-		{
-			"name":        "get_feed_detail",
-			"description": "获取小红书笔记详情，返回笔记内容、图片、作者信息、互动数据（点赞/收藏/分享数）及评论列表",
-			"inputSchema": map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"feed_id": map[string]interface{}{
-						"type":        "string",
-						"description": "小红书笔记ID，从Feed列表获取",
-					},
-					"xsec_token": map[string]interface{}{
-						"type":        "string",
-						"description": "访问令牌，从Feed列表的xsecToken字段获取",
-					},
-				},
-				"required": []string{"feed_id", "xsec_token"},
-			},
-		},
+// processToolsList 处理工具列表请求，只返回调用方有权限调用的工具
+func (s *AppServer) processToolsList(ctx context.Context, request *JSONRPCRequest) *JSONRPCResponse {
+	tools := make([]map[string]interface{}, 0, len(s.tools.List()))
+	for _, tool := range s.tools.List() {
+		if checkScope(ctx, tool.Scope) != nil {
+			continue
+		}
+		tools = append(tools, tool.MCPDefinition(s.confirmations != nil))
 	}
 
 	return &JSONRPCResponse{
@@ -267,56 +513,70 @@ func (s *AppServer) processToolCall(ctx context.Context, request *JSONRPCRequest
 	// 解析参数
 	params, ok := request.Params.(map[string]interface{})
 	if !ok {
-		return &JSONRPCResponse{
-			JSONRPC: "2.0",
-			Error: &JSONRPCError{
-				Code:    -32602,
-				Message: "Invalid params",
-			},
-			ID: request.ID,
-		}
+		return errorResponse(request, -32602, "Invalid params", nil)
 	}
 
 	toolName, _ := params["name"].(string)
 	toolArgs, _ := params["arguments"].(map[string]interface{})
 
-	var result *MCPToolResult
-
-	switch toolName {
-	case "check_login_status":
-		result = s.handleCheckLoginStatus(ctx)
-	case "publish_content":
-		result = s.handlePublishContent(ctx, toolArgs)
-	case "publish_longtext":
-		result = s.handlePublishLongText(ctx, toolArgs)
-	case "list_feeds":
-		result = s.handleListFeeds(ctx)
-	case "search_feeds":
-		result = s.handleSearchFeeds(ctx, toolArgs)
-	case "get_feed_detail":
-		result = s.handleGetFeedDetail(ctx, toolArgs)
-	default:
-		return &JSONRPCResponse{
-			JSONRPC: "2.0",
-			Error: &JSONRPCError{
-				Code:    -32602,
-				Message: fmt.Sprintf("Unknown tool: %s", toolName),
-			},
-			ID: request.ID,
+	tool, ok := s.tools.Get(toolName)
+	if !ok {
+		return errorResponse(request, -32602, fmt.Sprintf("Unknown tool: %s", toolName), nil)
+	}
+
+	if err := checkScope(ctx, tool.Scope); err != nil {
+		return errorResponse(request, errCodeForbidden, "Forbidden: "+err.Error(), nil)
+	}
+
+	input, err := tool.Decode(toolArgs)
+	if err != nil {
+		// 参数校验失败时在 error.data 中返回所有不合法的参数
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			return errorResponse(request, -32602, "Invalid params", validationErr)
+		}
+		return errorResponse(request, -32602, "Invalid params: "+err.Error(), nil)
+	}
+
+	// 客户端提供 progressToken 时，通过 notifications/progress 汇报执行进度
+	if meta, ok := params["_meta"].(map[string]interface{}); ok {
+		if progressToken, ok := meta["progressToken"]; ok && progressToken != nil {
+			ctx = xiaohongshu.WithProgress(ctx, progressReporter(ctx, progressToken))
+		}
+	}
+
+	// 确认模式下，写操作先返回预览和确认令牌
+	if s.requiresConfirmation(tool) {
+		pending, err := s.confirmToolCall(tool, toolArgs, input)
+		if err != nil {
+			return errorResponse(request, -32602, "Invalid params: "+err.Error(), nil)
+		}
+		if pending != nil {
+			logrus.WithContext(ctx).WithField("tool", toolName).Info("MCP: 等待确认")
+			return &JSONRPCResponse{
+				JSONRPC: "2.0",
+				Result:  pendingConfirmationResult(pending),
+				ID:      request.ID,
+			}
 		}
 	}
 
+	logrus.WithContext(ctx).WithField("tool", toolName).Info("MCP: 调用工具")
+
 	return &JSONRPCResponse{
 		JSONRPC: "2.0",
-		Result:  result,
+		Result:  tool.MCPResult(ctx, s, input),
 		ID:      request.ID,
 	}
 }
 
 // isStreamableMethod 判断方法是否支持流式响应
-func (s *AppServer) isStreamableMethod(_ string) bool {
-	// 目前我们的方法都不需要流式响应
-	// 未来可以在这里添加支持流式的方法
+func (s *AppServer) isStreamableMethod(method string) bool {
+	// 工具调用、资源读取和带实时数据的提示词耗时较长，通过 SSE 在结果之前推送进度等通知
+	switch method {
+	case "tools/call", "resources/read", "prompts/get":
+		return true
+	}
 	return false
 }
 
@@ -329,27 +589,65 @@ func (s *AppServer) sendJSONResponse(w http.ResponseWriter, response *JSONRPCRes
 	}
 }
 
-// sendSSEResponse 发送 SSE 响应
-func (s *AppServer) sendSSEResponse(w http.ResponseWriter, response *JSONRPCResponse) {
+// sendJSONBatchResponse 发送批量 JSON 响应
+func (s *AppServer) sendJSONBatchResponse(w http.ResponseWriter, responses []*JSONRPCResponse) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(responses); err != nil {
+		logrus.WithError(err).Error("Failed to encode batch response")
+	}
+}
+
+// sseStream 以 SSE 格式写出 JSON-RPC 消息的响应流
+type sseStream struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// newSSEStream 设置 SSE 响应头并返回响应流
+func newSSEStream(w http.ResponseWriter) *sseStream {
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
 
-	// 将响应转换为 JSON
-	data, err := json.Marshal(response)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	return &sseStream{w: w, flusher: flusher}
+}
+
+// send 发送一条 SSE 消息
+func (s *sseStream) send(v any) {
+	// 将消息转换为 JSON
+	data, err := json.Marshal(v)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to marshal SSE response")
+		logrus.WithError(err).Error("Failed to marshal SSE message")
 		return
 	}
 
-	// 发送 SSE 格式的响应
-	fmt.Fprintf(w, "data: %s\n\n", string(data))
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	if f, ok := w.(http.Flusher); ok {
-		f.Flush()
+	// 发送 SSE 格式的消息
+	fmt.Fprintf(s.w, "event: message\ndata: %s\n\n", data)
+
+	if s.flusher != nil {
+		s.flusher.Flush()
 	}
 }
 
+// notify 实现 clientNotifier，在响应流中发送通知
+func (s *sseStream) notify(method string, params any) {
+	s.send(&JSONRPCNotification{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
+
 // sendStreamableError 发送错误响应
 func (s *AppServer) sendStreamableError(w http.ResponseWriter, id interface{}, code int, message string) {
 	response := &JSONRPCResponse{
@@ -362,3 +660,16 @@ func (s *AppServer) sendStreamableError(w http.ResponseWriter, id interface{}, c
 	}
 	s.sendJSONResponse(w, response)
 }
+
+// errorResponse 构建 JSON-RPC 错误响应
+func errorResponse(request *JSONRPCRequest, code int, message string, data any) *JSONRPCResponse {
+	return &JSONRPCResponse{
+		JSONRPC: "2.0",
+		Error: &JSONRPCError{
+			Code:    code,
+			Message: message,
+			Data:    data,
+		},
+		ID: request.ID,
+	}
+}
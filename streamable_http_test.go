@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMCPServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	appServer := NewAppServer(NewXiaohongshuService())
+	server := httptest.NewServer(appServer.StreamableHTTPHandler())
+	t.Cleanup(server.Close)
+	return server
+}
+
+func postMCP(t *testing.T, url, sessionID, body string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if sessionID != "" {
+		req.Header.Set(mcpSessionHeader, sessionID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func initializeMCP(t *testing.T, url string) string {
+	t.Helper()
+
+	resp := postMCP(t, url, "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","clientInfo":{"name":"test","version":"1.0"}}}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var response JSONRPCResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	require.Nil(t, response.Error)
+	assert.Equal(t, "2025-03-26", response.Result.(map[string]any)["protocolVersion"])
+
+	sessionID := resp.Header.Get(mcpSessionHeader)
+	require.NotEmpty(t, sessionID)
+	return sessionID
+}
+
+func TestStreamableHTTPSessionLifecycle(t *testing.T) {
+	server := newTestMCPServer(t)
+	sessionID := initializeMCP(t, server.URL)
+
+	// 初始化后的请求必须携带会话 ID
+	resp := postMCP(t, server.URL, "", `{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = postMCP(t, server.URL, "unknown-session", `{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// 通知消息返回 202
+	resp = postMCP(t, server.URL, sessionID, `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	resp = postMCP(t, server.URL, sessionID, `{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// DELETE 终止会话后不能再使用
+	req, err := http.NewRequest(http.MethodDelete, server.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set(mcpSessionHeader, sessionID)
+	deleteResp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	deleteResp.Body.Close()
+	assert.Equal(t, http.StatusOK, deleteResp.StatusCode)
+
+	resp = postMCP(t, server.URL, sessionID, `{"jsonrpc":"2.0","id":3,"method":"ping"}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestStreamableHTTPBatch(t *testing.T) {
+	server := newTestMCPServer(t)
+	sessionID := initializeMCP(t, server.URL)
+
+	resp := postMCP(t, server.URL, sessionID, `[
+		{"jsonrpc":"2.0","method":"notifications/initialized"},
+		{"jsonrpc":"2.0","id":"a","method":"ping"},
+		{"jsonrpc":"2.0","id":"b","method":"no/such/method"}
+	]`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var responses []JSONRPCResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&responses))
+	require.Len(t, responses, 2)
+	assert.Equal(t, "a", responses[0].ID)
+	assert.Nil(t, responses[0].Error)
+	assert.Equal(t, "b", responses[1].ID)
+	require.NotNil(t, responses[1].Error)
+	assert.Equal(t, -32601, responses[1].Error.Code)
+}
+
+func TestStreamableHTTPBatchInvalidElements(t *testing.T) {
+	server := newTestMCPServer(t)
+	sessionID := initializeMCP(t, server.URL)
+
+	// 不合法的元素各自返回 -32600，不影响批量中的其他请求
+	resp := postMCP(t, server.URL, sessionID, `[null, 5, {"jsonrpc":"2.0","id":"a","method":"ping"}, {"jsonrpc":"2.0","id":"b","method":7}]`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var responses []JSONRPCResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&responses))
+	require.Len(t, responses, 4)
+	var invalid int
+	for _, response := range responses {
+		if response.ID == "a" {
+			assert.Nil(t, response.Error)
+			continue
+		}
+		require.NotNil(t, response.Error)
+		assert.Equal(t, -32600, response.Error.Code)
+		assert.Nil(t, response.ID)
+		invalid++
+	}
+	assert.Equal(t, 3, invalid)
+
+	// 只有不合法元素的批量不需要会话
+	resp = postMCP(t, server.URL, "", `[null]`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&responses))
+	require.Len(t, responses, 1)
+	assert.Equal(t, -32600, responses[0].Error.Code)
+}
+
+func TestNegotiateProtocolVersion(t *testing.T) {
+	assert.Equal(t, "2024-11-05", negotiateProtocolVersion("2024-11-05"))
+	assert.Equal(t, supportedProtocolVersions[0], negotiateProtocolVersion("1999-01-01"))
+	assert.Equal(t, supportedProtocolVersions[0], negotiateProtocolVersion(""))
+}
+
+func TestSessionManagerExpire(t *testing.T) {
+	manager := NewSessionManager(time.Minute)
+	session := manager.Create()
+
+	manager.expire(time.Now())
+	assert.Equal(t, 1, manager.Count())
+
+	manager.expire(time.Now().Add(2 * time.Minute))
+	assert.Equal(t, 0, manager.Count())
+
+	_, ok := manager.Get(session.ID)
+	assert.False(t, ok)
+
+	select {
+	case <-session.Done():
+	default:
+		t.Fatal("expired session should be closed")
+	}
+}
+
+func TestSessionManagerKeepsListeningSession(t *testing.T) {
+	manager := NewSessionManager(time.Minute)
+	session := manager.Create()
+
+	// 保持 SSE 连接的会话不会因为没有 POST 请求而过期
+	require.True(t, session.acquireStream())
+	manager.expire(time.Now().Add(2 * time.Minute))
+	assert.Equal(t, 1, manager.Count())
+
+	// 连接断开后从断开时开始计算空闲时间
+	session.releaseStream()
+	manager.expire(time.Now())
+	assert.Equal(t, 1, manager.Count())
+	manager.expire(time.Now().Add(2 * time.Minute))
+	assert.Equal(t, 0, manager.Count())
+}
+
+func TestProgressNotifications(t *testing.T) {
+	// 请求使用 SSE 响应时，进度通知写入同一个响应流
+	recorder := httptest.NewRecorder()
+	stream := newSSEStream(recorder)
+	ctx := withNotifier(context.Background(), stream)
+
+	reporter := progressReporter(ctx, "token-1")
+	reporter.Report(1, 3, "打开搜索页面")
+	stream.send(&JSONRPCResponse{JSONRPC: "2.0", Result: map[string]any{}, ID: 1})
+
+	assert.Equal(t, "text/event-stream", recorder.Header().Get("Content-Type"))
+	events := strings.Split(strings.TrimSpace(recorder.Body.String()), "\n\n")
+	require.Len(t, events, 2)
+	assert.Contains(t, events[0], `"method":"notifications/progress"`)
+	assert.Contains(t, events[0], `"progressToken":"token-1"`)
+	assert.Contains(t, events[0], `"total":3`)
+	assert.Contains(t, events[1], `"id":1`)
+
+	// 否则通过会话的推送通道发送
+	session := newMCPSession("test")
+	reporter = progressReporter(withSession(context.Background(), session), 7)
+	reporter.Report(2, 0, "")
+
+	select {
+	case data := <-session.stream:
+		var notification JSONRPCNotification
+		require.NoError(t, json.Unmarshal(data, &notification))
+		assert.Equal(t, "notifications/progress", notification.Method)
+		params := notification.Params.(map[string]any)
+		assert.Equal(t, float64(7), params["progressToken"])
+		assert.NotContains(t, params, "total")
+	default:
+		t.Fatal("expected progress notification on session stream")
+	}
+}
+
+func TestSessionCancelRequest(t *testing.T) {
+	session := newMCPSession("test")
+
+	ctx, finish := session.trackRequest(context.Background(), float64(5))
+	assert.False(t, session.cancelRequest("5"), "id 类型不同不应匹配")
+	assert.True(t, session.cancelRequest(float64(5)))
+
+	select {
+	case <-ctx.Done():
+	default:
+		t.Fatal("cancelled request context should be done")
+	}
+	assert.True(t, finish())
+	assert.False(t, session.cancelRequest(float64(5)), "已结束的请求不能再取消")
+
+	// 会话终止时取消所有进行中的请求
+	ctx, finish = session.trackRequest(context.Background(), "call-1")
+	session.close()
+	<-ctx.Done()
+	assert.False(t, finish())
+}
+
+func TestCancelledNotification(t *testing.T) {
+	appServer := NewAppServer(NewXiaohongshuService())
+	session := newMCPSession("test")
+	ctx := withSession(context.Background(), session)
+
+	requestCtx, finish := session.trackRequest(ctx, "call-1")
+	response := appServer.processJSONRPCRequest(&JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "notifications/cancelled",
+		Params:  map[string]any{"requestId": "call-1", "reason": "user abort"},
+	}, ctx)
+	assert.Nil(t, response)
+
+	<-requestCtx.Done()
+	assert.True(t, finish())
+}
@@ -0,0 +1,501 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/input"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
+)
+
+const (
+	// defaultTakeoverTimeout 等待操作员处理的最长时间
+	defaultTakeoverTimeout = 10 * time.Minute
+	// maxTakeoverAttempts 同一次操作最多请求人工接管的次数
+	maxTakeoverAttempts = 3
+)
+
+//go:embed takeover.html
+var takeoverPage []byte
+
+// TakeoverInfo 等待人工接管的操作
+type TakeoverInfo struct {
+	ID        string    `json:"id"`
+	Account   string    `json:"account" description:"账号名，default 表示默认账号"`
+	Reason    string    `json:"reason" description:"触发风控的原因"`
+	PageURL   string    `json:"page_url" description:"风控页面地址"`
+	ViewURL   string    `json:"view_url" description:"实时画面地址，在浏览器中打开即可操作页面，其中的 token 是访问凭证"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// takeoverFrame 页面截屏帧
+type takeoverFrame struct {
+	Data   string  `json:"data"` // base64 编码的 JPEG
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// takeoverInput 操作员的鼠标、键盘输入，坐标为页面 CSS 像素
+type takeoverInput struct {
+	// Type mouse、key、text、scroll
+	Type string `json:"type"`
+	// Action 鼠标事件：down、move、up
+	Action string  `json:"action,omitempty"`
+	X      float64 `json:"x,omitempty"`
+	Y      float64 `json:"y,omitempty"`
+	// Key 按键名称，与浏览器 KeyboardEvent.key 一致
+	Key    string  `json:"key,omitempty"`
+	Text   string  `json:"text,omitempty"`
+	DeltaY float64 `json:"delta_y,omitempty"`
+}
+
+// takeoverKeys 支持转发的特殊按键，可打印字符直接以文本输入
+var takeoverKeys = map[string]input.Key{
+	"Enter":      input.Enter,
+	"Backspace":  input.Backspace,
+	"Tab":        input.Tab,
+	"Escape":     input.Escape,
+	"Delete":     input.Delete,
+	"ArrowLeft":  input.ArrowLeft,
+	"ArrowRight": input.ArrowRight,
+	"ArrowUp":    input.ArrowUp,
+	"ArrowDown":  input.ArrowDown,
+}
+
+// TakeoverSession 一次人工接管：转播页面画面，转发操作员的输入，等待操作员标记处理完成
+type TakeoverSession struct {
+	info TakeoverInfo
+	page *rod.Page
+	// token 访问接管页面的凭证，随接管结束失效
+	token string
+
+	mu          sync.Mutex
+	subscribers map[chan takeoverFrame]struct{}
+	lastFrame   *takeoverFrame
+	mousePushed bool
+
+	// result 操作员的处理结果，true 表示已解决
+	result   chan bool
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// finish 结束接管，resolved 表示操作员是否已解决
+func (t *TakeoverSession) finish(resolved bool) bool {
+	select {
+	case t.result <- resolved:
+		return true
+	default:
+		return false
+	}
+}
+
+func (t *TakeoverSession) close() {
+	t.doneOnce.Do(func() { close(t.done) })
+}
+
+// subscribe 订阅画面，新订阅方会立即收到最新一帧
+func (t *TakeoverSession) subscribe() chan takeoverFrame {
+	ch := make(chan takeoverFrame, 4)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.subscribers[ch] = struct{}{}
+	if t.lastFrame != nil {
+		ch <- *t.lastFrame
+	}
+	return ch
+}
+
+func (t *TakeoverSession) unsubscribe(ch chan takeoverFrame) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.subscribers, ch)
+}
+
+// broadcast 发送画面，订阅方处理不过来时丢弃该帧
+func (t *TakeoverSession) broadcast(frame takeoverFrame) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.lastFrame = &frame
+	for ch := range t.subscribers {
+		select {
+		case ch <- frame:
+		default:
+		}
+	}
+}
+
+// startScreencast 通过 CDP 截屏转播页面画面，返回停止函数
+func (t *TakeoverSession) startScreencast() (func(), error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	page := t.page.Context(ctx)
+
+	wait := page.EachEvent(func(e *proto.PageScreencastFrame) {
+		frame := takeoverFrame{Data: base64.StdEncoding.EncodeToString(e.Data)}
+		if e.Metadata != nil {
+			frame.Width, frame.Height = e.Metadata.DeviceWidth, e.Metadata.DeviceHeight
+		}
+		t.broadcast(frame)
+
+		_ = proto.PageScreencastFrameAck{SessionID: e.SessionID}.Call(page)
+	})
+	go wait()
+
+	quality := 60
+	if err := (proto.PageStartScreencast{Format: proto.PageStartScreencastFormatJpeg, Quality: &quality}).Call(page); err != nil {
+		cancel()
+		return nil, errors.Wrap(err, "start screencast")
+	}
+
+	return func() {
+		_ = proto.PageStopScreencast{}.Call(page)
+		cancel()
+	}, nil
+}
+
+// dispatch 将操作员的输入转发给页面
+func (t *TakeoverSession) dispatch(in takeoverInput) error {
+	page := t.page
+
+	switch in.Type {
+	case "mouse":
+		event := proto.InputDispatchMouseEvent{X: in.X, Y: in.Y, Button: proto.InputMouseButtonLeft}
+
+		t.mu.Lock()
+		switch in.Action {
+		case "down":
+			event.Type = proto.InputDispatchMouseEventTypeMousePressed
+			event.ClickCount = 1
+			t.mousePushed = true
+		case "up":
+			event.Type = proto.InputDispatchMouseEventTypeMouseReleased
+			event.ClickCount = 1
+			t.mousePushed = false
+		case "move":
+			// 按住拖动（例如滑块验证码）时需要带上按键状态
+			event.Type = proto.InputDispatchMouseEventTypeMouseMoved
+			buttons := 0
+			if t.mousePushed {
+				buttons = 1
+			} else {
+				event.Button = proto.InputMouseButtonNone
+			}
+			event.Buttons = &buttons
+		default:
+			t.mu.Unlock()
+			return errors.Errorf("unknown mouse action %q", in.Action)
+		}
+		t.mu.Unlock()
+
+		return event.Call(page)
+	case "scroll":
+		return proto.InputDispatchMouseEvent{
+			Type: proto.InputDispatchMouseEventTypeMouseWheel, X: in.X, Y: in.Y, DeltaY: in.DeltaY,
+		}.Call(page)
+	case "key":
+		if key, ok := takeoverKeys[in.Key]; ok {
+			return page.Keyboard.Type(key)
+		}
+		if utf8.RuneCountInString(in.Key) == 1 {
+			return page.InsertText(in.Key)
+		}
+		// 忽略 Shift、Control 等修饰键
+		return nil
+	case "text":
+		return page.InsertText(in.Text)
+	default:
+		return errors.Errorf("unknown input type %q", in.Type)
+	}
+}
+
+// TakeoverManager 管理等待人工接管的操作
+type TakeoverManager struct {
+	mu       sync.Mutex
+	sessions map[string]*TakeoverSession
+	baseURL  string
+	timeout  time.Duration
+}
+
+// NewTakeoverManager 创建人工接管管理器，baseURL 为操作员访问本服务的地址
+func NewTakeoverManager(baseURL string, timeout time.Duration) *TakeoverManager {
+	return &TakeoverManager{
+		sessions: make(map[string]*TakeoverSession),
+		baseURL:  baseURL,
+		timeout:  timeout,
+	}
+}
+
+// Await 遇到风控页面时请求人工接管，阻塞直到操作员标记已解决（返回 true）、放弃或超时
+func (m *TakeoverManager) Await(ctx context.Context, page *rod.Page, account string, riskErr *xiaohongshu.RiskControlError) bool {
+	session, err := m.create(page, account, riskErr)
+	if err != nil {
+		logrus.WithContext(ctx).WithError(err).Error("创建人工接管失败")
+		return false
+	}
+	defer m.remove(session)
+
+	stop, err := session.startScreencast()
+	if err != nil {
+		logrus.WithContext(ctx).WithError(err).Error("启动页面转播失败")
+		return false
+	}
+	defer stop()
+
+	announceTakeover(ctx, session.info, m.timeout)
+
+	timer := time.NewTimer(m.timeout)
+	defer timer.Stop()
+
+	select {
+	case resolved := <-session.result:
+		logrus.WithContext(ctx).Infof("人工接管结束，已解决: %v", resolved)
+		return resolved
+	case <-timer.C:
+		logrus.WithContext(ctx).Warn("等待人工处理超时")
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// announceTakeover 通知操作员处理接管。接管链接中的 token 可以完全控制已登录的浏览器，
+// 不写入服务日志，只通过 notifications/message 发给有 admin 权限的调用方；
+// 服务日志和其他调用方只收到接管 ID，由管理员通过 list_takeovers 获取链接
+func announceTakeover(ctx context.Context, info TakeoverInfo, timeout time.Duration) {
+	fields := logrus.Fields{"account": info.Account, "takeover": info.ID}
+	message := fmt.Sprintf("触发风控（%s），等待人工处理（接管 %s），请在 %s 内完成验证", info.Reason, info.ID, timeout)
+	hint := message + "，管理员可以通过 list_takeovers 获取接管链接"
+
+	if checkScope(ctx, ScopeAdmin) != nil {
+		// 带上下文的日志同时发送给发起调用的 MCP 客户端
+		logrus.WithContext(ctx).WithFields(fields).Warn(hint)
+		return
+	}
+
+	logrus.WithFields(fields).Warn(hint)
+	notifyFields := logrus.Fields{"view_url": info.ViewURL}
+	for k, v := range fields {
+		notifyFields[k] = v
+	}
+	notifyLog(ctx, logrus.WarnLevel, message+"，打开 "+info.ViewURL+" 操作页面", notifyFields)
+}
+
+func (m *TakeoverManager) create(page *rod.Page, account string, riskErr *xiaohongshu.RiskControlError) (*TakeoverSession, error) {
+	id, err := randomHex(16)
+	if err != nil {
+		return nil, errors.Wrap(err, "generate takeover id")
+	}
+	token, err := randomHex(32)
+	if err != nil {
+		return nil, errors.Wrap(err, "generate takeover token")
+	}
+
+	now := time.Now()
+	session := &TakeoverSession{
+		info: TakeoverInfo{
+			ID:        id,
+			Account:   displayAccount(account),
+			Reason:    riskErr.Reason,
+			PageURL:   riskErr.URL,
+			ViewURL:   m.baseURL + "/takeover/" + id + "?token=" + token,
+			CreatedAt: now,
+			ExpiresAt: now.Add(m.timeout),
+		},
+		page:        page,
+		token:       token,
+		subscribers: make(map[chan takeoverFrame]struct{}),
+		result:      make(chan bool, 1),
+		done:        make(chan struct{}),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[id] = session
+	return session, nil
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func (m *TakeoverManager) remove(session *TakeoverSession) {
+	m.mu.Lock()
+	delete(m.sessions, session.info.ID)
+	m.mu.Unlock()
+
+	session.close()
+}
+
+// Get 根据 ID 获取接管会话
+func (m *TakeoverManager) Get(id string) (*TakeoverSession, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, ok := m.sessions[id]
+	return session, ok
+}
+
+// List 所有等待人工处理的操作，按创建时间排序
+func (m *TakeoverManager) List() []TakeoverInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	infos := make([]TakeoverInfo, 0, len(m.sessions))
+	for _, session := range m.sessions {
+		infos = append(infos, session.info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].CreatedAt.Before(infos[j].CreatedAt) })
+	return infos
+}
+
+// SetTakeover 开启人工接管：遇到风控页面时保持页面打开，等待操作员通过实时画面处理后继续执行
+func (s *XiaohongshuService) SetTakeover(takeover *TakeoverManager) {
+	s.takeover = takeover
+}
+
+// awaitTakeover 操作因风控失败时请求人工接管，返回是否可以重试。
+// 重试会从头重新执行操作而不是从中断处继续；发布操作只在点击发布按钮之前检测风控，重新执行不会重复发布
+func (s *XiaohongshuService) awaitTakeover(ctx context.Context, page *rod.Page, err error, attempt int) bool {
+	var riskErr *xiaohongshu.RiskControlError
+	if s.takeover == nil || attempt >= maxTakeoverAttempts || !errors.As(err, &riskErr) {
+		return false
+	}
+	return s.takeover.Await(ctx, page, accountFromContext(ctx), riskErr)
+}
+
+// setupTakeoverRoutes 人工接管页面。浏览器的 EventSource 无法携带认证头，
+// 因此这些路由除了 admin 权限的 API Key 之外，也接受接管链接中的 token，
+// token 只通过需要 admin 权限的接口和日志提供，接管结束后失效
+func (s *AppServer) setupTakeoverRoutes(router *gin.Engine) {
+	group := router.Group("/takeover/:id", s.takeoverSessionMiddleware())
+	group.GET("", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", takeoverPage)
+	})
+	group.GET("/stream", takeoverStreamHandler)
+	group.POST("/input", takeoverInputHandler)
+	group.POST("/resolve", func(c *gin.Context) { takeoverFinishHandler(c, true) })
+	group.POST("/abort", func(c *gin.Context) { takeoverFinishHandler(c, false) })
+}
+
+func (s *AppServer) takeoverSessionMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		takeover := s.xiaohongshuService.takeover
+		if takeover == nil {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+
+		session, ok := takeover.Get(c.Param("id"))
+		if !ok {
+			c.String(http.StatusNotFound, "接管已结束或不存在")
+			c.Abort()
+			return
+		}
+
+		if !s.takeoverAuthorized(c, session) {
+			c.String(http.StatusUnauthorized, "缺少或无效的接管凭证")
+			c.Abort()
+			return
+		}
+
+		c.Set("takeover", session)
+		c.Next()
+	}
+}
+
+// takeoverAuthorized 请求携带了接管链接中的 token，或者有 admin 权限的 API Key
+func (s *AppServer) takeoverAuthorized(c *gin.Context, session *TakeoverSession) bool {
+	token := c.Query("token")
+	if token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(session.token)) == 1 {
+		return true
+	}
+	if s.auth == nil {
+		return false
+	}
+	key, ok := s.auth.Authenticate(c.Request)
+	return ok && key.Allows(ScopeAdmin)
+}
+
+// takeoverStreamHandler 以 SSE 推送页面画面，接管结束时发送 closed 事件
+func takeoverStreamHandler(c *gin.Context) {
+	session := c.MustGet("takeover").(*TakeoverSession)
+
+	frames := session.subscribe()
+	defer session.unsubscribe(frames)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-session.done:
+			fmt.Fprint(c.Writer, "event: closed\ndata: {}\n\n")
+			c.Writer.Flush()
+			return
+		case frame := <-frames:
+			data, _ := json.Marshal(frame)
+			fmt.Fprintf(c.Writer, "event: frame\ndata: %s\n\n", data)
+			c.Writer.Flush()
+		}
+	}
+}
+
+// takeoverInputHandler 转发操作员的输入
+func takeoverInputHandler(c *gin.Context) {
+	session := c.MustGet("takeover").(*TakeoverSession)
+
+	var in takeoverInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", "请求参数错误", err.Error())
+		return
+	}
+
+	if err := session.dispatch(in); err != nil {
+		respondError(c, http.StatusBadRequest, "INPUT_FAILED", "转发输入失败", err.Error())
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// takeoverFinishHandler 操作员标记已解决或放弃
+func takeoverFinishHandler(c *gin.Context, resolved bool) {
+	session := c.MustGet("takeover").(*TakeoverSession)
+
+	if !session.finish(resolved) {
+		respondError(c, http.StatusConflict, "TAKEOVER_FINISHED", "接管已结束", nil)
+		return
+	}
+
+	message := "已放弃，操作将返回风控错误"
+	if resolved {
+		message = "已标记为解决，操作将继续执行"
+	}
+	respondSuccess(c, session.info, message)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
+)
+
+func TestTakeoverRoutes(t *testing.T) {
+	service := NewXiaohongshuService()
+	manager := NewTakeoverManager("http://localhost:18060", time.Minute)
+	service.SetTakeover(manager)
+	appServer := NewAppServer(service)
+	appServer.EnableAuth(testAuthConfig)
+	router := setupRoutes(appServer)
+
+	session, err := manager.create(nil, "alice", &xiaohongshu.RiskControlError{
+		URL: "https://www.xiaohongshu.com/website-login/captcha", Reason: "出现验证码",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:18060/takeover/"+session.info.ID+"?token="+session.token, session.info.ViewURL)
+	path := "/takeover/" + session.info.ID
+	query := "?token=" + session.token
+
+	request := func(method, path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// 接管列表需要 admin 权限
+	assert.Equal(t, http.StatusForbidden, request(http.MethodGet, "/api/v1/takeovers", "read-key").Code)
+	recorder := request(http.MethodGet, "/api/v1/takeovers", "admin-key")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), session.info.ViewURL)
+
+	// 接管页面需要链接中的 token 或 admin 权限的 API Key，只知道 ID 不能访问
+	recorder = request(http.MethodGet, path+query, "")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "EventSource")
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, path, "admin-key").Code)
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, path, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, path, "read-key").Code)
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, path+"?token=wrong", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, path+"/stream", "").Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/takeover/unknown"+query, "").Code)
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, path+"/input", strings.NewReader(`{"type":"mouse"}`)))
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, path+"/input"+query, strings.NewReader(`{"type":"unknown"}`)))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodPost, path+"/resolve", "").Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, path+"/resolve"+query, "").Code)
+	assert.True(t, <-session.result)
+	// 每次接管只能结束一次
+	session.result <- true
+	assert.Equal(t, http.StatusConflict, request(http.MethodPost, path+"/abort"+query, "").Code)
+
+	manager.remove(session)
+	assert.Empty(t, manager.List())
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, path+query, "").Code)
+	select {
+	case <-session.done:
+	default:
+		t.Fatal("takeover session should be closed")
+	}
+}
+
+func TestTakeoverBroadcast(t *testing.T) {
+	session := &TakeoverSession{subscribers: make(map[chan takeoverFrame]struct{})}
+
+	first := session.subscribe()
+	session.broadcast(takeoverFrame{Data: "1", Width: 1280, Height: 720})
+	assert.Equal(t, "1", (<-first).Data)
+
+	// 新订阅方立即收到最新一帧
+	second := session.subscribe()
+	assert.Equal(t, "1", (<-second).Data)
+
+	session.unsubscribe(first)
+	session.broadcast(takeoverFrame{Data: "2"})
+	assert.Equal(t, "2", (<-second).Data)
+	assert.Empty(t, first)
+}
+
+func TestAnnounceTakeoverKeepsTokenOutOfServerLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logrus.StandardLogger()
+	output, hooks := logger.Out, logger.ReplaceHooks(logrus.LevelHooks{})
+	logger.SetOutput(&buf)
+	logger.AddHook(&mcpLogHook{})
+	t.Cleanup(func() {
+		logger.SetOutput(output)
+		logger.ReplaceHooks(hooks)
+	})
+
+	info := TakeoverInfo{
+		ID:      "tk1",
+		Account: "alice",
+		Reason:  "滑块验证",
+		ViewURL: "http://example.com/takeover/tk1?token=secret",
+	}
+	receive := func(session *MCPSession) string {
+		select {
+		case data := <-session.stream:
+			return string(data)
+		default:
+			t.Fatal("no notification")
+			return ""
+		}
+	}
+
+	// admin 调用方通过通知收到链接
+	admin := newMCPSession("admin")
+	ctx := withAPIKey(withSession(context.Background(), admin), &APIKey{Name: "ops", Scopes: []Scope{ScopeAdmin}})
+	announceTakeover(ctx, info, 10*time.Minute)
+	assert.Contains(t, receive(admin), "token=secret")
+
+	// 其他调用方只收到接管 ID
+	reader := newMCPSession("reader")
+	ctx = withAPIKey(withSession(context.Background(), reader), &APIKey{Name: "bot", Scopes: []Scope{ScopeRead}})
+	announceTakeover(ctx, info, 10*time.Minute)
+	notification := receive(reader)
+	assert.Contains(t, notification, "tk1")
+	assert.NotContains(t, notification, "secret")
+
+	assert.Contains(t, buf.String(), "tk1")
+	assert.NotContains(t, buf.String(), "secret")
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// Tool 工具定义。MCP 的 tools/list、tools/call 以及 /api/v1 下的 REST 接口都由同一份定义生成
+type Tool struct {
+	Name        string
+	Description string
+
+	// REST 接口的请求方法和路径（相对 /api/v1）
+	Method string
+	Path   string
+
+	// SuccessMessage REST 接口成功时的提示信息
+	SuccessMessage string
+	// ErrorCode、ErrorMessage 执行失败时返回的错误码和错误信息
+	ErrorCode    string
+	ErrorMessage string
+	// ArgumentErrors 参数校验失败时按参数名返回的错误码和错误信息，未列出的参数返回 INVALID_REQUEST
+	ArgumentErrors map[string]ToolError
+
+	// InputSchema、OutputSchema 由输入输出结构体生成
+	InputSchema  map[string]any
+	OutputSchema map[string]any
+
+	Annotations ToolAnnotations
+	// RequiresConfirmation 开启确认模式时，需要先确认才会执行
+	RequiresConfirmation bool
+	// Scope 开启认证时，调用方需要具备的权限
+	Scope Scope
+
+	newInput  func() any
+	call      func(ctx context.Context, s *AppServer, input any) (any, error)
+	mcpResult func(ctx context.Context, s *AppServer, input, output any) *MCPToolResult
+}
+
+// ToolError REST 接口的错误码和错误信息
+type ToolError struct {
+	Code    string
+	Message string
+}
+
+// ToolAnnotations MCP 工具注解，帮助客户端判断工具的副作用
+type ToolAnnotations struct {
+	Title           string `json:"title,omitempty"`
+	ReadOnlyHint    bool   `json:"readOnlyHint"`
+	DestructiveHint bool   `json:"destructiveHint"`
+	IdempotentHint  bool   `json:"idempotentHint"`
+	OpenWorldHint   bool   `json:"openWorldHint"`
+}
+
+// toolSpec 类型化的工具定义，In、Out 为输入输出结构体
+type toolSpec[In, Out any] struct {
+	Name           string
+	Description    string
+	Method         string
+	Path           string
+	SuccessMessage string
+	ErrorCode      string
+	ErrorMessage   string
+	ArgumentErrors map[string]ToolError
+
+	Annotations          ToolAnnotations
+	RequiresConfirmation bool
+	Scope                Scope
+
+	// Handler 执行工具
+	Handler func(ctx context.Context, s *AppServer, in *In) (*Out, error)
+	// MCPResult 自定义 MCP 工具结果（例如附带图片、资源链接），为空时只返回结构化数据
+	MCPResult func(ctx context.Context, s *AppServer, in *In, out *Out) *MCPToolResult
+}
+
+// newTool 根据类型化的定义创建工具
+func newTool[In, Out any](spec toolSpec[In, Out]) *Tool {
+	tool := &Tool{
+		Name:           spec.Name,
+		Description:    spec.Description,
+		Method:         spec.Method,
+		Path:           spec.Path,
+		SuccessMessage: spec.SuccessMessage,
+		ErrorCode:      spec.ErrorCode,
+		ErrorMessage:   spec.ErrorMessage,
+		ArgumentErrors: spec.ArgumentErrors,
+		InputSchema:    schemaFor[In](),
+		OutputSchema:   schemaFor[Out](),
+
+		Annotations:          spec.Annotations,
+		RequiresConfirmation: spec.RequiresConfirmation,
+		Scope:                spec.Scope,
+
+		newInput: func() any { return new(In) },
+		call: func(ctx context.Context, s *AppServer, input any) (any, error) {
+			return spec.Handler(ctx, s, input.(*In))
+		},
+	}
+
+	if spec.MCPResult != nil {
+		tool.mcpResult = func(ctx context.Context, s *AppServer, input, output any) *MCPToolResult {
+			return spec.MCPResult(ctx, s, input.(*In), output.(*Out))
+		}
+	}
+
+	return tool
+}
+
+// Decode 按 inputSchema 校验参数，并解码为输入结构体。
+// 校验失败时返回 *ValidationError，包含所有不合法的参数
+func (t *Tool) Decode(args map[string]any) (any, error) {
+	// 统一转换为 JSON 解码后的类型再校验
+	data, err := json.Marshal(args)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid arguments")
+	}
+
+	values := map[string]any{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, errors.Wrap(err, "invalid arguments")
+	}
+
+	if fields := validateSchema(t.InputSchema, values); len(fields) > 0 {
+		return nil, &ValidationError{Fields: fields}
+	}
+
+	input := t.newInput()
+	if err := json.Unmarshal(data, input); err != nil {
+		return nil, errors.Wrap(err, "invalid arguments")
+	}
+
+	return input, nil
+}
+
+// Call 执行工具
+func (t *Tool) Call(ctx context.Context, s *AppServer, input any) (any, error) {
+	return t.call(ctx, s, input)
+}
+
+// MCPResult 执行工具并构建 MCP 工具结果
+func (t *Tool) MCPResult(ctx context.Context, s *AppServer, input any) *MCPToolResult {
+	output, err := t.Call(ctx, s, input)
+	if err != nil {
+		return errorResult(t.ErrorMessage, err)
+	}
+
+	if t.mcpResult != nil {
+		return t.mcpResult(ctx, s, input, output)
+	}
+	return structuredResult(output)
+}
+
+// MCPDefinition tools/list 中的工具描述。
+// confirmMode 为 true 时，需要确认的工具额外声明 confirmation_token 参数和预览结果的结构
+func (t *Tool) MCPDefinition(confirmMode bool) map[string]any {
+	inputSchema, outputSchema := t.InputSchema, t.OutputSchema
+
+	if confirmMode && t.RequiresConfirmation {
+		properties := map[string]any{
+			confirmationTokenParam: map[string]any{
+				"type":        "string",
+				"description": "确认令牌。不传时只返回预览和令牌，不会执行；确认无误后携带相同参数和令牌再次调用",
+			},
+		}
+		for name, property := range t.InputSchema["properties"].(map[string]any) {
+			properties[name] = property
+		}
+
+		inputSchema = make(map[string]any, len(t.InputSchema))
+		for k, v := range t.InputSchema {
+			inputSchema[k] = v
+		}
+		inputSchema["properties"] = properties
+
+		outputSchema = map[string]any{
+			"type":  "object",
+			"anyOf": []any{t.OutputSchema, schemaFor[PendingConfirmation]()},
+		}
+	}
+
+	return map[string]any{
+		"name":         t.Name,
+		"description":  t.Description,
+		"inputSchema":  inputSchema,
+		"outputSchema": outputSchema,
+		"annotations":  t.Annotations,
+	}
+}
+
+// queryArguments 按 inputSchema 的类型将 URL 查询参数转换为工具参数
+func (t *Tool) queryArguments(query map[string][]string) map[string]any {
+	properties, _ := t.InputSchema["properties"].(map[string]any)
+
+	args := make(map[string]any, len(query))
+	for name, values := range query {
+		if len(values) == 0 {
+			continue
+		}
+
+		property, _ := properties[name].(map[string]any)
+		switch property["type"] {
+		case "array":
+			items := make([]any, len(values))
+			for i, v := range values {
+				items[i] = v
+			}
+			args[name] = items
+		case "boolean":
+			if v, err := strconv.ParseBool(values[0]); err == nil {
+				args[name] = v
+			} else {
+				args[name] = values[0]
+			}
+		case "integer", "number":
+			if v, err := strconv.ParseFloat(values[0], 64); err == nil {
+				args[name] = v
+			} else {
+				args[name] = values[0]
+			}
+		default:
+			args[name] = values[0]
+		}
+	}
+	return args
+}
+
+// ToolRegistry 工具注册表
+type ToolRegistry struct {
+	tools  []*Tool
+	byName map[string]*Tool
+}
+
+// NewToolRegistry 创建工具注册表
+func NewToolRegistry(tools ...*Tool) *ToolRegistry {
+	r := &ToolRegistry{byName: make(map[string]*Tool, len(tools))}
+	for _, tool := range tools {
+		if _, ok := r.byName[tool.Name]; ok {
+			panic("duplicate tool: " + tool.Name)
+		}
+		r.tools = append(r.tools, tool)
+		r.byName[tool.Name] = tool
+	}
+	return r
+}
+
+// Get 根据名称查找工具
+func (r *ToolRegistry) Get(name string) (*Tool, bool) {
+	tool, ok := r.byName[name]
+	return tool, ok
+}
+
+// List 按注册顺序返回所有工具
+func (r *ToolRegistry) List() []*Tool {
+	return r.tools
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolSchemaFromStruct(t *testing.T) {
+	registry := NewToolRegistry(defaultTools()...)
+
+	tool, ok := registry.Get("publish_content")
+	require.True(t, ok)
+
+	properties := tool.InputSchema["properties"].(map[string]any)
+	assert.NotContains(t, properties, "video")
+	assert.Equal(t, []string{"title", "content", "images"}, tool.InputSchema["required"])
+
+	images := properties["images"].(map[string]any)
+	assert.Equal(t, "array", images["type"])
+	assert.Equal(t, 1, images["minItems"])
+	assert.Equal(t, map[string]any{"type": "string"}, images["items"])
+	assert.NotEmpty(t, images["description"])
+
+	// 递归结构（子评论）不会无限展开
+	detail, ok := registry.Get("get_feed_detail")
+	require.True(t, ok)
+	assert.Equal(t, "object", detail.OutputSchema["type"])
+}
+
+func TestToolDecode(t *testing.T) {
+	tool, ok := NewToolRegistry(defaultTools()...).Get("publish_content")
+	require.True(t, ok)
+
+	input, err := tool.Decode(map[string]any{"title": "标题", "content": "正文", "images": []any{"a.jpg"}})
+	require.NoError(t, err)
+	assert.Equal(t, &PublishRequest{Title: "标题", Content: "正文", Images: []string{"a.jpg"}}, input)
+
+	_, err = tool.Decode(map[string]any{"title": "标题", "content": "正文", "images": []any{1}})
+	assert.Error(t, err)
+
+	_, err = tool.Decode(map[string]any{"content": "正文", "images": []any{"a.jpg"}})
+	assert.Error(t, err)
+}
+
+func TestToolDecodeEmptyRequiredString(t *testing.T) {
+	registry := NewToolRegistry(defaultTools()...)
+
+	publish, ok := registry.Get("publish_content")
+	require.True(t, ok)
+	assert.Equal(t, 1, publish.InputSchema["properties"].(map[string]any)["title"].(map[string]any)["minLength"])
+
+	for _, field := range []string{"title", "content"} {
+		args := map[string]any{"title": "标题", "content": "正文", "images": []any{"a.jpg"}}
+		args[field] = ""
+		_, err := publish.Decode(args)
+		var validationErr *ValidationError
+		require.ErrorAs(t, err, &validationErr, field)
+		assert.Equal(t, []FieldError{{Field: field, Rule: "minLength", Message: "长度不能少于 1 个字符"}}, validationErr.Fields)
+	}
+
+	search, ok := registry.Get("search_feeds")
+	require.True(t, ok)
+	_, err := search.Decode(map[string]any{"keyword": ""})
+	assert.Error(t, err)
+}
+
+func TestToolQueryArguments(t *testing.T) {
+	tool, ok := NewToolRegistry(defaultTools()...).Get("search_feeds")
+	require.True(t, ok)
+
+	args := tool.queryArguments(url.Values{"keyword": {"露营"}, "include_covers": {"true"}})
+	assert.Equal(t, map[string]any{"keyword": "露营", "include_covers": true}, args)
+}
+
+func TestToolRESTValidation(t *testing.T) {
+	router := setupRoutes(NewAppServer(NewXiaohongshuService()))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/feeds/search", nil))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "MISSING_KEYWORD")
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/publish", strings.NewReader(`{"title":"标题"}`)))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "INVALID_REQUEST")
+}
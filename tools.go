@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
+)
+
+// defaultTools 所有工具的定义，新增工具只需要在这里注册
+func defaultTools() []*Tool {
+	return []*Tool{
+		newTool(toolSpec[struct{}, LoginStatusResponse]{
+			Name:           "check_login_status",
+			Description:    "检查小红书登录状态",
+			Method:         http.MethodGet,
+			Path:           "/login/status",
+			SuccessMessage: "检查登录状态成功",
+			ErrorCode:      "STATUS_CHECK_FAILED",
+			ErrorMessage:   "检查登录状态失败",
+			Annotations:    readOnlyAnnotations,
+			Scope:          ScopeRead,
+			Handler:        checkLoginStatus,
+			MCPResult:      loginStatusResult,
+		}),
+		newTool(toolSpec[struct{}, LoginQrcodeResponse]{
+			Name:           "get_login_qrcode",
+			Description:    "获取小红书登录二维码（图片），用户需在返回的超时时间内用小红书 App 扫码",
+			Method:         http.MethodGet,
+			Path:           "/login/qrcode",
+			SuccessMessage: "获取登录二维码成功",
+			ErrorCode:      "GET_LOGIN_QRCODE_FAILED",
+			ErrorMessage:   "获取登录二维码失败",
+			Annotations:    loginAnnotations,
+			Scope:          ScopeAdmin,
+			Handler:        getLoginQrcode,
+			MCPResult:      loginQrcodeResult,
+		}),
+		newTool(toolSpec[PublishRequest, PublishResponse]{
+			Name:                 "publish_content",
+			Description:          "发布小红书图文内容",
+			Method:               http.MethodPost,
+			Path:                 "/publish",
+			SuccessMessage:       "发布成功",
+			ErrorCode:            "PUBLISH_FAILED",
+			ErrorMessage:         "发布失败",
+			Annotations:          publishAnnotations,
+			Scope:                ScopePublish,
+			RequiresConfirmation: true,
+			Handler:              publishContent,
+		}),
+		newTool(toolSpec[PublishLongTextRequest, PublishResponse]{
+			Name:                 "publish_longtext",
+			Description:          "发布长文到小红书",
+			Method:               http.MethodPost,
+			Path:                 "/publish-longtext",
+			SuccessMessage:       "长文发布成功",
+			ErrorCode:            "PUBLISH_LONGTEXT_FAILED",
+			ErrorMessage:         "长文发布失败",
+			Annotations:          publishAnnotations,
+			Scope:                ScopePublish,
+			RequiresConfirmation: true,
+			Handler:              publishLongText,
+		}),
+		newTool(toolSpec[ListFeedsRequest, FeedsListResponse]{
+			Name:           "list_feeds",
+			Description:    "获取小红书首页推荐列表",
+			Method:         http.MethodGet,
+			Path:           "/feeds/list",
+			SuccessMessage: "获取Feeds列表成功",
+			ErrorCode:      "LIST_FEEDS_FAILED",
+			ErrorMessage:   "获取Feeds列表失败",
+			Annotations:    readOnlyAnnotations,
+			Scope:          ScopeRead,
+			Handler:        listFeeds,
+			MCPResult: func(ctx context.Context, s *AppServer, in *ListFeedsRequest, out *FeedsListResponse) *MCPToolResult {
+				return feedsResult(ctx, out, in.IncludeCovers)
+			},
+		}),
+		newTool(toolSpec[SearchFeedsRequest, FeedsListResponse]{
+			Name:           "search_feeds",
+			Description:    "搜索小红书内容（需要已登录）",
+			Method:         http.MethodGet,
+			Path:           "/feeds/search",
+			SuccessMessage: "搜索Feeds成功",
+			ErrorCode:      "SEARCH_FEEDS_FAILED",
+			ErrorMessage:   "搜索Feeds失败",
+			Annotations:    readOnlyAnnotations,
+			Scope:          ScopeRead,
+			Handler:        searchFeeds,
+			MCPResult: func(ctx context.Context, s *AppServer, in *SearchFeedsRequest, out *FeedsListResponse) *MCPToolResult {
+				return feedsResult(ctx, out, in.IncludeCovers)
+			},
+			ArgumentErrors: map[string]ToolError{
+				"keyword": {Code: "MISSING_KEYWORD", Message: "缺少关键词参数"},
+			},
+		}),
+		newTool(toolSpec[FeedDetailRequest, FeedDetailResponse]{
+			Name:           "get_feed_detail",
+			Description:    "获取小红书笔记详情，返回笔记内容、图片、作者信息、互动数据（点赞/收藏/分享数）及评论列表",
+			Method:         http.MethodPost,
+			Path:           "/feeds/detail",
+			SuccessMessage: "获取Feed详情成功",
+			ErrorCode:      "GET_FEED_DETAIL_FAILED",
+			ErrorMessage:   "获取Feed详情失败",
+			Annotations:    readOnlyAnnotations,
+			Scope:          ScopeRead,
+			Handler:        getFeedDetail,
+			MCPResult:      feedDetailResult,
+		}),
+		newTool(toolSpec[struct{}, AccountStatusResponse]{
+			Name:           "get_account_status",
+			Description:    "查看账号的风控熔断状态：触发风控（验证码、安全验证）的账号会暂停自动化操作一段时间",
+			Method:         http.MethodGet,
+			Path:           "/accounts/status",
+			SuccessMessage: "获取账号状态成功",
+			ErrorCode:      "GET_ACCOUNT_STATUS_FAILED",
+			ErrorMessage:   "获取账号状态失败",
+			Annotations:    localReadOnlyAnnotations,
+			Scope:          ScopeRead,
+			Handler:        getAccountStatus,
+		}),
+		newTool(toolSpec[ResumeAccountRequest, ResumeAccountResponse]{
+			Name:           "resume_account",
+			Description:    "人工完成验证后，提前恢复因风控暂停的账号",
+			Method:         http.MethodPost,
+			Path:           "/accounts/resume",
+			SuccessMessage: "恢复账号成功",
+			ErrorCode:      "RESUME_ACCOUNT_FAILED",
+			ErrorMessage:   "恢复账号失败",
+			Annotations:    ToolAnnotations{IdempotentHint: true},
+			Scope:          ScopeAdmin,
+			Handler:        resumeAccount,
+		}),
+		newTool(toolSpec[struct{}, TakeoverListResponse]{
+			Name:           "list_takeovers",
+			Description:    "列出因风控等待人工处理的操作，打开返回的 view_url 即可查看实时画面并操作页面",
+			Method:         http.MethodGet,
+			Path:           "/takeovers",
+			SuccessMessage: "获取人工接管列表成功",
+			ErrorCode:      "LIST_TAKEOVERS_FAILED",
+			ErrorMessage:   "获取人工接管列表失败",
+			Annotations:    localReadOnlyAnnotations,
+			Scope:          ScopeAdmin,
+			Handler:        listTakeovers,
+		}),
+		newTool(toolSpec[struct{}, xiaohongshu.SelectorReport]{
+			Name:           "get_selector_report",
+			Description:    "查看当前生效的页面元素选择器版本，以及每个选择器各条规则的命中次数，用于发现小红书页面改版导致失效的选择器",
+			Method:         http.MethodGet,
+			Path:           "/selectors/report",
+			SuccessMessage: "获取选择器报告成功",
+			ErrorCode:      "GET_SELECTOR_REPORT_FAILED",
+			ErrorMessage:   "获取选择器报告失败",
+			Annotations:    localReadOnlyAnnotations,
+			Scope:          ScopeAdmin,
+			Handler:        getSelectorReport,
+		}),
+	}
+}
+
+// 访问小红书网站的工具 openWorldHint 为 true
+var (
+	// readOnlyAnnotations 只读取数据的工具
+	readOnlyAnnotations = ToolAnnotations{ReadOnlyHint: true, IdempotentHint: true, OpenWorldHint: true}
+	// localReadOnlyAnnotations 只读取服务本地状态的工具
+	localReadOnlyAnnotations = ToolAnnotations{ReadOnlyHint: true, IdempotentHint: true}
+	// loginAnnotations 登录会保存本地 cookies，但不会修改账号数据
+	loginAnnotations = ToolAnnotations{IdempotentHint: true, OpenWorldHint: true}
+	// publishAnnotations 公开发布笔记，重复调用会发布多篇
+	publishAnnotations = ToolAnnotations{OpenWorldHint: true}
+)
+
+// checkLoginStatus 检查登录状态
+func checkLoginStatus(ctx context.Context, s *AppServer, _ *struct{}) (*LoginStatusResponse, error) {
+	return s.xiaohongshuService.CheckLoginStatus(ctx)
+}
+
+// loginStatusResult 未登录时提示获取二维码
+func loginStatusResult(_ context.Context, _ *AppServer, _ *struct{}, status *LoginStatusResponse) *MCPToolResult {
+	result := structuredResult(status)
+	if !status.IsLoggedIn {
+		result.Content = append(result.Content, textContent("当前未登录，可以调用 get_login_qrcode 获取登录二维码"))
+	}
+	return result
+}
+
+// getLoginQrcode 获取登录二维码
+func getLoginQrcode(ctx context.Context, s *AppServer, _ *struct{}) (*LoginQrcodeResponse, error) {
+	return s.xiaohongshuService.GetLoginQrcode(ctx)
+}
+
+// loginQrcodeResult 以图片内容块返回二维码
+func loginQrcodeResult(_ context.Context, _ *AppServer, _ *struct{}, result *LoginQrcodeResponse) *MCPToolResult {
+	if result.IsLoggedIn {
+		return structuredResult(result, textContent("当前已处于登录状态"))
+	}
+
+	img, mimeType, err := decodeDataURL(result.Img)
+	if err != nil {
+		return errorResult("解析登录二维码失败", err)
+	}
+
+	return structuredResult(result,
+		textContent(fmt.Sprintf("请在 %s 内使用小红书 App 扫码登录", result.Timeout)),
+		imageContent(img, mimeType),
+	)
+}
+
+// publishContent 发布图文内容
+func publishContent(ctx context.Context, s *AppServer, req *PublishRequest) (*PublishResponse, error) {
+	logrus.WithContext(ctx).Infof("发布内容 - 标题: %s, 图片数量: %d", req.Title, len(req.Images))
+	return s.xiaohongshuService.PublishContent(ctx, req)
+}
+
+// publishLongText 发布长文
+func publishLongText(ctx context.Context, s *AppServer, req *PublishLongTextRequest) (*PublishResponse, error) {
+	logrus.WithContext(ctx).Infof("发布长文 - 标题: %s", req.Title)
+	return s.xiaohongshuService.PublishLongText(ctx, req)
+}
+
+// listFeeds 获取首页推荐列表
+func listFeeds(ctx context.Context, s *AppServer, _ *ListFeedsRequest) (*FeedsListResponse, error) {
+	return s.xiaohongshuService.ListFeeds(ctx)
+}
+
+// searchFeeds 搜索Feeds
+func searchFeeds(ctx context.Context, s *AppServer, req *SearchFeedsRequest) (*FeedsListResponse, error) {
+	logrus.WithContext(ctx).Infof("搜索Feeds - 关键词: %s", req.Keyword)
+	return s.xiaohongshuService.SearchFeeds(ctx, req.Keyword)
+}
+
+// feedsResult 构建 Feeds 列表结果：结构化数据、每条笔记的资源链接，以及可选的封面缩略图
+func feedsResult(ctx context.Context, result *FeedsListResponse, includeCovers bool) *MCPToolResult {
+	extra := feedResourceLinks(result.Feeds)
+	if includeCovers {
+		extra = append(extra, feedCoverThumbnails(ctx, result.Feeds)...)
+	}
+
+	return structuredResult(result, extra...)
+}
+
+// getFeedDetail 获取Feed详情
+func getFeedDetail(ctx context.Context, s *AppServer, req *FeedDetailRequest) (*FeedDetailResponse, error) {
+	logrus.WithContext(ctx).Infof("获取Feed详情 - Feed ID: %s", req.FeedID)
+	return s.xiaohongshuService.GetFeedDetail(ctx, req.FeedID, req.XsecToken)
+}
+
+// feedDetailResult 附带笔记的资源链接
+func feedDetailResult(_ context.Context, _ *AppServer, req *FeedDetailRequest, result *FeedDetailResponse) *MCPToolResult {
+	link := resourceLinkContent(noteResourceURI(req.FeedID, req.XsecToken), "笔记 "+req.FeedID,
+		"可通过 resources/read 读取，或 resources/subscribe 订阅互动数据变化", resourceMimeType)
+	return structuredResult(result, link)
+}
+
+// getAccountStatus 获取账号熔断状态，非 admin 调用方只能看到自己的账号
+func getAccountStatus(ctx context.Context, s *AppServer, _ *struct{}) (*AccountStatusResponse, error) {
+	breaker := s.xiaohongshuService.breaker
+	if breaker == nil {
+		return &AccountStatusResponse{Accounts: []AccountStatus{}}, nil
+	}
+
+	accounts := breaker.Status()
+	if key, ok := apiKeyFromContext(ctx); ok && !key.Allows(ScopeAdmin) {
+		own := displayAccount(key.Account)
+		filtered := accounts[:0]
+		for _, status := range accounts {
+			if status.Account == own {
+				filtered = append(filtered, status)
+			}
+		}
+		accounts = filtered
+	}
+
+	return &AccountStatusResponse{
+		CooldownSeconds: int(breaker.cooldown.Seconds()),
+		Accounts:        accounts,
+	}, nil
+}
+
+// resumeAccount 提前恢复账号
+func resumeAccount(ctx context.Context, s *AppServer, req *ResumeAccountRequest) (*ResumeAccountResponse, error) {
+	breaker := s.xiaohongshuService.breaker
+	if breaker == nil {
+		return nil, errors.New("未开启账号熔断")
+	}
+
+	account := req.Account
+	if account == "default" {
+		account = ""
+	}
+
+	resumed := breaker.Resume(account)
+	logrus.WithContext(ctx).Infof("恢复账号 %s - 此前处于暂停期: %v", displayAccount(account), resumed)
+	return &ResumeAccountResponse{Account: displayAccount(account), Resumed: resumed}, nil
+}
+
+// listTakeovers 列出等待人工处理的操作
+func listTakeovers(_ context.Context, s *AppServer, _ *struct{}) (*TakeoverListResponse, error) {
+	takeovers := []TakeoverInfo{}
+	if s.xiaohongshuService.takeover != nil {
+		takeovers = s.xiaohongshuService.takeover.List()
+	}
+	return &TakeoverListResponse{Takeovers: takeovers, Count: len(takeovers)}, nil
+}
+
+// getSelectorReport 当前生效的选择器及其命中情况
+func getSelectorReport(_ context.Context, _ *AppServer, _ *struct{}) (*xiaohongshu.SelectorReport, error) {
+	report := xiaohongshu.GetSelectorReport()
+	return &report, nil
+}
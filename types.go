@@ -1,5 +1,11 @@
 package main
 
+import (
+	"encoding/json"
+
+	"github.com/xpzouying/xiaohongshu-mcp/xiaohongshu"
+)
+
 // HTTP API 响应类型
 
 // ErrorResponse 错误响应
@@ -7,6 +13,8 @@ type ErrorResponse struct {
 	Error   string `json:"error"`
 	Code    string `json:"code"`
 	Details any    `json:"details,omitempty"`
+	// ArtifactID 浏览器操作失败时保存的失败现场，管理员可通过 /api/v1/artifacts/{id} 下载
+	ArtifactID string `json:"artifact_id,omitempty"`
 }
 
 // SuccessResponse 成功响应
@@ -26,6 +34,24 @@ type JSONRPCRequest struct {
 	ID      any    `json:"id"`
 }
 
+// IsNotification 是否为通知消息（没有 id，不需要响应）
+func (r *JSONRPCRequest) IsNotification() bool {
+	return r.Method != "" && r.ID == nil
+}
+
+// BindParams 将 params 解析到指定结构体
+func (r *JSONRPCRequest) BindParams(v any) error {
+	if r.Params == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(r.Params)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
 // JSONRPCResponse JSON-RPC 响应
 type JSONRPCResponse struct {
 	JSONRPC string        `json:"jsonrpc"`
@@ -34,6 +60,13 @@ type JSONRPCResponse struct {
 	ID      any           `json:"id"`
 }
 
+// JSONRPCNotification 服务端发送给客户端的 JSON-RPC 通知
+type JSONRPCNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
 // JSONRPCError JSON-RPC 错误
 type JSONRPCError struct {
 	Code    int    `json:"code"`
@@ -51,24 +84,67 @@ type MCPToolCall struct {
 
 // MCPToolResult MCP 工具结果
 type MCPToolResult struct {
-	Content []MCPContent `json:"content"`
-	IsError bool         `json:"isError,omitempty"`
+	Content           []MCPContent `json:"content"`
+	StructuredContent any          `json:"structuredContent,omitempty"`
+	IsError           bool         `json:"isError,omitempty"`
 }
 
-// MCPContent MCP 内容
+// MCPContent MCP 内容，根据 Type 使用不同字段：
+// text 使用 Text；image 使用 Data（base64）和 MimeType；resource_link 使用 URI、Name 等
 type MCPContent struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type        string `json:"type"`
+	Text        string `json:"text,omitempty"`
+	Data        string `json:"data,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+	URI         string `json:"uri,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Resource 内嵌资源，type 为 resource 时使用
+	Resource *MCPResourceContents `json:"resource,omitempty"`
+}
+
+// ListFeedsRequest Feeds列表请求
+type ListFeedsRequest struct {
+	IncludeCovers bool `json:"include_covers" description:"是否在结果中附带前几条笔记的封面缩略图（图片内容块），默认 false"`
+}
+
+// SearchFeedsRequest 搜索Feeds请求
+type SearchFeedsRequest struct {
+	Keyword       string `json:"keyword" binding:"required" description:"搜索关键词"`
+	IncludeCovers bool   `json:"include_covers" description:"是否在结果中附带前几条笔记的封面缩略图（图片内容块），默认 false"`
 }
 
 // FeedDetailRequest Feed详情请求
 type FeedDetailRequest struct {
-	FeedID    string `json:"feed_id" binding:"required"`
-	XsecToken string `json:"xsec_token" binding:"required"`
+	FeedID    string `json:"feed_id" binding:"required" description:"小红书笔记ID，从Feed列表获取"`
+	XsecToken string `json:"xsec_token" binding:"required" description:"访问令牌，从Feed列表的xsecToken字段获取"`
+}
+
+// AccountStatusResponse 账号风控熔断状态
+type AccountStatusResponse struct {
+	CooldownSeconds int             `json:"cooldown_seconds" description:"触发风控后暂停的时长（秒）"`
+	Accounts        []AccountStatus `json:"accounts" description:"触发过风控的账号"`
+}
+
+// ResumeAccountRequest 恢复账号请求
+type ResumeAccountRequest struct {
+	Account string `json:"account,omitempty" description:"账号名，为空或 default 表示默认账号"`
+}
+
+// ResumeAccountResponse 恢复账号响应
+type ResumeAccountResponse struct {
+	Account string `json:"account"`
+	Resumed bool   `json:"resumed" description:"账号此前是否处于暂停期"`
+}
+
+// TakeoverListResponse 等待人工处理的操作
+type TakeoverListResponse struct {
+	Takeovers []TakeoverInfo `json:"takeovers"`
+	Count     int            `json:"count"`
 }
 
 // FeedDetailResponse Feed详情响应
 type FeedDetailResponse struct {
-	FeedID string `json:"feed_id"`
-	Data   any    `json:"data"`
+	FeedID string                          `json:"feed_id"`
+	Data   *xiaohongshu.FeedDetailResponse `json:"data"`
 }
@@ -0,0 +1,397 @@
+package xiaohongshu
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// 网页版加载更多数据时调用的接口，首屏之后的搜索结果、评论和用户笔记只出现在这些接口的响应中
+const (
+	apiSearchNotes = "/api/sns/web/v1/search/notes"
+	apiComments    = "/api/sns/web/v2/comment/page"
+	apiUserPosted  = "/api/sns/web/v1/user_posted"
+)
+
+// apiPattern 捕获的接口地址
+const apiPattern = `/api/sns/web/`
+
+// apiWaitTimeout 等待页面发出接口请求的最长时间
+const apiWaitTimeout = 5 * time.Second
+
+// apiPollInterval 等待接口响应时的检查间隔
+const apiPollInterval = 200 * time.Millisecond
+
+// apiScrollPages 每次操作最多滚动加载的页数
+var apiScrollPages atomic.Int32
+
+// SetAPIScrollPages 设置搜索、详情和用户主页滚动加载更多的页数，0 表示只使用首屏数据。
+// 每滚动一次页面会多请求一次接口，页数越多越容易触发风控
+func SetAPIScrollPages(pages int) {
+	apiScrollPages.Store(int32(max(pages, 0)))
+}
+
+// scrollBottomScript 滚动到底部触发加载更多，详情页的评论在 .note-scroller 中滚动
+const scrollBottomScript = `() => {
+	const scroller = document.querySelector(".note-scroller");
+	if (scroller) {
+		scroller.scrollTop = scroller.scrollHeight;
+	} else {
+		window.scrollTo(0, document.body.scrollHeight);
+	}
+	return true;
+}`
+
+// apiResponse 接口返回的外层结构
+type apiResponse struct {
+	Code    int             `json:"code"`
+	Success bool            `json:"success"`
+	Msg     string          `json:"msg"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type apiUser struct {
+	UserID    string `json:"user_id"`
+	Nickname  string `json:"nickname"`
+	NickName  string `json:"nick_name"`
+	Avatar    string `json:"avatar"`
+	Image     string `json:"image"`
+	XsecToken string `json:"xsec_token"`
+}
+
+func (u apiUser) toUser() User {
+	avatar := u.Avatar
+	if avatar == "" {
+		avatar = u.Image
+	}
+	return User{
+		UserID:    u.UserID,
+		Nickname:  u.Nickname,
+		NickName:  u.NickName,
+		Avatar:    avatar,
+		XsecToken: u.XsecToken,
+	}
+}
+
+type apiInteractInfo struct {
+	Liked          bool   `json:"liked"`
+	LikedCount     string `json:"liked_count"`
+	SharedCount    string `json:"shared_count"`
+	CommentCount   string `json:"comment_count"`
+	CollectedCount string `json:"collected_count"`
+	Collected      bool   `json:"collected"`
+}
+
+func (i apiInteractInfo) toInteractInfo() InteractInfo {
+	return InteractInfo(i)
+}
+
+type apiImageInfo struct {
+	ImageScene string `json:"image_scene"`
+	URL        string `json:"url"`
+}
+
+type apiCover struct {
+	Width      int            `json:"width"`
+	Height     int            `json:"height"`
+	URL        string         `json:"url"`
+	FileID     string         `json:"file_id"`
+	URLPre     string         `json:"url_pre"`
+	URLDefault string         `json:"url_default"`
+	InfoList   []apiImageInfo `json:"info_list"`
+}
+
+func (c apiCover) toCover() Cover {
+	cover := Cover{
+		Width:      c.Width,
+		Height:     c.Height,
+		URL:        c.URL,
+		FileID:     c.FileID,
+		URLPre:     c.URLPre,
+		URLDefault: c.URLDefault,
+	}
+	for _, info := range c.InfoList {
+		cover.InfoList = append(cover.InfoList, ImageInfo(info))
+	}
+	return cover
+}
+
+type apiNoteCard struct {
+	Type         string          `json:"type"`
+	DisplayTitle string          `json:"display_title"`
+	User         apiUser         `json:"user"`
+	InteractInfo apiInteractInfo `json:"interact_info"`
+	Cover        apiCover        `json:"cover"`
+	Video        *struct {
+		Capa VideoCapability `json:"capa"`
+	} `json:"video"`
+}
+
+func (n apiNoteCard) toNoteCard() NoteCard {
+	card := NoteCard{
+		Type:         n.Type,
+		DisplayTitle: n.DisplayTitle,
+		User:         n.User.toUser(),
+		InteractInfo: n.InteractInfo.toInteractInfo(),
+		Cover:        n.Cover.toCover(),
+	}
+	if n.Video != nil {
+		card.Video = &Video{Capa: n.Video.Capa}
+	}
+	return card
+}
+
+// apiSearchNotesData 搜索接口的数据
+type apiSearchNotesData struct {
+	Items []struct {
+		ID        string      `json:"id"`
+		ModelType string      `json:"model_type"`
+		XsecToken string      `json:"xsec_token"`
+		NoteCard  apiNoteCard `json:"note_card"`
+	} `json:"items"`
+	HasMore bool `json:"has_more"`
+}
+
+// apiUserPostedData 用户笔记接口的数据，笔记卡片的字段直接在笔记上
+type apiUserPostedData struct {
+	Notes []struct {
+		apiNoteCard
+		NoteID    string `json:"note_id"`
+		XsecToken string `json:"xsec_token"`
+	} `json:"notes"`
+	Cursor  string `json:"cursor"`
+	HasMore bool   `json:"has_more"`
+}
+
+type apiComment struct {
+	ID              string       `json:"id"`
+	NoteID          string       `json:"note_id"`
+	Content         string       `json:"content"`
+	LikeCount       string       `json:"like_count"`
+	CreateTime      int64        `json:"create_time"`
+	IPLocation      string       `json:"ip_location"`
+	Liked           bool         `json:"liked"`
+	UserInfo        apiUser      `json:"user_info"`
+	SubCommentCount string       `json:"sub_comment_count"`
+	SubComments     []apiComment `json:"sub_comments"`
+	ShowTags        []string     `json:"show_tags"`
+}
+
+func (c apiComment) toComment() Comment {
+	comment := Comment{
+		ID:              c.ID,
+		NoteID:          c.NoteID,
+		Content:         c.Content,
+		LikeCount:       c.LikeCount,
+		CreateTime:      c.CreateTime,
+		IPLocation:      c.IPLocation,
+		Liked:           c.Liked,
+		UserInfo:        c.UserInfo.toUser(),
+		SubCommentCount: c.SubCommentCount,
+		ShowTags:        c.ShowTags,
+	}
+	for _, sub := range c.SubComments {
+		comment.SubComments = append(comment.SubComments, sub.toComment())
+	}
+	return comment
+}
+
+// apiCommentsData 评论接口的数据
+type apiCommentsData struct {
+	Comments []apiComment `json:"comments"`
+	Cursor   string       `json:"cursor"`
+	HasMore  bool         `json:"has_more"`
+}
+
+// decodeAPIResponse 解析接口响应，返回 data 部分
+func decodeAPIResponse(resp CapturedResponse, data any) error {
+	var envelope apiResponse
+	if err := json.Unmarshal(resp.Body, &envelope); err != nil {
+		return errors.Wrap(err, "decode api response")
+	}
+	if !envelope.Success {
+		return errors.Errorf("api error %d: %s", envelope.Code, envelope.Msg)
+	}
+	return errors.Wrap(json.Unmarshal(envelope.Data, data), "decode api data")
+}
+
+// apiCapture 操作期间捕获的接口响应。捕获是尽力而为的，失败时操作只使用 __INITIAL_STATE__ 中的数据
+type apiCapture struct {
+	capture ResponseCapture
+}
+
+// startAPICapture 在打开页面之前开始捕获接口响应
+func startAPICapture(ctx context.Context, d Driver) *apiCapture {
+	capture, err := d.CaptureResponses(ctx, apiPattern)
+	if err != nil {
+		slog.WarnContext(ctx, "捕获接口响应失败，只使用页面初始数据", "error", err)
+		return &apiCapture{}
+	}
+	return &apiCapture{capture: capture}
+}
+
+func (c *apiCapture) stop() {
+	if c.capture != nil {
+		c.capture.Stop()
+	}
+}
+
+// responses 捕获的指定接口的响应，按请求完成的顺序
+func (c *apiCapture) responses(endpoint string) []CapturedResponse {
+	if c.capture == nil {
+		return nil
+	}
+	var matched []CapturedResponse
+	for _, resp := range c.capture.Responses() {
+		if u, err := url.Parse(resp.URL); err == nil && strings.HasSuffix(u.Path, endpoint) {
+			matched = append(matched, resp)
+		}
+	}
+	return matched
+}
+
+// wait 等待指定接口的响应超过 count 个，超时返回 false
+func (c *apiCapture) wait(ctx context.Context, d Driver, endpoint string, count int) bool {
+	if c.capture == nil {
+		return false
+	}
+	for elapsed := time.Duration(0); elapsed < apiWaitTimeout; elapsed += apiPollInterval {
+		if len(c.responses(endpoint)) > count {
+			return true
+		}
+		if err := d.Sleep(ctx, apiPollInterval); err != nil {
+			return false
+		}
+	}
+	return len(c.responses(endpoint)) > count
+}
+
+// hasMore 指定接口最后一页是否还有更多数据，还没有捕获到响应时按有更多处理
+func (c *apiCapture) hasMore(endpoint string) bool {
+	responses := c.responses(endpoint)
+	if len(responses) == 0 {
+		return true
+	}
+	var data struct {
+		HasMore bool `json:"has_more"`
+	}
+	if err := decodeAPIResponse(responses[len(responses)-1], &data); err != nil {
+		return false
+	}
+	return data.HasMore
+}
+
+// loadMore 滚动到底部加载更多，直到达到 SetAPIScrollPages 设置的页数、没有更多数据或页面不再请求
+func (c *apiCapture) loadMore(ctx context.Context, d Driver, endpoint string) {
+	if c.capture == nil {
+		return
+	}
+	for page := 0; page < int(apiScrollPages.Load()) && c.hasMore(endpoint); page++ {
+		count := len(c.responses(endpoint))
+		if _, err := d.Eval(ctx, scrollBottomScript); err != nil {
+			slog.WarnContext(ctx, "滚动加载更多失败", "error", err)
+			return
+		}
+		if !c.wait(ctx, d, endpoint, count) {
+			slog.DebugContext(ctx, "滚动后没有加载更多", "endpoint", endpoint, "page", page+1)
+			return
+		}
+	}
+}
+
+// searchFeeds 捕获的搜索结果
+func (c *apiCapture) searchFeeds(ctx context.Context) []Feed {
+	var feeds []Feed
+	for _, resp := range c.responses(apiSearchNotes) {
+		var data apiSearchNotesData
+		if err := decodeAPIResponse(resp, &data); err != nil {
+			slog.WarnContext(ctx, "解析搜索接口响应失败", "url", resp.URL, "error", err)
+			continue
+		}
+		for _, item := range data.Items {
+			feeds = append(feeds, Feed{
+				XsecToken: item.XsecToken,
+				ID:        item.ID,
+				ModelType: item.ModelType,
+				NoteCard:  item.NoteCard.toNoteCard(),
+			})
+		}
+	}
+	return feeds
+}
+
+// userFeeds 捕获的用户笔记
+func (c *apiCapture) userFeeds(ctx context.Context) []Feed {
+	var feeds []Feed
+	for _, resp := range c.responses(apiUserPosted) {
+		var data apiUserPostedData
+		if err := decodeAPIResponse(resp, &data); err != nil {
+			slog.WarnContext(ctx, "解析用户笔记接口响应失败", "url", resp.URL, "error", err)
+			continue
+		}
+		for _, note := range data.Notes {
+			feeds = append(feeds, Feed{
+				XsecToken: note.XsecToken,
+				ID:        note.NoteID,
+				ModelType: "note",
+				NoteCard:  note.toNoteCard(),
+			})
+		}
+	}
+	return feeds
+}
+
+// comments 捕获的评论，ok 为 false 表示没有捕获到评论接口的响应
+func (c *apiCapture) comments(ctx context.Context) (comments CommentList, ok bool) {
+	for _, resp := range c.responses(apiComments) {
+		var data apiCommentsData
+		if err := decodeAPIResponse(resp, &data); err != nil {
+			slog.WarnContext(ctx, "解析评论接口响应失败", "url", resp.URL, "error", err)
+			continue
+		}
+		for _, comment := range data.Comments {
+			comments.List = append(comments.List, comment.toComment())
+		}
+		comments.Cursor, comments.HasMore = data.Cursor, data.HasMore
+		ok = true
+	}
+	return comments, ok
+}
+
+// mergeFeeds 在 feeds 后追加 more 中没有出现过的笔记，保持原有顺序
+func mergeFeeds(feeds, more []Feed) []Feed {
+	seen := make(map[string]bool, len(feeds))
+	for _, feed := range feeds {
+		seen[feed.ID] = true
+	}
+	for _, feed := range more {
+		if !seen[feed.ID] {
+			seen[feed.ID] = true
+			feed.Index = len(feeds)
+			feeds = append(feeds, feed)
+		}
+	}
+	return feeds
+}
+
+// mergeComments 在页面初始数据的评论后追加接口返回的评论，翻页位置以接口为准
+func mergeComments(comments, more CommentList) CommentList {
+	seen := make(map[string]bool, len(comments.List))
+	for _, comment := range comments.List {
+		seen[comment.ID] = true
+	}
+	for _, comment := range more.List {
+		if !seen[comment.ID] {
+			seen[comment.ID] = true
+			comments.List = append(comments.List, comment)
+		}
+	}
+	comments.Cursor, comments.HasMore = more.Cursor, more.HasMore
+	return comments
+}
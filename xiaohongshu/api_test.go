@@ -0,0 +1,137 @@
+package xiaohongshu
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// apiResp 构造捕获的接口响应
+func apiResp(endpoint, data string) CapturedResponse {
+	return CapturedResponse{
+		URL:    "https://edith.xiaohongshu.com" + endpoint,
+		Status: 200,
+		Body:   []byte(`{"code":0,"success":true,"msg":"成功","data":` + data + `}`),
+	}
+}
+
+func TestSearchMergesAPIResponses(t *testing.T) {
+	var state any
+	require.NoError(t, json.Unmarshal([]byte(`{"search":{"feeds":{"_value":[
+		{"id":"n1","xsecToken":"t1","modelType":"note","index":0,"noteCard":{"type":"normal","displayTitle":"首屏",
+			"user":{"userId":"u1","nickname":"作者"},"interactInfo":{"likedCount":"10"},"cover":{"urlDefault":"https://img/1"}}}
+	]}}}`), &state))
+
+	page1 := apiResp(apiSearchNotes, `{"has_more":true,"items":[
+		{"id":"n1","model_type":"note","xsec_token":"t1","note_card":{"display_title":"首屏"}},
+		{"id":"n2","model_type":"note","xsec_token":"t2","note_card":{"type":"video","display_title":"第二条",
+			"user":{"user_id":"u2","nick_name":"作者2","avatar":"https://avatar/2"},
+			"interact_info":{"liked_count":"20","comment_count":"3"},
+			"cover":{"width":100,"height":200,"url_default":"https://img/2","info_list":[{"image_scene":"WB_DFT","url":"https://img/2/dft"}]},
+			"video":{"capa":{"duration":15}}}}]}`)
+	page2 := apiResp(apiSearchNotes, `{"has_more":false,"items":[
+		{"id":"n3","model_type":"note","xsec_token":"t3","note_card":{"display_title":"第三条"}}]}`)
+
+	newDriver := func() *FakeDriver {
+		return NewFakeDriver(map[string]*FakePage{
+			webURL("/search_result"): {
+				State:           state,
+				Responses:       []CapturedResponse{page1},
+				ScrollResponses: []CapturedResponse{page2, apiResp(apiSearchNotes, `{"has_more":false,"items":[{"id":"n4"}]}`)},
+			},
+		})
+	}
+
+	// 默认不滚动，只合并首屏加载的接口响应
+	feeds, err := NewSearchAction(newDriver()).Search(context.Background(), "Kimi")
+	require.NoError(t, err)
+	require.Len(t, feeds, 2)
+	assert.Equal(t, "首屏", feeds[0].NoteCard.DisplayTitle)
+	assert.Equal(t, Feed{
+		XsecToken: "t2",
+		ID:        "n2",
+		ModelType: "note",
+		Index:     1,
+		NoteCard: NoteCard{
+			Type:         "video",
+			DisplayTitle: "第二条",
+			User:         User{UserID: "u2", NickName: "作者2", Avatar: "https://avatar/2"},
+			InteractInfo: InteractInfo{LikedCount: "20", CommentCount: "3"},
+			Cover: Cover{Width: 100, Height: 200, URLDefault: "https://img/2",
+				InfoList: []ImageInfo{{ImageScene: "WB_DFT", URL: "https://img/2/dft"}}},
+			Video: &Video{Capa: VideoCapability{Duration: 15}},
+		},
+	}, feeds[1])
+
+	// 滚动到没有更多数据为止，不超过设置的页数
+	SetAPIScrollPages(5)
+	t.Cleanup(func() { SetAPIScrollPages(0) })
+
+	driver := newDriver()
+	feeds, err = NewSearchAction(driver).Search(context.Background(), "Kimi")
+	require.NoError(t, err)
+	require.Len(t, feeds, 3)
+	assert.Equal(t, "n3", feeds[2].ID)
+	assert.Equal(t, 1, driver.Page().scrolled)
+}
+
+func TestFeedDetailMergesAPIComments(t *testing.T) {
+	var state any
+	require.NoError(t, json.Unmarshal([]byte(`{"note":{"noteDetailMap":{"n1":{
+		"note":{"noteId":"n1","title":"标题","desc":"","type":"normal","user":{"userId":"u1"},"interactInfo":{},"imageList":[]},
+		"comments":{"list":[{"id":"c1","content":"首屏评论"}],"cursor":"c1","hasMore":true}}}}}`), &state))
+
+	driver := NewFakeDriver(map[string]*FakePage{
+		webURL("/explore/n1"): {
+			State: state,
+			Responses: []CapturedResponse{
+				{URL: "https://edith.xiaohongshu.com" + apiComments, Status: 461, Body: []byte(`{"code":300011,"success":false,"msg":"账号异常"}`)},
+				apiResp(apiComments+"?note_id=n1&cursor=c1", `{"cursor":"c2","has_more":false,"comments":[
+					{"id":"c1","content":"首屏评论"},
+					{"id":"c2","note_id":"n1","content":"接口评论","like_count":"5","create_time":1700000000000,"ip_location":"上海",
+						"user_info":{"user_id":"u2","nickname":"评论者","image":"https://avatar/2"},"sub_comment_count":"1",
+						"sub_comments":[{"id":"c3","content":"回复"}],"show_tags":["is_author"]}]}`),
+			},
+		},
+	})
+
+	detail, err := NewFeedDetailAction(driver).GetFeedDetail(context.Background(), "n1", "token")
+	require.NoError(t, err)
+	assert.Equal(t, "标题", detail.Note.Title)
+	assert.Equal(t, CommentList{
+		List: []Comment{
+			{ID: "c1", Content: "首屏评论"},
+			{
+				ID:              "c2",
+				NoteID:          "n1",
+				Content:         "接口评论",
+				LikeCount:       "5",
+				CreateTime:      1700000000000,
+				IPLocation:      "上海",
+				UserInfo:        User{UserID: "u2", Nickname: "评论者", Avatar: "https://avatar/2"},
+				SubCommentCount: "1",
+				SubComments:     []Comment{{ID: "c3", Content: "回复", UserInfo: User{}}},
+				ShowTags:        []string{"is_author"},
+			},
+		},
+		Cursor:  "c2",
+		HasMore: false,
+	}, detail.Comments)
+}
+
+func TestFeedDetailSkipsCommentWait(t *testing.T) {
+	var state any
+	require.NoError(t, json.Unmarshal([]byte(`{"note":{"noteDetailMap":{"n1":{
+		"note":{"noteId":"n1","title":"标题","desc":"","type":"normal","user":{"userId":"u1"},"interactInfo":{},"imageList":[]},
+		"comments":{"list":[{"id":"c1","content":"首屏评论"}],"cursor":"c1","hasMore":true}}}}}`), &state))
+
+	// 首屏已有评论且不滚动时，评论接口没有响应也不等待
+	driver := NewFakeDriver(map[string]*FakePage{webURL("/explore/n1"): {State: state}})
+	detail, err := NewFeedDetailAction(driver).GetFeedDetail(context.Background(), "n1", "token")
+	require.NoError(t, err)
+	assert.Equal(t, []Comment{{ID: "c1", Content: "首屏评论"}}, detail.Comments.List)
+	assert.Less(t, driver.Elapsed(), apiWaitTimeout)
+}
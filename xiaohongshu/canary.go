@@ -0,0 +1,301 @@
+package xiaohongshu
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// 巡检页面，与 selectors.yaml 中的 canary 字段对应
+const (
+	CanaryPageExplore         = "explore"
+	CanaryPageSearch          = "search"
+	CanaryPageNoteDetail      = "note_detail"
+	CanaryPagePublish         = "publish"
+	CanaryPagePublishImage    = "publish_image"
+	CanaryPagePublishLongText = "publish_longtext"
+)
+
+// 检查项类型
+const (
+	CanaryKindPage     = "page"
+	CanaryKindSelector = "selector"
+	CanaryKindState    = "state"
+)
+
+const (
+	// canaryKeyword 巡检搜索页使用的关键词
+	canaryKeyword = "穿搭"
+	// canarySelectorTimeout 巡检时等待每个元素出现的最长时间
+	canarySelectorTimeout = 5 * time.Second
+)
+
+var canaryPages = []string{
+	CanaryPageExplore,
+	CanaryPageSearch,
+	CanaryPageNoteDetail,
+	CanaryPagePublish,
+	CanaryPagePublishImage,
+	CanaryPagePublishLongText,
+}
+
+// canaryStatePaths 各页面 __INITIAL_STATE__ 中解析数据依赖的路径，{id} 为巡检的笔记 ID
+var canaryStatePaths = map[string][]string{
+	CanaryPageExplore:    {"feed.feeds._value"},
+	CanaryPageSearch:     {"search.feeds._value"},
+	CanaryPageNoteDetail: {"note.noteDetailMap.{id}.note", "note.noteDetailMap.{id}.comments"},
+}
+
+// statePathScript 判断 __INITIAL_STATE__ 中的路径是否存在
+const statePathScript = `(path) => {
+	let value = window.__INITIAL_STATE__;
+	for (const key of path.split(".")) {
+		if (value === undefined || value === null) return false;
+		value = value[key];
+	}
+	return value !== undefined && value !== null;
+}`
+
+// firstFeedScript 首页第一条笔记的 ID 和 xsec_token，用于巡检详情页
+const firstFeedScript = `() => {
+	const state = window.__INITIAL_STATE__;
+	const feeds = state && state.feed && state.feed.feeds && state.feed.feeds._value;
+	const feed = (feeds || []).find((f) => f.id && f.xsecToken);
+	return feed ? feed.id + " " + feed.xsecToken : "";
+}`
+
+func isCanaryPage(page string) bool {
+	for _, p := range canaryPages {
+		if p == page {
+			return true
+		}
+	}
+	return false
+}
+
+// CanaryCheck 一项巡检结果
+type CanaryCheck struct {
+	Page   string `json:"page"`
+	Kind   string `json:"kind" description:"page 页面加载、selector 页面元素、state __INITIAL_STATE__ 路径"`
+	Target string `json:"target" description:"页面地址、选择器名称或数据路径"`
+	OK     bool   `json:"ok"`
+	// Fallback 命中的选择器规则序号，大于 0 表示主规则已失效
+	Fallback int    `json:"fallback,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// CanaryReport 一次巡检的结果
+type CanaryReport struct {
+	StartedAt       time.Time     `json:"started_at"`
+	FinishedAt      time.Time     `json:"finished_at"`
+	SelectorVersion string        `json:"selector_version"`
+	Checks          []CanaryCheck `json:"checks"`
+	// Unchecked 需要上传图片等操作后才出现、巡检无法检查的选择器
+	Unchecked []string `json:"unchecked,omitempty"`
+}
+
+// Healthy 所有检查项是否都通过
+func (r *CanaryReport) Healthy() bool {
+	for _, check := range r.Checks {
+		if !check.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// Failed 未通过的检查项
+func (r *CanaryReport) Failed() []CanaryCheck {
+	var failed []CanaryCheck
+	for _, check := range r.Checks {
+		if !check.OK {
+			failed = append(failed, check)
+		}
+	}
+	return failed
+}
+
+func (r *CanaryReport) add(check CanaryCheck) {
+	r.Checks = append(r.Checks, check)
+}
+
+// CanaryAction 巡检：依次打开首页、搜索页、笔记详情页和创作者发布页的各个选项卡，
+// 检查选择器和 __INITIAL_STATE__ 数据路径是否仍然有效，不会提交任何内容
+type CanaryAction struct {
+	driver Driver
+}
+
+// NewCanaryAction 创建巡检
+func NewCanaryAction(driver Driver) *CanaryAction {
+	return &CanaryAction{driver: driver}
+}
+
+// Run 执行一次巡检。检查项失败记录在结果中，只有遇到风控页面时返回错误
+func (a *CanaryAction) Run(ctx context.Context) (*CanaryReport, error) {
+	report := &CanaryReport{
+		StartedAt:       time.Now(),
+		SelectorVersion: selectors.Report().Version,
+	}
+	defer func() { report.FinishedAt = time.Now() }()
+
+	for _, status := range selectors.Report().Selectors {
+		if selectors.spec(status.Key).Canary == "" {
+			report.Unchecked = append(report.Unchecked, status.Key)
+		}
+	}
+
+	feed, err := a.checkStatePage(ctx, report, CanaryPageExplore, webURL("/explore"), nil)
+	if err != nil {
+		return report, err
+	}
+
+	if _, err := a.checkStatePage(ctx, report, CanaryPageSearch, makeSearchURL(canaryKeyword), nil); err != nil {
+		return report, err
+	}
+
+	if id, token, ok := strings.Cut(feed, " "); ok {
+		detailURL := webURL(fmt.Sprintf("/explore/%s?xsec_token=%s&xsec_source=pc_feed", id, token))
+		if _, err := a.checkStatePage(ctx, report, CanaryPageNoteDetail, detailURL, strings.NewReplacer("{id}", id)); err != nil {
+			return report, err
+		}
+	} else {
+		report.add(CanaryCheck{Page: CanaryPageNoteDetail, Kind: CanaryKindPage, Detail: "首页没有可用于巡检的笔记"})
+	}
+
+	if err := a.checkPublishPages(ctx, report); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// checkStatePage 打开页面，检查 __INITIAL_STATE__ 路径和该页面的选择器，返回首页第一条笔记
+func (a *CanaryAction) checkStatePage(ctx context.Context, report *CanaryReport, name, url string, paths *strings.Replacer) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	loadErr := a.driver.Navigate(ctx, url)
+	if loadErr == nil {
+		loadErr = a.driver.WaitStable(ctx)
+	}
+	if loadErr == nil {
+		loadErr = waitForInitialState(ctx, a.driver)
+	}
+	if isRiskControl(loadErr) {
+		return "", loadErr
+	}
+	report.add(pageCheck(name, url, loadErr))
+	if loadErr != nil {
+		return "", nil
+	}
+
+	for _, path := range canaryStatePaths[name] {
+		if paths != nil {
+			path = paths.Replace(path)
+		}
+		check := CanaryCheck{Page: name, Kind: CanaryKindState, Target: path}
+		if result, err := a.driver.Eval(ctx, statePathScript, path); err != nil {
+			check.Detail = err.Error()
+		} else if check.OK = result.Bool(); !check.OK {
+			check.Detail = "__INITIAL_STATE__ 中没有该路径"
+		}
+		report.add(check)
+	}
+
+	a.checkSelectors(ctx, report, name)
+
+	if name != CanaryPageExplore {
+		return "", nil
+	}
+	result, err := a.driver.Eval(ctx, firstFeedScript)
+	if err != nil {
+		return "", nil
+	}
+	return result.Str(), nil
+}
+
+// checkPublishPages 打开创作者发布页，分别切换到各个选项卡检查选择器，不会上传或提交
+func (a *CanaryAction) checkPublishPages(ctx context.Context, report *CanaryReport) error {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	loadErr := a.driver.Navigate(ctx, publishURL())
+	if loadErr == nil {
+		_, loadErr = waitForSelector(ctx, a.driver, "publish.upload_area")
+	}
+	if isRiskControl(loadErr) {
+		return loadErr
+	}
+	report.add(pageCheck(CanaryPagePublish, publishURL(), loadErr))
+	if loadErr != nil {
+		return nil
+	}
+
+	a.checkSelectors(ctx, report, CanaryPagePublish)
+
+	tabs := []struct{ page, selector string }{
+		{CanaryPagePublishImage, "publish.image_tab"},
+		{CanaryPagePublishLongText, "publish.longtext_tab"},
+	}
+	for _, tab := range tabs {
+		el, err := findSelector(ctx, a.driver, tab.selector, canarySelectorTimeout)
+		if err == nil {
+			err = el.Click(ctx)
+		}
+		if err == nil {
+			err = a.driver.Sleep(ctx, 2*time.Second)
+		}
+		report.add(pageCheck(tab.page, tab.selector, err))
+		if err == nil {
+			a.checkSelectors(ctx, report, tab.page)
+		}
+	}
+	return nil
+}
+
+// checkSelectors 检查页面上所有 canary 为该页面的选择器
+func (a *CanaryAction) checkSelectors(ctx context.Context, report *CanaryReport, name string) {
+	for _, key := range selectors.canaryKeys(name) {
+		check := CanaryCheck{Page: name, Kind: CanaryKindSelector, Target: key}
+		if _, index, err := selectors.find(ctx, a.driver, key, canarySelectorTimeout, nil); err != nil {
+			check.Detail = err.Error()
+		} else {
+			check.OK = true
+			check.Fallback = index
+			check.Detail = "命中 " + selectors.spec(key).Candidates[index].String()
+		}
+		report.add(check)
+	}
+}
+
+func isRiskControl(err error) bool {
+	var riskErr *RiskControlError
+	return errors.As(err, &riskErr)
+}
+
+func pageCheck(name, target string, err error) CanaryCheck {
+	check := CanaryCheck{Page: name, Kind: CanaryKindPage, Target: target, OK: err == nil}
+	if err != nil {
+		check.Detail = err.Error()
+	}
+	return check
+}
+
+// canaryKeys 巡检时在 page 页面检查的选择器，按名称排序
+func (r *SelectorRegistry) canaryKeys(page string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var keys []string
+	for key, spec := range r.selectors {
+		if spec.Canary == page {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
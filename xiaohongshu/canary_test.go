@@ -0,0 +1,61 @@
+package xiaohongshu
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanaryWithFakeDriver(t *testing.T) {
+	explore := &FakePage{
+		State: map[string]any{"feed": map[string]any{"feeds": map[string]any{"_value": []any{}}}},
+		Scripts: map[string]any{
+			firstFeedScript: "64f1 token",
+		},
+		Elements: []*FakeElement{{Selectors: []string{".main-container .user .link-wrapper .channel"}}},
+	}
+	// 搜索页改版，数据路径变化
+	search := &FakePage{State: map[string]any{"search": map[string]any{"notes": []any{}}}}
+	detail := &FakePage{State: map[string]any{"note": map[string]any{"noteDetailMap": map[string]any{
+		"64f1": map[string]any{"note": map[string]any{"title": "秋天穿搭"}, "comments": map[string]any{}},
+	}}}}
+	publish := &FakePage{Elements: []*FakeElement{
+		{Selectors: []string{"div.upload-content"}},
+		{Selectors: []string{"div.creator-tab"}, InnerText: "上传图文"},
+		{Selectors: []string{"div.creator-tab"}, InnerText: "写长文"},
+		// 主规则失效，通过备选规则命中
+		{Selectors: []string{"input[type='file']"}},
+		{Selectors: []string{"button"}, InnerText: "新的创作"},
+	}}
+
+	driver := NewFakeDriver(map[string]*FakePage{
+		"https://www.xiaohongshu.com/explore":       explore,
+		"https://www.xiaohongshu.com/search_result": search,
+		"https://www.xiaohongshu.com/explore/64f1":  detail,
+		publishURL(): publish,
+	})
+
+	report, err := NewCanaryAction(driver).Run(context.Background())
+	require.NoError(t, err)
+	assert.False(t, report.Healthy())
+
+	failed := report.Failed()
+	require.Len(t, failed, 1)
+	assert.Equal(t, CanaryCheck{Page: CanaryPageSearch, Kind: CanaryKindState, Target: "search.feeds._value",
+		Detail: "__INITIAL_STATE__ 中没有该路径"}, failed[0])
+
+	var fallback CanaryCheck
+	for _, check := range report.Checks {
+		if check.Target == "publish.upload_input" {
+			fallback = check
+		}
+	}
+	assert.True(t, fallback.OK)
+	assert.Equal(t, 1, fallback.Fallback)
+
+	assert.Contains(t, report.Unchecked, "publish.submit_button")
+	// 巡检只切换选项卡，不会上传或提交
+	assert.Empty(t, publish.Elements[3].Files)
+}
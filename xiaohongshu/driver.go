@@ -0,0 +1,457 @@
+package xiaohongshu
+
+import (
+	"context"
+	"encoding/base64"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/pkg/errors"
+	"github.com/ysmood/gson"
+)
+
+// ErrElementNotFound 页面上没有匹配的元素
+var ErrElementNotFound = errors.New("element not found")
+
+// Finder 查找元素。只查找一次，不等待，没有匹配的元素时返回 ErrElementNotFound
+type Finder interface {
+	// Element 第一个匹配 css 的元素
+	Element(ctx context.Context, css string) (Element, error)
+	// ElementR 第一个匹配 css 且文本匹配正则 pattern 的元素
+	ElementR(ctx context.Context, css, pattern string) (Element, error)
+	// Elements 所有匹配 css 的元素，没有时返回空列表
+	Elements(ctx context.Context, css string) ([]Element, error)
+}
+
+// Driver 浏览器页面驱动，action 只通过它操作页面。
+// 默认实现基于 rod，测试时可以使用 FakeDriver 编排页面
+type Driver interface {
+	Finder
+
+	// Navigate 打开页面并等待加载完成
+	Navigate(ctx context.Context, url string) error
+	// URL 当前页面地址
+	URL(ctx context.Context) (string, error)
+	// WaitStable 等待页面加载完成且 DOM 不再变化
+	WaitStable(ctx context.Context) error
+	// WaitIdle 等待页面没有进行中的请求
+	WaitIdle(ctx context.Context) error
+	// Eval 执行 JS 函数，返回函数的返回值
+	Eval(ctx context.Context, js string, args ...any) (gson.JSON, error)
+	// ElementByRole 第一个 ARIA 角色为 role 的元素，name 不为空时可访问名称还需匹配该正则
+	ElementByRole(ctx context.Context, role, name string) (Element, error)
+	// Sleep 等待指定时长，ctx 取消时立即返回错误
+	Sleep(ctx context.Context, d time.Duration) error
+	// CaptureResponses 开始捕获地址匹配正则 pattern 的 JSON 响应，直到调用 Stop 或 ctx 结束
+	CaptureResponses(ctx context.Context, pattern string) (ResponseCapture, error)
+	// BlockResources 开始按 policy 拦截页面请求，直到调用 Stop 或 ctx 结束
+	BlockResources(ctx context.Context, policy ResourcePolicy) (ResourceBlock, error)
+}
+
+// CapturedResponse 捕获的接口响应
+type CapturedResponse struct {
+	URL    string
+	Status int
+	Body   []byte
+}
+
+// ResponseCapture 进行中的响应捕获
+type ResponseCapture interface {
+	// Responses 到目前为止捕获的响应，按完成顺序
+	Responses() []CapturedResponse
+	Stop()
+}
+
+// Element 页面元素
+type Element interface {
+	Finder
+
+	Click(ctx context.Context) error
+	// Input 聚焦元素并输入文本
+	Input(ctx context.Context, text string) error
+	SelectAllText(ctx context.Context) error
+	// SetFiles 设置文件输入框的文件
+	SetFiles(ctx context.Context, paths []string) error
+	// Text 元素的文本，输入框为输入的值
+	Text(ctx context.Context) (string, error)
+	// Attribute 元素的属性，不存在时返回 nil
+	Attribute(ctx context.Context, name string) (*string, error)
+	WaitVisible(ctx context.Context) error
+	ScrollIntoView(ctx context.Context) error
+	Parent(ctx context.Context) (Element, error)
+}
+
+// rodDriver 基于 rod 的页面驱动
+type rodDriver struct {
+	page *rod.Page
+}
+
+// NewRodDriver 使用 rod 页面创建驱动
+func NewRodDriver(page *rod.Page) Driver {
+	return &rodDriver{page: page}
+}
+
+func (d *rodDriver) p(ctx context.Context) *rod.Page {
+	return d.page.Context(ctx)
+}
+
+func (d *rodDriver) Navigate(ctx context.Context, url string) error {
+	page := d.p(ctx)
+	if err := page.Navigate(url); err != nil {
+		return errors.Wrapf(err, "open %s", url)
+	}
+	return page.WaitLoad()
+}
+
+func (d *rodDriver) URL(ctx context.Context) (string, error) {
+	info, err := d.p(ctx).Info()
+	if err != nil {
+		return "", err
+	}
+	return info.URL, nil
+}
+
+func (d *rodDriver) WaitStable(ctx context.Context) error {
+	return d.p(ctx).WaitStable(time.Second)
+}
+
+func (d *rodDriver) WaitIdle(ctx context.Context) error {
+	return d.p(ctx).WaitIdle(time.Minute)
+}
+
+func (d *rodDriver) Eval(ctx context.Context, js string, args ...any) (gson.JSON, error) {
+	result, err := d.p(ctx).Eval(js, args...)
+	if err != nil {
+		return gson.JSON{}, err
+	}
+	return result.Value, nil
+}
+
+func (d *rodDriver) Element(ctx context.Context, css string) (Element, error) {
+	return wrapRodElement(d.p(ctx).Sleeper(rod.NotFoundSleeper).Element(css))
+}
+
+func (d *rodDriver) ElementR(ctx context.Context, css, pattern string) (Element, error) {
+	return wrapRodElement(d.p(ctx).Sleeper(rod.NotFoundSleeper).ElementR(css, pattern))
+}
+
+func (d *rodDriver) Elements(ctx context.Context, css string) ([]Element, error) {
+	return wrapRodElements(d.p(ctx).Elements(css))
+}
+
+func (d *rodDriver) ElementByRole(ctx context.Context, role, name string) (Element, error) {
+	return wrapRodElement(d.p(ctx).Sleeper(rod.NotFoundSleeper).ElementByJS(rod.Eval(roleScript, role, name)))
+}
+
+func (d *rodDriver) Sleep(ctx context.Context, duration time.Duration) error {
+	return sleep(ctx, duration)
+}
+
+// CaptureResponses 通过 CDP Network 事件捕获响应，请求完成后读取响应体
+func (d *rodDriver) CaptureResponses(ctx context.Context, pattern string) (ResponseCapture, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	page := d.p(ctx)
+	if err := (proto.NetworkEnable{}).Call(page); err != nil {
+		cancel()
+		return nil, errors.Wrap(err, "enable network events")
+	}
+
+	capture := &rodCapture{cancel: cancel}
+	// pending 只在事件回调中访问，事件按顺序处理
+	pending := map[proto.NetworkRequestID]*proto.NetworkResponse{}
+	wait := page.EachEvent(func(e *proto.NetworkResponseReceived) {
+		if re.MatchString(e.Response.URL) && strings.Contains(e.Response.MIMEType, "json") {
+			pending[e.RequestID] = e.Response
+		}
+	}, func(e *proto.NetworkLoadingFinished) {
+		resp, ok := pending[e.RequestID]
+		if !ok {
+			return
+		}
+		delete(pending, e.RequestID)
+
+		result, err := proto.NetworkGetResponseBody{RequestID: e.RequestID}.Call(page)
+		if err != nil {
+			return
+		}
+		body := []byte(result.Body)
+		if result.Base64Encoded {
+			if body, err = base64.StdEncoding.DecodeString(result.Body); err != nil {
+				return
+			}
+		}
+		capture.add(CapturedResponse{URL: resp.URL, Status: resp.Status, Body: body})
+	}, func(e *proto.NetworkLoadingFailed) {
+		delete(pending, e.RequestID)
+	})
+	go wait()
+
+	return capture, nil
+}
+
+// rodCapture 基于 CDP 事件的响应捕获
+type rodCapture struct {
+	mu        sync.Mutex
+	responses []CapturedResponse
+	cancel    context.CancelFunc
+}
+
+func (c *rodCapture) add(resp CapturedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.responses = append(c.responses, resp)
+}
+
+func (c *rodCapture) Responses() []CapturedResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]CapturedResponse(nil), c.responses...)
+}
+
+func (c *rodCapture) Stop() {
+	c.cancel()
+}
+
+// networkResourceTypes 资源类型对应的 CDP 请求类型，埋点按地址匹配
+var networkResourceTypes = map[ResourceKind]proto.NetworkResourceType{
+	ResourceImage: proto.NetworkResourceTypeImage,
+	ResourceMedia: proto.NetworkResourceTypeMedia,
+	ResourceFont:  proto.NetworkResourceTypeFont,
+}
+
+// BlockResources 通过 Fetch 拦截请求，被拦截的请求以 BlockedByClient 失败。
+// Measure 模式下不拦截，通过 CDP Network 事件统计请求实际传输的字节数
+func (d *rodDriver) BlockResources(ctx context.Context, policy ResourcePolicy) (ResourceBlock, error) {
+	var analytics []*regexp.Regexp
+	for _, pattern := range analyticsURLPatterns {
+		analytics = append(analytics, regexp.MustCompile(proto.PatternToReg(pattern)))
+	}
+	// resourceKind 请求的资源类型，不在 policy 中时返回 false
+	resourceKind := func(url string, typ proto.NetworkResourceType) (ResourceKind, bool) {
+		if policy.Blocks(ResourceAnalytics) && slices.ContainsFunc(analytics, func(re *regexp.Regexp) bool { return re.MatchString(url) }) {
+			return ResourceAnalytics, true
+		}
+		kind := ResourceKind(strings.ToLower(string(typ)))
+		return kind, kind != ResourceAnalytics && policy.Blocks(kind)
+	}
+
+	if policy.Measure {
+		return d.measureResources(ctx, resourceKind)
+	}
+
+	router := d.p(ctx).HijackRequests()
+	block := newRodResourceBlock(func() { router.Stop() })
+	// 路由只按地址匹配处理函数，所以所有规则共用一个处理函数，再按请求类型归类
+	handler := func(h *rod.Hijack) {
+		kind, _ := resourceKind(h.Request.URL().String(), h.Request.Type())
+		block.add(kind, 0)
+		h.Response.Fail(proto.NetworkErrorReasonBlockedByClient)
+	}
+
+	for _, kind := range policy.Block {
+		if kind == ResourceAnalytics {
+			for _, pattern := range analyticsURLPatterns {
+				if err := router.Add(pattern, "", handler); err != nil {
+					router.Stop()
+					return nil, errors.Wrap(err, "block analytics")
+				}
+			}
+			continue
+		}
+		if err := router.Add("*", networkResourceTypes[kind], handler); err != nil {
+			router.Stop()
+			return nil, errors.Wrapf(err, "block %s", kind)
+		}
+	}
+	go router.Run()
+
+	return block, nil
+}
+
+// measureResources 统计 resourceKind 归类的请求加载完成时实际传输的字节数
+func (d *rodDriver) measureResources(ctx context.Context, resourceKind func(url string, typ proto.NetworkResourceType) (ResourceKind, bool)) (ResourceBlock, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	page := d.p(ctx)
+	if err := (proto.NetworkEnable{}).Call(page); err != nil {
+		cancel()
+		return nil, errors.Wrap(err, "enable network events")
+	}
+
+	block := newRodResourceBlock(cancel)
+	// kinds 只在事件回调中访问，事件按顺序处理
+	kinds := map[proto.NetworkRequestID]ResourceKind{}
+	wait := page.EachEvent(func(e *proto.NetworkRequestWillBeSent) {
+		if kind, ok := resourceKind(e.Request.URL, e.Type); ok {
+			kinds[e.RequestID] = kind
+		}
+	}, func(e *proto.NetworkLoadingFinished) {
+		if kind, ok := kinds[e.RequestID]; ok {
+			delete(kinds, e.RequestID)
+			block.add(kind, int64(e.EncodedDataLength))
+		}
+	}, func(e *proto.NetworkLoadingFailed) {
+		delete(kinds, e.RequestID)
+	})
+	go wait()
+
+	return block, nil
+}
+
+// rodResourceBlock 基于请求拦截或 Network 事件的资源统计
+type rodResourceBlock struct {
+	stop    func()
+	mu      sync.Mutex
+	blocked map[ResourceKind]int
+	bytes   map[ResourceKind]int64
+}
+
+func newRodResourceBlock(stop func()) *rodResourceBlock {
+	return &rodResourceBlock{stop: stop, blocked: map[ResourceKind]int{}, bytes: map[ResourceKind]int64{}}
+}
+
+func (b *rodResourceBlock) add(kind ResourceKind, bytes int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.blocked[kind]++
+	if bytes > 0 {
+		b.bytes[kind] += bytes
+	}
+}
+
+func (b *rodResourceBlock) Blocked() map[ResourceKind]int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return maps.Clone(b.blocked)
+}
+
+func (b *rodResourceBlock) Bytes() map[ResourceKind]int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return maps.Clone(b.bytes)
+}
+
+func (b *rodResourceBlock) Stop() {
+	b.stop()
+}
+
+// rodElement 基于 rod 的页面元素
+type rodElement struct {
+	el *rod.Element
+}
+
+func wrapRodElement(el *rod.Element, err error) (Element, error) {
+	var notFound *rod.ElementNotFoundError
+	if errors.As(err, &notFound) {
+		return nil, ErrElementNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rodElement{el: el}, nil
+}
+
+func wrapRodElements(elems rod.Elements, err error) ([]Element, error) {
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Element, 0, len(elems))
+	for _, el := range elems {
+		result = append(result, &rodElement{el: el})
+	}
+	return result, nil
+}
+
+func (e *rodElement) e(ctx context.Context) *rod.Element {
+	return e.el.Context(ctx)
+}
+
+func (e *rodElement) Element(ctx context.Context, css string) (Element, error) {
+	return wrapRodElement(e.e(ctx).Sleeper(rod.NotFoundSleeper).Element(css))
+}
+
+func (e *rodElement) ElementR(ctx context.Context, css, pattern string) (Element, error) {
+	return wrapRodElement(e.e(ctx).Sleeper(rod.NotFoundSleeper).ElementR(css, pattern))
+}
+
+func (e *rodElement) Elements(ctx context.Context, css string) ([]Element, error) {
+	return wrapRodElements(e.e(ctx).Elements(css))
+}
+
+func (e *rodElement) Click(ctx context.Context) error {
+	return e.e(ctx).Click(proto.InputMouseButtonLeft, 1)
+}
+
+func (e *rodElement) Input(ctx context.Context, text string) error {
+	return e.e(ctx).Input(text)
+}
+
+// selectAllTextScript 全选元素中的文本，contenteditable 元素没有 select 方法，改用 Range 选中
+const selectAllTextScript = `function () {
+	if (typeof this.select === "function") {
+		this.select();
+		return;
+	}
+	const range = document.createRange();
+	range.selectNodeContents(this);
+	const selection = window.getSelection();
+	selection.removeAllRanges();
+	selection.addRange(range);
+}`
+
+func (e *rodElement) SelectAllText(ctx context.Context) error {
+	_, err := e.e(ctx).Eval(selectAllTextScript)
+	return err
+}
+
+func (e *rodElement) SetFiles(ctx context.Context, paths []string) error {
+	return e.e(ctx).SetFiles(paths)
+}
+
+func (e *rodElement) Text(ctx context.Context) (string, error) {
+	return e.e(ctx).Text()
+}
+
+func (e *rodElement) Attribute(ctx context.Context, name string) (*string, error) {
+	return e.e(ctx).Attribute(name)
+}
+
+func (e *rodElement) WaitVisible(ctx context.Context) error {
+	return e.e(ctx).WaitVisible()
+}
+
+func (e *rodElement) ScrollIntoView(ctx context.Context) error {
+	return e.e(ctx).ScrollIntoView()
+}
+
+func (e *rodElement) Parent(ctx context.Context) (Element, error) {
+	return wrapRodElement(e.e(ctx).Parent())
+}
+
+// waitElement 反复调用 find 直到找到元素，超过 timeout 后返回 ErrElementNotFound
+func waitElement(ctx context.Context, d Driver, timeout time.Duration, find func() (Element, error)) (Element, error) {
+	var waited time.Duration
+	for {
+		el, err := find()
+		if !errors.Is(err, ErrElementNotFound) {
+			return el, err
+		}
+		if waited >= timeout {
+			return nil, err
+		}
+		if err := d.Sleep(ctx, selectorPollInterval); err != nil {
+			return nil, err
+		}
+		waited += selectorPollInterval
+	}
+}
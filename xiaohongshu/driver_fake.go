@@ -0,0 +1,460 @@
+package xiaohongshu
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/ysmood/gson"
+)
+
+// defaultFakeMaxWait FakeDriver 默认允许的累计等待时长
+const defaultFakeMaxWait = 10 * time.Minute
+
+// FakeDriver 内存中的页面驱动，按预先编排的页面和元素响应操作，用于确定性地测试 action。
+// 等待不消耗真实时间，只累计在 Elapsed 中。不是并发安全的
+type FakeDriver struct {
+	pages   map[string]*FakePage
+	current *FakePage
+	url     string
+	visited []string
+	elapsed time.Duration
+	// captures 进行中的响应捕获，打开页面时收到该页面的响应
+	captures []*fakeCapture
+	// blocks 进行中的资源拦截，打开页面时按策略统计页面的资源
+	blocks []*fakeResourceBlock
+
+	// MaxWait 累计等待超过该时长后 Sleep 返回 context.DeadlineExceeded，
+	// 避免等待一直不出现的元素时测试卡住
+	MaxWait time.Duration
+}
+
+// FakePage 编排的页面
+type FakePage struct {
+	// State 页面的 __INITIAL_STATE__，为 nil 表示页面上没有该变量
+	State any
+	// RiskReason 不为空时表示风控页面，为检测到的原因
+	RiskReason string
+	// Scripts 其他脚本的返回值，key 为脚本内容，没有编排的脚本返回 undefined
+	Scripts map[string]any
+	// Elements 页面上的元素
+	Elements []*FakeElement
+	// Responses 打开页面时加载的接口响应
+	Responses []CapturedResponse
+	// ScrollResponses 每次滚动到底部依次加载一个接口响应
+	ScrollResponses []CapturedResponse
+	// Resources 打开页面时加载的资源，被拦截的计入 ResourceBlock.Blocked
+	Resources []ResourceKind
+	// ResourceSizes 每类资源单个请求传输的字节数，Measure 模式下计入 ResourceBlock.Bytes
+	ResourceSizes map[ResourceKind]int64
+
+	scrolled int
+}
+
+// FakeElement 编排的页面元素
+type FakeElement struct {
+	// Selectors 元素匹配的 CSS 选择器，与查找时使用的选择器完全相同才算匹配
+	Selectors []string
+	// Role ARIA 角色
+	Role string
+	// InnerText 元素显示的文本
+	InnerText string
+	// Attrs 元素属性
+	Attrs map[string]string
+	// Hidden 元素不可见，WaitVisible 返回错误
+	Hidden bool
+	// Children 子元素
+	Children []*FakeElement
+	// OnClick 点击时调用，用于模拟页面变化，如切换选项卡后出现新的元素
+	OnClick func(page *FakePage)
+
+	// Clicks 被点击的次数
+	Clicks int
+	// Value 输入的内容
+	Value string
+	// Files 设置的文件
+	Files []string
+
+	page     *FakePage
+	parent   *FakeElement
+	selected bool
+}
+
+// NewFakeDriver 创建 FakeDriver，pages 的 key 为页面地址
+func NewFakeDriver(pages map[string]*FakePage) *FakeDriver {
+	return &FakeDriver{pages: pages, MaxWait: defaultFakeMaxWait}
+}
+
+// Visited 依次打开过的页面地址
+func (d *FakeDriver) Visited() []string {
+	return d.visited
+}
+
+// Elapsed 累计等待的时长
+func (d *FakeDriver) Elapsed() time.Duration {
+	return d.elapsed
+}
+
+// Page 当前页面
+func (d *FakeDriver) Page() *FakePage {
+	return d.current
+}
+
+func (d *FakeDriver) Navigate(ctx context.Context, url string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	page, ok := d.pages[url]
+	if !ok {
+		// 没有编排完整地址时按去掉查询参数的地址查找
+		page, ok = d.pages[strings.SplitN(url, "?", 2)[0]]
+	}
+	if !ok {
+		return errors.Errorf("fake driver: no page for %s", url)
+	}
+
+	d.current, d.url = page, url
+	d.visited = append(d.visited, url)
+	for _, resp := range page.Responses {
+		d.Respond(resp)
+	}
+	for _, b := range d.blocks {
+		for _, kind := range page.Resources {
+			if !b.stopped && b.policy.Blocks(kind) {
+				b.blocked[kind]++
+				if b.policy.Measure {
+					b.bytes[kind] += page.ResourceSizes[kind]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (d *FakeDriver) BlockResources(ctx context.Context, policy ResourcePolicy) (ResourceBlock, error) {
+	b := &fakeResourceBlock{policy: policy, blocked: map[ResourceKind]int{}, bytes: map[ResourceKind]int64{}}
+	d.blocks = append(d.blocks, b)
+	return b, nil
+}
+
+// Blocks 开始过的资源拦截的策略，按开始的顺序
+func (d *FakeDriver) Blocks() []ResourcePolicy {
+	var policies []ResourcePolicy
+	for _, b := range d.blocks {
+		policies = append(policies, b.policy)
+	}
+	return policies
+}
+
+// Respond 模拟页面加载了一个接口响应，如点击或滚动后加载更多
+func (d *FakeDriver) Respond(resp CapturedResponse) {
+	for _, c := range d.captures {
+		if !c.stopped && c.re.MatchString(resp.URL) {
+			c.responses = append(c.responses, resp)
+		}
+	}
+}
+
+func (d *FakeDriver) CaptureResponses(ctx context.Context, pattern string) (ResponseCapture, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	c := &fakeCapture{re: re}
+	d.captures = append(d.captures, c)
+	return c, nil
+}
+
+func (d *FakeDriver) URL(ctx context.Context) (string, error) {
+	return d.url, nil
+}
+
+func (d *FakeDriver) WaitStable(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (d *FakeDriver) WaitIdle(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (d *FakeDriver) Eval(ctx context.Context, js string, args ...any) (gson.JSON, error) {
+	if err := ctx.Err(); err != nil {
+		return gson.JSON{}, err
+	}
+	page := d.page()
+
+	switch js {
+	case InitialStateScript:
+		if page.State == nil {
+			return gson.New(""), nil
+		}
+		data, err := json.Marshal(page.State)
+		if err != nil {
+			return gson.JSON{}, err
+		}
+		return gson.New(string(data)), nil
+	case initialStateReadyScript:
+		return gson.New(page.State != nil || page.RiskReason != ""), nil
+	case riskControlScript:
+		return gson.New(page.RiskReason), nil
+	case statePathScript:
+		return gson.New(page.hasStatePath(args[0].(string))), nil
+	case scrollBottomScript:
+		if page.scrolled < len(page.ScrollResponses) {
+			d.Respond(page.ScrollResponses[page.scrolled])
+			page.scrolled++
+		}
+		return gson.New(true), nil
+	}
+
+	if result, ok := page.Scripts[js]; ok {
+		return gson.New(result), nil
+	}
+	return gson.New(nil), nil
+}
+
+func (d *FakeDriver) Element(ctx context.Context, css string) (Element, error) {
+	return first(d.page().find(nil, css, ""))
+}
+
+func (d *FakeDriver) ElementR(ctx context.Context, css, pattern string) (Element, error) {
+	return first(d.page().find(nil, css, pattern))
+}
+
+func (d *FakeDriver) Elements(ctx context.Context, css string) ([]Element, error) {
+	return d.page().find(nil, css, ""), nil
+}
+
+func (d *FakeDriver) ElementByRole(ctx context.Context, role, name string) (Element, error) {
+	re := regexp.MustCompile(name)
+
+	var found Element
+	d.page().walk(nil, func(el *FakeElement) bool {
+		if el.Role != role {
+			return false
+		}
+		label := strings.Join([]string{el.Attrs["aria-label"], el.Attrs["placeholder"], el.Attrs["data-placeholder"], el.InnerText}, " ")
+		if name != "" && !re.MatchString(label) {
+			return false
+		}
+		found = el
+		return true
+	})
+	if found == nil {
+		return nil, ErrElementNotFound
+	}
+	return found, nil
+}
+
+func (d *FakeDriver) Sleep(ctx context.Context, duration time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d.elapsed += duration
+	if d.MaxWait > 0 && d.elapsed > d.MaxWait {
+		return errors.Wrapf(context.DeadlineExceeded, "fake driver: waited %s", d.elapsed)
+	}
+	return nil
+}
+
+// page 当前页面，还没有打开页面时为空白页
+func (d *FakeDriver) page() *FakePage {
+	if d.current == nil {
+		d.current = &FakePage{}
+	}
+	return d.current
+}
+
+// walk 深度优先遍历 root 下的元素（root 为空时遍历整个页面），fn 返回 true 时停止
+func (p *FakePage) walk(root *FakeElement, fn func(el *FakeElement) bool) bool {
+	children := p.Elements
+	if root != nil {
+		children = root.Children
+	}
+	for _, el := range children {
+		el.page, el.parent = p, root
+		if fn(el) || p.walk(el, fn) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *FakePage) find(root *FakeElement, css, pattern string) []Element {
+	var re *regexp.Regexp
+	if pattern != "" {
+		re = regexp.MustCompile(pattern)
+	}
+
+	var found []Element
+	p.walk(root, func(el *FakeElement) bool {
+		if !el.matches(css) {
+			return false
+		}
+		if re != nil && !re.MatchString(el.InnerText) && !re.MatchString(el.Attrs["placeholder"]) {
+			return false
+		}
+		found = append(found, el)
+		return false
+	})
+	return found
+}
+
+// hasStatePath __INITIAL_STATE__ 中是否存在 path（以 . 分隔）
+func (p *FakePage) hasStatePath(path string) bool {
+	if p.State == nil {
+		return false
+	}
+	data, err := json.Marshal(p.State)
+	if err != nil {
+		return false
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return false
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return false
+		}
+		if value, ok = m[key]; !ok {
+			return false
+		}
+	}
+	return value != nil
+}
+
+// fakeCapture FakeDriver 的响应捕获
+type fakeCapture struct {
+	re        *regexp.Regexp
+	responses []CapturedResponse
+	stopped   bool
+}
+
+func (c *fakeCapture) Responses() []CapturedResponse {
+	return append([]CapturedResponse(nil), c.responses...)
+}
+
+func (c *fakeCapture) Stop() {
+	c.stopped = true
+}
+
+// fakeResourceBlock FakeDriver 的资源拦截
+type fakeResourceBlock struct {
+	policy  ResourcePolicy
+	blocked map[ResourceKind]int
+	bytes   map[ResourceKind]int64
+	stopped bool
+}
+
+func (b *fakeResourceBlock) Blocked() map[ResourceKind]int {
+	return maps.Clone(b.blocked)
+}
+
+func (b *fakeResourceBlock) Bytes() map[ResourceKind]int64 {
+	return maps.Clone(b.bytes)
+}
+
+func (b *fakeResourceBlock) Stop() {
+	b.stopped = true
+}
+
+func first(elems []Element) (Element, error) {
+	if len(elems) == 0 {
+		return nil, ErrElementNotFound
+	}
+	return elems[0], nil
+}
+
+func (e *FakeElement) matches(css string) bool {
+	for _, s := range e.Selectors {
+		if s == css {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *FakeElement) Element(ctx context.Context, css string) (Element, error) {
+	return first(e.page.find(e, css, ""))
+}
+
+func (e *FakeElement) ElementR(ctx context.Context, css, pattern string) (Element, error) {
+	return first(e.page.find(e, css, pattern))
+}
+
+func (e *FakeElement) Elements(ctx context.Context, css string) ([]Element, error) {
+	return e.page.find(e, css, ""), nil
+}
+
+func (e *FakeElement) Click(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	e.Clicks++
+	if e.OnClick != nil {
+		e.OnClick(e.page)
+	}
+	return nil
+}
+
+func (e *FakeElement) Input(ctx context.Context, text string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if e.selected {
+		e.Value, e.selected = "", false
+	}
+	e.Value += text
+	return nil
+}
+
+func (e *FakeElement) SelectAllText(ctx context.Context) error {
+	e.selected = true
+	return nil
+}
+
+func (e *FakeElement) SetFiles(ctx context.Context, paths []string) error {
+	e.Files = append([]string(nil), paths...)
+	return nil
+}
+
+func (e *FakeElement) Text(ctx context.Context) (string, error) {
+	if e.Value != "" {
+		return e.Value, nil
+	}
+	return e.InnerText, nil
+}
+
+func (e *FakeElement) Attribute(ctx context.Context, name string) (*string, error) {
+	value, ok := e.Attrs[name]
+	if !ok {
+		return nil, nil
+	}
+	return &value, nil
+}
+
+func (e *FakeElement) WaitVisible(ctx context.Context) error {
+	if e.Hidden {
+		return errors.New("fake driver: element is hidden")
+	}
+	return nil
+}
+
+func (e *FakeElement) ScrollIntoView(ctx context.Context) error {
+	return nil
+}
+
+func (e *FakeElement) Parent(ctx context.Context) (Element, error) {
+	if e.parent == nil {
+		return nil, ErrElementNotFound
+	}
+	return e.parent, nil
+}
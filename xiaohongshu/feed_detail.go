@@ -4,59 +4,65 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
+	"strings"
 	"time"
-
-	"github.com/go-rod/rod"
 )
 
+// feedDetailState noteDetailMap 中单篇笔记的数据
+type feedDetailState struct {
+	Note     FeedDetail  `json:"note"`
+	Comments CommentList `json:"comments"`
+}
+
 // FeedDetailAction 表示 Feed 详情页动作
 type FeedDetailAction struct {
-	page *rod.Page
+	driver Driver
 }
 
 // NewFeedDetailAction 创建 Feed 详情页动作
-func NewFeedDetailAction(page *rod.Page) *FeedDetailAction {
-	return &FeedDetailAction{page: page}
+func NewFeedDetailAction(driver Driver) *FeedDetailAction {
+	return &FeedDetailAction{driver: driver}
 }
 
 // GetFeedDetail 获取 Feed 详情页数据
 func (f *FeedDetailAction) GetFeedDetail(ctx context.Context, feedID, xsecToken string) (*FeedDetailResponse, error) {
-	page := f.page.Context(ctx).Timeout(60 * time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
 
 	// 构建详情页 URL
-	url := fmt.Sprintf("https://www.xiaohongshu.com/explore/%s?xsec_token=%s&xsec_source=pc_feed", feedID, xsecToken)
+	url := webURL(fmt.Sprintf("/explore/%s?xsec_token=%s&xsec_source=pc_feed", feedID, xsecToken))
+
+	defer blockResources(ctx, f.driver, "feed_detail")()
+
+	// 评论由页面打开后请求评论接口加载
+	capture := startAPICapture(ctx, f.driver)
+	defer capture.stop()
 
 	// 导航到详情页
-	page.MustNavigate(url)
-	page.MustWaitStable()
-	page.MustWait(`() => window.__INITIAL_STATE__ !== undefined`)
+	if err := f.driver.Navigate(ctx, url); err != nil {
+		return nil, err
+	}
+	if err := f.driver.WaitStable(ctx); err != nil {
+		return nil, err
+	}
+	if err := waitForInitialState(ctx, f.driver); err != nil {
+		return nil, err
+	}
 
 	// 获取 window.__INITIAL_STATE__ 并转换为 JSON 字符串
-	result := page.MustEval(`() => {
-		if (window.__INITIAL_STATE__) {
-			return JSON.stringify(window.__INITIAL_STATE__);
-		}
-		return "";
-	}`).String()
-
-	if result == "" {
-		return nil, fmt.Errorf("__INITIAL_STATE__ not found")
+	result, err := readInitialState(ctx, f.driver)
+	if err != nil {
+		return nil, err
 	}
 
-	// 将原始结果保存到 feed_detail.json 文件用于测试
-	err := os.WriteFile("feed_detail.json", []byte(result), 0644)
-	if err != nil {
-		return nil, fmt.Errorf("failed to write feed_detail.json: %w", err)
+	if err := checkStateSchema(ctx, feedDetailSchema, result, strings.NewReplacer("{id}", feedID)); err != nil {
+		return nil, err
 	}
 
 	// 定义响应结构并直接反序列化
 	var initialState struct {
 		Note struct {
-			NoteDetailMap map[string]struct {
-				Note     FeedDetail  `json:"note"`
-				Comments CommentList `json:"comments"`
-			} `json:"noteDetailMap"`
+			NoteDetailMap map[string]feedDetailState `json:"noteDetailMap"`
 		} `json:"note"`
 	}
 
@@ -70,8 +76,19 @@ func (f *FeedDetailAction) GetFeedDetail(ctx context.Context, feedID, xsecToken
 		return nil, fmt.Errorf("feed %s not found in noteDetailMap", feedID)
 	}
 
+	// 首屏已有评论且不滚动加载时不等待评论接口，避免每次都多等 apiWaitTimeout
+	if len(noteDetail.Comments.List) == 0 || apiScrollPages.Load() > 0 {
+		capture.wait(ctx, f.driver, apiComments, 0)
+		capture.loadMore(ctx, f.driver, apiComments)
+	}
+
+	comments := noteDetail.Comments
+	if more, ok := capture.comments(ctx); ok {
+		comments = mergeComments(comments, more)
+	}
+
 	return &FeedDetailResponse{
 		Note:     noteDetail.Note,
-		Comments: noteDetail.Comments,
+		Comments: comments,
 	}, nil
 }
@@ -5,12 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"time"
-
-	"github.com/go-rod/rod"
 )
 
 type FeedsListAction struct {
-	page *rod.Page
+	driver Driver
 }
 
 // FeedsResult 定义页面初始状态结构
@@ -18,30 +16,35 @@ type FeedsResult struct {
 	Feed FeedData `json:"feed"`
 }
 
-func NewFeedsListAction(page *rod.Page) *FeedsListAction {
-	pp := page.Timeout(60 * time.Second)
+func NewFeedsListAction(ctx context.Context, driver Driver) (*FeedsListAction, error) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	defer blockResources(ctx, driver, "feeds")()
 
-	pp.MustNavigate("https://www.xiaohongshu.com")
-	pp.MustWaitStable()
-	pp.MustWait(`() => window.__INITIAL_STATE__ !== undefined`)
+	if err := driver.Navigate(ctx, BaseURL()); err != nil {
+		return nil, err
+	}
+	if err := driver.WaitStable(ctx); err != nil {
+		return nil, err
+	}
+	if err := waitForInitialState(ctx, driver); err != nil {
+		return nil, err
+	}
 
-	return &FeedsListAction{page: pp}
+	return &FeedsListAction{driver: driver}, nil
 }
 
 // GetFeedsList 获取页面的 Feed 列表数据
 func (f *FeedsListAction) GetFeedsList(ctx context.Context) ([]Feed, error) {
-	page := f.page.Context(ctx)
-
 	// 获取 window.__INITIAL_STATE__ 并转换为 JSON 字符串
-	result := page.MustEval(`() => {
-		if (window.__INITIAL_STATE__) {
-			return JSON.stringify(window.__INITIAL_STATE__);
-		}
-		return "";
-	}`).String()
-
-	if result == "" {
-		return nil, fmt.Errorf("__INITIAL_STATE__ not found")
+	result, err := readInitialState(ctx, f.driver)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkStateSchema(ctx, feedsSchema, result, nil); err != nil {
+		return nil, err
 	}
 
 	// 解析完整的 InitialState
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/xpzouying/xiaohongshu-mcp/browser"
 )
@@ -21,7 +22,8 @@ func TestGetFeedsList(t *testing.T) {
 	defer page.Close()
 
 	// NewFeedsListAction 内部已经处理导航
-	action := NewFeedsListAction(page)
+	action, err := NewFeedsListAction(context.Background(), NewRodDriver(page))
+	require.NoError(t, err)
 
 	feeds, err := action.GetFeedsList(context.Background())
 	require.NoError(t, err)
@@ -83,3 +85,25 @@ func TestGetFeedsList(t *testing.T) {
 		}
 	}
 }
+
+func TestGetFeedsListWithFakeDriver(t *testing.T) {
+	driver := NewFakeDriver(map[string]*FakePage{
+		"https://www.xiaohongshu.com": {
+			State: map[string]any{
+				"feed": map[string]any{"feeds": map[string]any{"_value": []Feed{
+					{ID: "64f1", XsecToken: "token", ModelType: "note", NoteCard: NoteCard{Type: "normal", DisplayTitle: "秋天穿搭"}},
+				}}},
+			},
+		},
+	})
+
+	action, err := NewFeedsListAction(context.Background(), driver)
+	require.NoError(t, err)
+
+	feeds, err := action.GetFeedsList(context.Background())
+	require.NoError(t, err)
+	require.Len(t, feeds, 1)
+	assert.Equal(t, "64f1", feeds[0].ID)
+	assert.Equal(t, "秋天穿搭", feeds[0].NoteCard.DisplayTitle)
+	assert.Equal(t, []string{"https://www.xiaohongshu.com"}, driver.Visited())
+}
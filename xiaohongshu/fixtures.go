@@ -0,0 +1,275 @@
+package xiaohongshu
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/ysmood/gson"
+)
+
+// defaultBaseURL 小红书网页版地址
+const defaultBaseURL = "https://www.xiaohongshu.com"
+
+// baseURL 首页、搜索、详情和用户主页使用的网页版地址，回放录制的页面时指向本地服务
+var baseURL = defaultBaseURL
+
+// SetBaseURL 修改网页版地址，为空时恢复默认地址。只应在启动时或测试中调用
+func SetBaseURL(u string) {
+	if u == "" {
+		u = defaultBaseURL
+	}
+	baseURL = strings.TrimRight(u, "/")
+}
+
+// BaseURL 当前使用的网页版地址
+func BaseURL() string {
+	return baseURL
+}
+
+// webURL 网页版上 path 对应的完整地址
+func webURL(path string) string {
+	return baseURL + path
+}
+
+// 录制的页面保存在 fixtures 目录中：
+//
+//	index.json       请求地址（路径和查询参数）到页面名称的映射
+//	<name>.html      去掉脚本后的页面 HTML
+//	<name>.state.json 页面的 __INITIAL_STATE__
+const (
+	fixtureIndexFile   = "index.json"
+	fixtureHTMLSuffix  = ".html"
+	fixtureStateSuffix = ".state.json"
+)
+
+// fixtureMu 多个会话同时录制时保护 index.json 的读写
+var fixtureMu sync.Mutex
+
+// pageHTMLScript 导出当前页面的 HTML，去掉脚本，回放时页面不会再请求小红书接口
+const pageHTMLScript = `() => {
+	const root = document.documentElement.cloneNode(true);
+	root.querySelectorAll("script, noscript, link[rel=preload], link[rel=modulepreload]").forEach((el) => el.remove());
+	return "<!DOCTYPE html>\n" + root.outerHTML;
+}`
+
+// fixtureNameInvalid 页面名称中不能出现的字符
+var fixtureNameInvalid = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// fixtureKey 录制和回放时查找页面使用的请求地址：路径和查询参数，不含域名
+func fixtureKey(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", errors.Wrapf(err, "parse %s", rawURL)
+	}
+	key := u.EscapedPath()
+	if key == "" {
+		key = "/"
+	}
+	if u.RawQuery != "" {
+		key += "?" + u.RawQuery
+	}
+	return key, nil
+}
+
+// fixtureName 页面文件名，由路径生成，带查询参数时追加其哈希区分不同的搜索词
+func fixtureName(key string) string {
+	path, query, _ := strings.Cut(key, "?")
+	name := strings.Trim(fixtureNameInvalid.ReplaceAllString(path, "_"), "_")
+	if name == "" {
+		name = "index"
+	}
+	if query != "" {
+		sum := sha1.Sum([]byte(query))
+		name += "_" + hex.EncodeToString(sum[:4])
+	}
+	return name
+}
+
+func readFixtureIndex(dir string) (map[string]string, error) {
+	index := map[string]string{}
+	data, err := os.ReadFile(filepath.Join(dir, fixtureIndexFile))
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, errors.Wrapf(err, "parse %s", fixtureIndexFile)
+	}
+	return index, nil
+}
+
+// SaveFixture 保存一个页面，url 为打开页面时使用的地址，state 为 __INITIAL_STATE__ 的 JSON
+func SaveFixture(dir, url, html, state string) (string, error) {
+	key, err := fixtureKey(url)
+	if err != nil {
+		return "", err
+	}
+	name := fixtureName(key)
+
+	fixtureMu.Lock()
+	defer fixtureMu.Unlock()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+fixtureHTMLSuffix), []byte(html), 0644); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+fixtureStateSuffix), []byte(state), 0644); err != nil {
+		return "", err
+	}
+
+	index, err := readFixtureIndex(dir)
+	if err != nil {
+		return "", err
+	}
+	index[key] = name
+
+	// 保留查询参数中的 &，方便查看
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(index); err != nil {
+		return "", err
+	}
+	return name, os.WriteFile(filepath.Join(dir, fixtureIndexFile), buf.Bytes(), 0644)
+}
+
+// recordingDriver 在 action 读取 __INITIAL_STATE__ 时把当前页面保存到 fixtures 目录
+type recordingDriver struct {
+	Driver
+
+	dir string
+	// url 最近一次打开的地址，回放时浏览器请求的也是这个地址，而不是跳转后的地址
+	url string
+}
+
+// NewRecordingDriver 创建录制页面的驱动，首页、搜索、详情和用户主页读取页面数据时
+// 会把页面 HTML 和 __INITIAL_STATE__ 保存到 dir，录制失败只记录日志，不影响操作
+func NewRecordingDriver(driver Driver, dir string) Driver {
+	return &recordingDriver{Driver: driver, dir: dir}
+}
+
+func (d *recordingDriver) Navigate(ctx context.Context, url string) error {
+	if err := d.Driver.Navigate(ctx, url); err != nil {
+		return err
+	}
+	d.url = url
+	return nil
+}
+
+func (d *recordingDriver) Eval(ctx context.Context, js string, args ...any) (gson.JSON, error) {
+	result, err := d.Driver.Eval(ctx, js, args...)
+	if err == nil && (js == InitialStateScript || js == userProfileScript) {
+		if name, err := d.record(ctx); err != nil {
+			slog.WarnContext(ctx, "录制页面失败", "url", d.url, "error", err)
+		} else {
+			slog.InfoContext(ctx, "录制页面", "url", d.url, "name", name)
+		}
+	}
+	return result, err
+}
+
+func (d *recordingDriver) record(ctx context.Context) (string, error) {
+	if d.url == "" {
+		return "", errors.New("no page opened")
+	}
+	state, err := readInitialState(ctx, d.Driver)
+	if err != nil {
+		return "", err
+	}
+	html, err := d.Driver.Eval(ctx, pageHTMLScript)
+	if err != nil {
+		return "", errors.Wrap(err, "read page html")
+	}
+	return SaveFixture(d.dir, d.url, html.Str(), state)
+}
+
+// ReplayHandler 回放 fixtures 目录中录制的页面，配合 SetBaseURL 离线测试读取类操作
+type ReplayHandler struct {
+	dir   string
+	index map[string]string
+}
+
+// NewReplayHandler 读取 dir 中录制的页面列表
+func NewReplayHandler(dir string) (*ReplayHandler, error) {
+	index, err := readFixtureIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(index) == 0 {
+		return nil, errors.Errorf("no fixtures in %s", dir)
+	}
+	return &ReplayHandler{dir: dir, index: index}, nil
+}
+
+// lookup 按完整的请求地址查找页面，找不到时按路径查找，如详情页的 xsec_token 不同。
+// 同一路径录制了多个页面时取地址排序最前的一个，保证结果稳定
+func (h *ReplayHandler) lookup(key string) (string, bool) {
+	if name, ok := h.index[key]; ok {
+		return name, true
+	}
+	path, _, _ := strings.Cut(key, "?")
+	var found string
+	for k := range h.index {
+		if p, _, _ := strings.Cut(k, "?"); p == path && (found == "" || k < found) {
+			found = k
+		}
+	}
+	if found == "" {
+		return "", false
+	}
+	return h.index[found], true
+}
+
+func (h *ReplayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key, err := fixtureKey(r.URL.RequestURI())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	name, ok := h.lookup(key)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	html, err := os.ReadFile(filepath.Join(h.dir, name+fixtureHTMLSuffix))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	state, err := os.ReadFile(filepath.Join(h.dir, name+fixtureStateSuffix))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(injectInitialState(string(html), string(state))))
+}
+
+// injectInitialState 在 head 中插入设置 __INITIAL_STATE__ 的脚本
+func injectInitialState(html, state string) string {
+	// 避免数据中的 </script> 提前结束脚本
+	script := "<script>window.__INITIAL_STATE__=" + strings.ReplaceAll(state, "</", `<\/`) + "</script>"
+	if i := strings.Index(html, "</head>"); i >= 0 {
+		return html[:i] + script + html[i:]
+	}
+	return script + html
+}
@@ -0,0 +1,130 @@
+package xiaohongshu
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-rod/rod/lib/launcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xpzouying/xiaohongshu-mcp/browser"
+)
+
+func TestFixtureName(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.xiaohongshu.com", "index"},
+		{"https://www.xiaohongshu.com/explore", "explore"},
+		{"https://www.xiaohongshu.com/explore/64f1a2b3000000001f03c2d1?xsec_token=abc&xsec_source=pc_feed", "explore_64f1a2b3000000001f03c2d1_305ae1ca"},
+		{makeSearchURL("穿搭"), "search_result_7e84963c"},
+	}
+	for _, tt := range tests {
+		key, err := fixtureKey(tt.url)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, fixtureName(key), tt.url)
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	fake := NewFakeDriver(map[string]*FakePage{
+		BaseURL(): {
+			State: map[string]any{"feed": map[string]any{"feeds": map[string]any{"_value": []map[string]any{
+				{"id": "n1", "xsecToken": "t1", "noteCard": map[string]any{"displayTitle": "第一条"}},
+			}}}},
+			Scripts: map[string]any{
+				pageHTMLScript: `<!DOCTYPE html><html><head><title>小红书</title></head><body><div id="app"></div></body></html>`,
+			},
+		},
+	})
+
+	ctx := context.Background()
+	action, err := NewFeedsListAction(ctx, NewRecordingDriver(fake, dir))
+	require.NoError(t, err)
+	feeds, err := action.GetFeedsList(ctx)
+	require.NoError(t, err)
+	require.Len(t, feeds, 1)
+
+	index, err := readFixtureIndex(dir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"/": "index"}, index)
+	assert.FileExists(t, filepath.Join(dir, "index.html"))
+	assert.FileExists(t, filepath.Join(dir, "index.state.json"))
+
+	handler, err := NewReplayHandler(dir)
+	require.NoError(t, err)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/?channel_id=homefeed_recommend")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), `<head><title>小红书</title><script>window.__INITIAL_STATE__={"feed":`)
+
+	resp, err = http.Get(server.URL + "/explore/n1")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestInjectInitialStateEscapesScript(t *testing.T) {
+	html := injectInitialState("<html><body></body></html>", `{"desc":"</script><b>"}`)
+	assert.Equal(t, `<script>window.__INITIAL_STATE__={"desc":"<\/script><b>"}</script><html><body></body></html>`, html)
+}
+
+// TestReplayReadActions 在浏览器中回放 testdata/fixtures，离线检查读取类操作的解析。
+// 可以用 go run ./cmd/fixtures -dir xiaohongshu/testdata/fixtures 重新录制
+func TestReplayReadActions(t *testing.T) {
+	if _, ok := launcher.LookPath(); !ok {
+		t.Skip("SKIP: 没有找到浏览器")
+	}
+
+	handler, err := NewReplayHandler(filepath.Join("testdata", "fixtures"))
+	require.NoError(t, err)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	SetBaseURL(server.URL)
+	t.Cleanup(func() { SetBaseURL("") })
+
+	b := browser.NewBrowser(true)
+	defer b.Close()
+	page := b.NewPage()
+	defer page.Close()
+
+	ctx := context.Background()
+	driver := NewRodDriver(page)
+
+	action, err := NewFeedsListAction(ctx, driver)
+	require.NoError(t, err)
+	feeds, err := action.GetFeedsList(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, feeds)
+	feed := feeds[0]
+	require.NotEmpty(t, feed.ID)
+	require.NotEmpty(t, feed.XsecToken)
+
+	results, err := NewSearchAction(driver).Search(ctx, "穿搭")
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+
+	detail, err := NewFeedDetailAction(driver).GetFeedDetail(ctx, feed.ID, feed.XsecToken)
+	require.NoError(t, err)
+	assert.Equal(t, feed.ID, detail.Note.NoteID)
+	assert.NotEmpty(t, detail.Note.Title)
+
+	user := feed.NoteCard.User
+	profile, err := NewUserProfileAction(driver).UserProfile(ctx, user.UserID, user.XsecToken)
+	require.NoError(t, err)
+	assert.NotEmpty(t, profile.BasicInfo.Nickname)
+	assert.NotEmpty(t, profile.Feeds)
+}
@@ -4,54 +4,90 @@ import (
 	"context"
 	"time"
 
-	"github.com/go-rod/rod"
 	"github.com/pkg/errors"
 )
 
 type LoginAction struct {
-	page *rod.Page
+	driver Driver
 }
 
-func NewLogin(page *rod.Page) *LoginAction {
-	return &LoginAction{page: page}
+func NewLogin(driver Driver) *LoginAction {
+	return &LoginAction{driver: driver}
 }
 
 func (a *LoginAction) CheckLoginStatus(ctx context.Context) (bool, error) {
-	pp := a.page.Context(ctx)
-	pp.MustNavigate("https://www.xiaohongshu.com/explore").MustWaitLoad()
-
-	time.Sleep(1 * time.Second)
+	if err := navigate(ctx, a.driver, webURL("/explore")); err != nil {
+		return false, err
+	}
 
-	exists, _, err := pp.Has(`.main-container .user .link-wrapper .channel`)
-	if err != nil {
-		return false, errors.Wrap(err, "check login status failed")
+	if err := a.driver.Sleep(ctx, 1*time.Second); err != nil {
+		return false, err
 	}
 
-	if !exists {
-		return false, errors.Wrap(err, "login status element not found")
+	if !hasSelector(ctx, a.driver, "login.logged_in") {
+		return false, errors.New("login status element not found")
 	}
 
 	return true, nil
 }
 
 func (a *LoginAction) Login(ctx context.Context) error {
-	pp := a.page.Context(ctx)
-
 	// 导航到小红书首页，这会触发二维码弹窗
-	pp.MustNavigate("https://www.xiaohongshu.com/explore").MustWaitLoad()
+	if err := navigate(ctx, a.driver, webURL("/explore")); err != nil {
+		return err
+	}
 
 	// 等待一小段时间让页面完全加载
-	time.Sleep(2 * time.Second)
+	if err := a.driver.Sleep(ctx, 2*time.Second); err != nil {
+		return err
+	}
 
 	// 检查是否已经登录
-	if exists, _, _ := pp.Has(".main-container .user .link-wrapper .channel"); exists {
+	if hasSelector(ctx, a.driver, "login.logged_in") {
 		// 已经登录，直接返回
 		return nil
 	}
 
 	// 等待扫码成功提示或者登录完成
 	// 这里我们等待登录成功的元素出现，这样更简单可靠
-	pp.MustElement(".main-container .user .link-wrapper .channel")
+	_, err := findSelector(ctx, a.driver, "login.logged_in", 0)
+	return err
+}
+
+// FetchQrcodeImage 打开首页获取登录二维码，返回 data URL 格式的图片。
+// 如果已经登录，返回 isLoggedIn 为 true。
+func (a *LoginAction) FetchQrcodeImage(ctx context.Context) (img string, isLoggedIn bool, err error) {
+	// 导航到小红书首页，这会触发二维码弹窗
+	if err := navigate(ctx, a.driver, webURL("/explore")); err != nil {
+		return "", false, err
+	}
+
+	if err := a.driver.Sleep(ctx, 2*time.Second); err != nil {
+		return "", false, err
+	}
+
+	if hasSelector(ctx, a.driver, "login.logged_in") {
+		return "", true, nil
+	}
+
+	qrcode, err := findSelector(ctx, a.driver, "login.qrcode", 10*time.Second)
+	if err != nil {
+		return "", false, err
+	}
+
+	src, err := qrcode.Attribute(ctx, "src")
+	if err != nil {
+		return "", false, errors.Wrap(err, "get qrcode src failed")
+	}
+	if src == nil || *src == "" {
+		return "", false, errors.New("qrcode src is empty")
+	}
+
+	return *src, false, nil
+}
 
-	return nil
+// WaitForLogin 等待扫码登录完成，ctx 结束前登录成功返回 true
+func (a *LoginAction) WaitForLogin(ctx context.Context) bool {
+	_, err := findSelector(ctx, a.driver, "login.logged_in", 0)
+	return err == nil
 }
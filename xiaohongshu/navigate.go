@@ -2,24 +2,23 @@ package xiaohongshu
 
 import (
 	"context"
-
-	"github.com/go-rod/rod"
+	"time"
 )
 
 type NavigateAction struct {
-	page *rod.Page
+	driver Driver
 }
 
-func NewNavigate(page *rod.Page) *NavigateAction {
-	return &NavigateAction{page: page}
+func NewNavigate(driver Driver) *NavigateAction {
+	return &NavigateAction{driver: driver}
 }
 
 func (n *NavigateAction) ToExplorePage(ctx context.Context) error {
-	page := n.page.Context(ctx)
-
-	page.MustNavigate("https://www.xiaohongshu.com/explore").
-		MustWaitLoad().
-		MustElement(`div#app`)
-
-	return nil
+	if err := navigate(ctx, n.driver, webURL("/explore")); err != nil {
+		return err
+	}
+	_, err := waitElement(ctx, n.driver, time.Minute, func() (Element, error) {
+		return n.driver.Element(ctx, `div#app`)
+	})
+	return err
 }
@@ -0,0 +1,53 @@
+package xiaohongshu
+
+import (
+	"context"
+	"log/slog"
+)
+
+// ProgressReporter 汇报浏览器操作的步骤进度
+type ProgressReporter interface {
+	// Report progress 为当前步骤序号，total 为总步骤数（未知时为 0）
+	Report(progress, total float64, message string)
+}
+
+// ProgressFunc 将普通函数适配为 ProgressReporter
+type ProgressFunc func(progress, total float64, message string)
+
+// Report 实现 ProgressReporter
+func (f ProgressFunc) Report(progress, total float64, message string) {
+	f(progress, total, message)
+}
+
+type progressContextKey struct{}
+
+// WithProgress 返回携带进度回调的 context，action 执行过程中会通过它汇报步骤
+func WithProgress(ctx context.Context, reporter ProgressReporter) context.Context {
+	return context.WithValue(ctx, progressContextKey{}, reporter)
+}
+
+// reportProgress 汇报进度，context 中没有回调时忽略
+func reportProgress(ctx context.Context, progress, total float64, message string) {
+	if reporter, ok := ctx.Value(progressContextKey{}).(ProgressReporter); ok && reporter != nil {
+		reporter.Report(progress, total, message)
+	}
+}
+
+// stepProgress 按顺序递增汇报的步骤进度
+type stepProgress struct {
+	ctx     context.Context
+	current float64
+	total   float64
+}
+
+// newStepProgress 创建步骤进度，done 为已完成的步骤数
+func newStepProgress(ctx context.Context, done, total float64) *stepProgress {
+	return &stepProgress{ctx: ctx, current: done, total: total}
+}
+
+// step 进入下一个步骤，同时记录步骤日志
+func (p *stepProgress) step(message string) {
+	p.current++
+	slog.InfoContext(p.ctx, message, "step", p.current, "total", p.total)
+	reportProgress(p.ctx, p.current, p.total, message)
+}
@@ -2,12 +2,11 @@ package xiaohongshu
 
 import (
     "context"
+    "fmt"
     "log/slog"
     "strings"
     "time"
 
-	"github.com/go-rod/rod"
-	"github.com/go-rod/rod/lib/proto"
     "github.com/pkg/errors"
 )
 
@@ -19,47 +18,69 @@ type PublishImageContent struct {
 }
 
 type PublishAction struct {
-	page *rod.Page
+	driver Driver
 }
 
-const (
-	urlOfPublic = `https://creator.xiaohongshu.com/publish/publish?source=official`
-)
+// defaultCreatorBaseURL 创作者中心地址
+const defaultCreatorBaseURL = "https://creator.xiaohongshu.com"
+
+// creatorBaseURL 发布使用的创作者中心地址，集成测试时指向本地的模拟服务（cmd/mock-xhs）
+var creatorBaseURL = defaultCreatorBaseURL
+
+// SetCreatorBaseURL 修改创作者中心地址，为空时恢复默认地址。只应在启动时或测试中调用
+func SetCreatorBaseURL(u string) {
+	if u == "" {
+		u = defaultCreatorBaseURL
+	}
+	creatorBaseURL = strings.TrimRight(u, "/")
+}
 
-func NewPublishImageAction(page *rod.Page) (*PublishAction, error) {
+// publishURL 创作者发布页地址
+func publishURL() string {
+	return creatorBaseURL + "/publish/publish?source=official"
+}
 
-	pp := page.Timeout(60 * time.Second)
+// scrollToBottomScript 滚动到页面底部
+const scrollToBottomScript = `() => window.scrollTo(0, document.body.scrollHeight)`
 
-	pp.MustNavigate(urlOfPublic)
+// scrollContainerToBottomScript 把内嵌的可滚动容器（如 microapp 容器）拉到底
+const scrollContainerToBottomScript = `() => { const el = document.querySelector('.microapp-container, #creator-publish-dom, .p-container'); if (el) { el.scrollTop = el.scrollHeight } }`
 
-	pp.MustElement(`div.upload-content`).MustWaitVisible()
-	slog.Info("wait for upload-content visible success")
+func NewPublishImageAction(ctx context.Context, driver Driver) (*PublishAction, error) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	// 图片数量在 Publish 时才确定，这里总步骤数未知
+	progress := newStepProgress(ctx, 0, 0)
+
+	progress.step("打开创作者发布页面")
+	if err := driver.Navigate(ctx, publishURL()); err != nil {
+		return nil, err
+	}
+
+	if _, err := waitForSelector(ctx, driver, "publish.upload_area"); err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "wait for upload-content visible success")
 
 	// 等待一段时间确保页面完全加载
-	time.Sleep(1 * time.Second)
-
-	createElems := pp.MustElements("div.creator-tab")
-	slog.Info("foundcreator-tab elements", "count", len(createElems))
-	for _, elem := range createElems {
-		text, err := elem.Text()
-		if err != nil {
-			slog.Error("获取元素文本失败", "error", err)
-			continue
-		}
+	if err := driver.Sleep(ctx, 1*time.Second); err != nil {
+		return nil, err
+	}
 
-		if text == "上传图文" {
-			if err := elem.Click(proto.InputMouseButtonLeft, 1); err != nil {
-				slog.Error("点击元素失败", "error", err)
-				continue
-			}
-			break
-		}
+	progress.step("切换到上传图文")
+	if tab, err := findSelector(ctx, driver, "publish.image_tab", 5*time.Second); err != nil {
+		slog.ErrorContext(ctx, "切换到上传图文失败", "error", err)
+	} else if err := tab.Click(ctx); err != nil {
+		slog.ErrorContext(ctx, "点击元素失败", "error", err)
 	}
 
-	time.Sleep(1 * time.Second)
+	if err := driver.Sleep(ctx, 1*time.Second); err != nil {
+		return nil, err
+	}
 
 	return &PublishAction{
-		page: pp,
+		driver: driver,
 	}, nil
 }
 
@@ -68,195 +89,210 @@ func (p *PublishAction) Publish(ctx context.Context, content PublishImageContent
 		return errors.New("图片不能为空")
 	}
 
-	page := p.page.Context(ctx)
+	// 前两步（打开页面、切换选项卡）在 NewPublishImageAction 中完成
+	progress := newStepProgress(ctx, 2, float64(len(content.ImagePaths)+5))
 
-	if err := uploadImages(page, content.ImagePaths); err != nil {
+	if err := uploadImages(ctx, p.driver, content.ImagePaths, progress); err != nil {
 		return errors.Wrap(err, "小红书上传图片失败")
 	}
 
-	if err := submitPublish(page, content.Title, content.Content); err != nil {
+	if err := submitPublish(ctx, p.driver, content.Title, content.Content, progress); err != nil {
 		return errors.Wrap(err, "小红书发布失败")
 	}
 
 	return nil
 }
 
-func uploadImages(page *rod.Page, imagesPaths []string) error {
-	pp := page.Timeout(30 * time.Second)
+func uploadImages(ctx context.Context, d Driver, imagesPaths []string, progress *stepProgress) error {
+	uploadCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
 
 	// 等待上传输入框出现
-	uploadInput := pp.MustElement(".upload-input")
+	uploadInput, err := findSelector(uploadCtx, d, "publish.upload_input", 0)
+	if err != nil {
+		return err
+	}
 
 	// 上传多个文件
-	uploadInput.MustSetFiles(imagesPaths...)
+	if err := uploadInput.SetFiles(uploadCtx, imagesPaths); err != nil {
+		return errors.Wrap(err, "设置上传文件失败")
+	}
 
 	// 等待上传完成
-	time.Sleep(3 * time.Second)
-
-	return nil
+	return waitForUploadComplete(ctx, d, len(imagesPaths), progress)
 }
 
-func submitPublish(page *rod.Page, title, content string) error {
-
-	titleElem := page.MustElement("div.d-input input")
-	titleElem.MustInput(title)
-
-	time.Sleep(1 * time.Second)
+// waitForUploadComplete 根据预览图数量等待图片上传完成，并逐张汇报进度。
+// 页面上找不到预览区域时退回到固定等待。
+func waitForUploadComplete(ctx context.Context, d Driver, expected int, progress *stepProgress) error {
+	const (
+		checkInterval = 500 * time.Millisecond
+		maxWait       = 60 * time.Second
+		// 超过该时间仍没有任何预览图，认为预览区域选择器不可用
+		previewProbe = 3 * time.Second
+	)
+
+	reported := 0
+	var waited time.Duration
+	for waited < maxWait {
+		uploaded := countSelector(ctx, d, "publish.image_preview")
+		if uploaded > expected {
+			uploaded = expected
+		}
 
-	if contentElem, ok := getContentElement(page); ok {
-		contentElem.MustInput(content)
-	} else {
-		return errors.New("没有找到内容输入框")
-	}
+		for ; reported < uploaded; reported++ {
+			progress.step(fmt.Sprintf("上传图片 %d/%d", reported+1, expected))
+		}
 
-	time.Sleep(1 * time.Second)
+		if uploaded >= expected {
+			return nil
+		}
 
-	submitButton := page.MustElement("div.submit div.d-button-content")
-	submitButton.MustClick()
+		if uploaded == 0 && waited > previewProbe {
+			slog.WarnContext(ctx, "no image preview found, fallback to fixed wait")
+			break
+		}
 
-	time.Sleep(3 * time.Second)
+		if err := d.Sleep(ctx, checkInterval); err != nil {
+			return err
+		}
+		waited += checkInterval
+	}
 
+	for ; reported < expected; reported++ {
+		progress.step(fmt.Sprintf("上传图片 %d/%d", reported+1, expected))
+	}
 	return nil
 }
 
-// 查找内容输入框 - 使用Race方法处理两种样式
-func getContentElement(page *rod.Page) (*rod.Element, bool) {
-	var foundElement *rod.Element
-	var found bool
-
-	page.Race().
-		Element("div.ql-editor").MustHandle(func(e *rod.Element) {
-		foundElement = e
-		found = true
-	}).
-		ElementFunc(func(page *rod.Page) (*rod.Element, error) {
-			return findTextboxByPlaceholder(page)
-		}).MustHandle(func(e *rod.Element) {
-		foundElement = e
-		found = true
-	}).
-		MustDo()
-
-	if found {
-		return foundElement, true
-	}
-
-	slog.Warn("no content element found by any method")
-	return nil, false
-}
+func submitPublish(ctx context.Context, d Driver, title, content string, progress *stepProgress) error {
+
+	progress.step("填写标题")
+	titleElem, err := findSelector(ctx, d, "publish.title_input", 10*time.Second)
+	if err != nil {
+		return err
+	}
+	if err := titleElem.Input(ctx, title); err != nil {
+		return errors.Wrap(err, "填写标题失败")
+	}
 
-func findTextboxByPlaceholder(page *rod.Page) (*rod.Element, error) {
-	elements := page.MustElements("p")
-	if elements == nil {
-		return nil, errors.New("no p elements found")
+	if err := d.Sleep(ctx, 1*time.Second); err != nil {
+		return err
 	}
 
-	// 查找包含指定placeholder的元素
-	placeholderElem := findPlaceholderElement(elements, "输入正文描述")
-	if placeholderElem == nil {
-		return nil, errors.New("no placeholder element found")
+	progress.step("填写正文")
+	contentElem, err := findSelector(ctx, d, "publish.content_editor", 10*time.Second)
+	if err != nil {
+		return err
+	}
+	if err := contentElem.Input(ctx, content); err != nil {
+		return errors.Wrap(err, "填写正文失败")
 	}
 
-	// 向上查找textbox父元素
-	textboxElem := findTextboxParent(placeholderElem)
-	if textboxElem == nil {
-		return nil, errors.New("no textbox parent found")
+	if err := d.Sleep(ctx, 1*time.Second); err != nil {
+		return err
 	}
 
-	return textboxElem, nil
+	progress.step("提交发布")
+	submitButton, err := findSelector(ctx, d, "publish.submit_button", 10*time.Second)
+	if err != nil {
+		return err
+	}
+	markSubmitted(ctx)
+	if err := submitButton.Click(ctx); err != nil {
+		return errors.Wrap(err, "点击发布按钮失败")
+	}
+
+	return waitPublishResult(ctx, d)
 }
 
-func findPlaceholderElement(elements []*rod.Element, searchText string) *rod.Element {
-	for _, elem := range elements {
-		placeholder, err := elem.Attribute("data-placeholder")
-		if err != nil || placeholder == nil {
-			continue
-		}
+// publishResultTimeout 提交后等待发布结果提示的最长时间
+const publishResultTimeout = 10 * time.Second
 
-		if strings.Contains(*placeholder, searchText) {
-			return elem
-		}
-	}
-	return nil
+// PublishRejectedError 提交后小红书提示发布失败，例如内容违规
+type PublishRejectedError struct {
+	// Message 页面提示的失败原因
+	Message string
 }
 
-func findTextboxParent(elem *rod.Element) *rod.Element {
-	currentElem := elem
-	for i := 0; i < 5; i++ {
-		parent, err := currentElem.Parent()
-		if err != nil {
-			break
-		}
+func (e *PublishRejectedError) Error() string {
+	return "发布被拒绝：" + e.Message
+}
 
-		role, err := parent.Attribute("role")
-		if err != nil || role == nil {
-			currentElem = parent
-			continue
-		}
+// waitPublishResult 等待提交后弹出的结果提示，提示不是发布成功时返回 *PublishRejectedError。
+// 没有等到提示时按发布成功处理。已经点击了发布按钮，这里不检测风控页面，
+// 避免人工接管后从头重新执行导致重复发布
+func waitPublishResult(ctx context.Context, d Driver) error {
+	toast, err := findSelector(ctx, d, "publish.result_toast", publishResultTimeout)
+	if err != nil {
+		slog.WarnContext(ctx, "提交后没有等到发布结果提示，按发布成功处理", "error", err)
+		return nil
+	}
 
-		if *role == "textbox" {
-			return parent
-		}
+	if hasSelector(ctx, d, "publish.success_toast") {
+		return nil
+	}
 
-		currentElem = parent
+	message, err := toast.Text(ctx)
+	if err != nil {
+		return errors.Wrap(err, "读取发布结果提示失败")
 	}
-	return nil
+	return &PublishRejectedError{Message: strings.TrimSpace(message)}
 }
 
+// longTextPublishSteps 长文发布的总步骤数
+const longTextPublishSteps = 10
+
 // NewPublishLongTextAction 创建长文发布Action
-func NewPublishLongTextAction(page *rod.Page) (*PublishAction, error) {
-	pp := page.Timeout(60 * time.Second)
+func NewPublishLongTextAction(ctx context.Context, driver Driver) (*PublishAction, error) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
 
-	pp.MustNavigate(urlOfPublic)
-	pp.MustElement(`div.upload-content`).MustWaitVisible()
+	progress := newStepProgress(ctx, 0, longTextPublishSteps)
+
+	progress.step("打开创作者发布页面")
+	if err := driver.Navigate(ctx, publishURL()); err != nil {
+		return nil, err
+	}
+	if _, err := waitForSelector(ctx, driver, "publish.upload_area"); err != nil {
+		return nil, err
+	}
 
 	// 等待页面加载
-	time.Sleep(1 * time.Second)
+	if err := driver.Sleep(ctx, 1*time.Second); err != nil {
+		return nil, err
+	}
 
 	// 点击"写长文"选项卡
-	createElems := pp.MustElements("div.creator-tab")
-	for _, elem := range createElems {
-		text, err := elem.Text()
-		if err != nil {
-			continue
-		}
-		if text == "写长文" {
-			if err := elem.Click(proto.InputMouseButtonLeft, 1); err != nil {
-				continue
-			}
-			break
-		}
+	progress.step("切换到写长文")
+	if tab, err := findSelector(ctx, driver, "publish.longtext_tab", 5*time.Second); err != nil {
+		slog.ErrorContext(ctx, "切换到写长文失败", "error", err)
+	} else if err := tab.Click(ctx); err != nil {
+		slog.ErrorContext(ctx, "点击元素失败", "error", err)
 	}
 
-	time.Sleep(2 * time.Second)
-
-	// 点击"新的创作"按钮
-	buttons := pp.MustElements("button")
-	var createButton *rod.Element
-	for _, btn := range buttons {
-		text, err := btn.Text()
-		if err != nil {
-			continue
-		}
-		if strings.Contains(text, "新的创作") {
-			createButton = btn
-			break
-		}
+	if err := driver.Sleep(ctx, 2*time.Second); err != nil {
+		return nil, err
 	}
 
-	if createButton == nil {
-		return nil, errors.New("找不到新的创作按钮")
+	// 点击"新的创作"按钮
+	progress.step("新的创作")
+	createButton, err := findSelector(ctx, driver, "longtext.new_button", 10*time.Second)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := createButton.Click(proto.InputMouseButtonLeft, 1); err != nil {
+	if err := createButton.Click(ctx); err != nil {
 		return nil, errors.Wrap(err, "点击新的创作按钮失败")
 	}
 
 	// 等待页面跳转
-	time.Sleep(2 * time.Second)
+	if err := driver.Sleep(ctx, 2*time.Second); err != nil {
+		return nil, err
+	}
 
 	return &PublishAction{
-		page: pp,
+		driver: driver,
 	}, nil
 }
 
@@ -266,9 +302,10 @@ func (p *PublishAction) PublishLongText(ctx context.Context, content PublishLong
 		return errors.New("标题和内容不能为空")
 	}
 
-	page := p.page.Context(ctx)
+	// 前三步在 NewPublishLongTextAction 中完成
+	progress := newStepProgress(ctx, 3, longTextPublishSteps)
 
-	if err := submitLongTextPublish(page, content.Title, content.Content); err != nil {
+	if err := submitLongTextPublish(ctx, p.driver, content.Title, content.Content, progress); err != nil {
 		return errors.Wrap(err, "小红书长文发布失败")
 	}
 
@@ -276,164 +313,212 @@ func (p *PublishAction) PublishLongText(ctx context.Context, content PublishLong
 }
 
 // submitLongTextPublish 提交长文发布
-func submitLongTextPublish(page *rod.Page, title, content string) error {
-	pp := page.Timeout(30 * time.Second)
+func submitLongTextPublish(ctx context.Context, d Driver, title, content string, progress *stepProgress) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
 
 	// 填写标题
-	titleElem, err := findLongTextTitleElement(pp)
+	progress.step("填写标题")
+	titleElem, err := findLongTextTitleElement(ctx, d)
 	if err != nil {
 		return errors.Wrap(err, "找不到标题输入框")
 	}
-
-	titleElem.MustClick()
-	time.Sleep(500 * time.Millisecond)
-	titleElem.MustSelectAllText()
-	titleElem.MustInput(title)
-	time.Sleep(1 * time.Second)
+	if err := fillElement(ctx, d, titleElem, title, true); err != nil {
+		return errors.Wrap(err, "填写标题失败")
+	}
 
 	// 填写内容
-	contentElem, err := findLongTextContentElement(pp)
+	progress.step("填写正文")
+	contentElem, err := findLongTextContentElement(ctx, d)
 	if err != nil {
 		return errors.Wrap(err, "找不到内容输入区域")
 	}
-
-	contentElem.MustClick()
-	time.Sleep(500 * time.Millisecond)
-	contentElem.MustInput(content)
-	time.Sleep(1 * time.Second)
+	if err := fillElement(ctx, d, contentElem, content, false); err != nil {
+		return errors.Wrap(err, "填写正文失败")
+	}
 
 	// 点击"一键排版"按钮
-	oneClickFormatButton, err := findOneClickFormatButton(pp)
+	progress.step("一键排版")
+	oneClickFormatButton, err := findOneClickFormatButton(ctx, d)
 	if err != nil {
 		return errors.Wrap(err, "找不到一键排版按钮")
 	}
-	oneClickFormatButton.MustClick()
-	time.Sleep(2 * time.Second)
+	if err := oneClickFormatButton.Click(ctx); err != nil {
+		return errors.Wrap(err, "点击一键排版按钮失败")
+	}
+	if err := d.Sleep(ctx, 2*time.Second); err != nil {
+		return err
+	}
 
 	// 点击"下一步"按钮
-	nextStepButton, err := findNextStepButton(pp)
+	progress.step("下一步")
+	nextStepButton, err := findNextStepButton(ctx, d)
 	if err != nil {
 		return errors.Wrap(err, "找不到下一步按钮")
 	}
-	nextStepButton.MustClick()
-	time.Sleep(3 * time.Second)
+	if err := nextStepButton.Click(ctx); err != nil {
+		return errors.Wrap(err, "点击下一步按钮失败")
+	}
+	if err := d.Sleep(ctx, 3*time.Second); err != nil {
+		return err
+	}
 
 	// 等待确认页面加载
-	time.Sleep(5 * time.Second)
+	if err := d.Sleep(ctx, 5*time.Second); err != nil {
+		return err
+	}
 
 	// 在确认页面重新填写标题和内容
-	if err := fillConfirmationPage(pp, title, content); err != nil {
+	progress.step("填写确认页面")
+	if err := fillConfirmationPage(ctx, d, title, content); err != nil {
 		return errors.Wrap(err, "填写确认页面失败")
 	}
 
 	// 设置可见范围为仅自己可见
-	if err := setVisibilityToPrivate(pp); err != nil {
+	progress.step("设置可见范围")
+	if err := setVisibilityToPrivate(ctx, d); err != nil {
 		return errors.Wrap(err, "设置可见范围失败")
 	}
 
 	// 点击发布按钮
-	publishButton, err := findPublishButton(pp)
+	progress.step("提交发布")
+	publishButton, err := findPublishButton(ctx, d)
 	if err != nil {
 		return errors.Wrap(err, "找不到发布按钮")
 	}
-	publishButton.MustClick()
-	time.Sleep(3 * time.Second)
+	markSubmitted(ctx)
+	if err := publishButton.Click(ctx); err != nil {
+		return errors.Wrap(err, "点击发布按钮失败")
+	}
 
-	return nil
+	return waitPublishResult(ctx, d)
+}
+
+// fillElement 点击元素后输入文本，selectAll 为 true 时先全选以替换原有内容
+func fillElement(ctx context.Context, d Driver, el Element, text string, selectAll bool) error {
+	if err := el.Click(ctx); err != nil {
+		return err
+	}
+	if err := d.Sleep(ctx, 500*time.Millisecond); err != nil {
+		return err
+	}
+	if selectAll {
+		if err := el.SelectAllText(ctx); err != nil {
+			return err
+		}
+	}
+	if err := el.Input(ctx, text); err != nil {
+		return err
+	}
+	return d.Sleep(ctx, 1*time.Second)
 }
 
 // fillConfirmationPage 在确认页面填写标题和内容
-func fillConfirmationPage(page *rod.Page, title, content string) error {
+func fillConfirmationPage(ctx context.Context, d Driver, title, content string) error {
 	// 填写确认页面的标题
-	confirmTitleElem, err := findConfirmationTitleElement(page)
+	confirmTitleElem, err := findConfirmationTitleElement(ctx, d)
 	if err != nil {
 		return errors.Wrap(err, "找不到确认页面标题输入框")
 	}
-
-	confirmTitleElem.MustClick()
-	time.Sleep(500 * time.Millisecond)
-	confirmTitleElem.MustSelectAllText()
-	confirmTitleElem.MustInput(title)
-	time.Sleep(1 * time.Second)
+	if err := fillElement(ctx, d, confirmTitleElem, title, true); err != nil {
+		return errors.Wrap(err, "填写确认页面标题失败")
+	}
 
 	// 填写确认页面的内容
-	confirmContentElem, err := findConfirmationContentElement(page)
+	confirmContentElem, err := findConfirmationContentElement(ctx, d)
 	if err != nil {
 		return errors.Wrap(err, "找不到确认页面内容输入区域")
 	}
-
-	confirmContentElem.MustClick()
-	time.Sleep(500 * time.Millisecond)
-	// ProseMirror编辑器不支持MustSelectAllText，直接输入内容
-	confirmContentElem.MustInput(content)
-	time.Sleep(1 * time.Second)
+	// ProseMirror编辑器不支持全选，直接输入内容
+	if err := fillElement(ctx, d, confirmContentElem, content, false); err != nil {
+		return errors.Wrap(err, "填写确认页面内容失败")
+	}
 
 	return nil
 }
 
 // setVisibilityToPrivate 设置可见范围为仅自己可见
-func setVisibilityToPrivate(page *rod.Page) error {
+func setVisibilityToPrivate(ctx context.Context, d Driver) error {
 	// 等待页面完全加载
-	time.Sleep(1 * time.Second)
+	if err := d.Sleep(ctx, 1*time.Second); err != nil {
+		return err
+	}
 
-    // 滚动到页面底部，确保设置区域可见
-    page.MustEval("() => window.scrollTo(0, document.body.scrollHeight)")
-    time.Sleep(1 * time.Second)
+	// 滚动到页面底部，确保设置区域可见
+	if _, err := d.Eval(ctx, scrollToBottomScript); err != nil {
+		return err
+	}
+	if err := d.Sleep(ctx, 1*time.Second); err != nil {
+		return err
+	}
 
 	// 查找可见范围选择器
-	visibilitySelector, err := findVisibilitySelector(page)
+	visibilitySelector, err := findVisibilitySelector(ctx, d)
 	if err != nil {
 		return errors.Wrap(err, "找不到可见范围选择器")
 	}
 
-    // 点击选择器展开下拉菜单
-    visibilitySelector.MustScrollIntoView()
-    visibilitySelector.MustClick()
-    // 等待弹层出现（如果存在下拉/弹层）
-    page.Timeout(3 * time.Second).Element("div.d-popover.d-dropdown, [role='listbox'], div[class*='popover'][class*='dropdown']")
+	// 点击选择器展开下拉菜单
+	if err := visibilitySelector.ScrollIntoView(ctx); err != nil {
+		return err
+	}
+	if err := visibilitySelector.Click(ctx); err != nil {
+		return errors.Wrap(err, "点击可见范围选择器失败")
+	}
+	// 等待弹层出现（如果存在下拉/弹层）
+	_, _ = waitElement(ctx, d, 3*time.Second, func() (Element, error) {
+		return trySelector(ctx, d, "longtext.visibility_overlay")
+	})
 
 	// 查找并点击"仅自己可见"选项
-	privateOption, err := findPrivateVisibilityOption(page)
+	privateOption, err := findPrivateVisibilityOption(ctx, d)
 	if err != nil {
 		return errors.Wrap(err, "找不到仅自己可见选项")
 	}
 
-    privateOption.MustClick()
-    if _, err := page.Timeout(4 * time.Second).
-        ElementR("div.d-select-content, div.d-text, div.d-select, div.d-select-wrapper", "仅自己可见|仅自己|仅我可见|私密"); err != nil {
-        return errors.Wrap(err, "可见范围未切换到仅自己")
-    }
-    return nil
+	if err := privateOption.Click(ctx); err != nil {
+		return errors.Wrap(err, "点击仅自己可见选项失败")
+	}
+	if _, err := waitElement(ctx, d, 4*time.Second, func() (Element, error) {
+		return trySelector(ctx, d, "longtext.visibility_private_value")
+	}); err != nil {
+		return errors.Wrap(err, "可见范围未切换到仅自己")
+	}
+	return nil
 }
 
 // findLongTextTitleElement 查找长文标题输入框
-func findLongTextTitleElement(page *rod.Page) (*rod.Element, error) {
-	time.Sleep(1 * time.Second)
+func findLongTextTitleElement(ctx context.Context, d Driver) (Element, error) {
+	if err := d.Sleep(ctx, 1*time.Second); err != nil {
+		return nil, err
+	}
 
 	// 查找包含"输入标题"文本的元素
-	titleElements := page.MustElements("div, span, input, textarea")
+	titleElements, err := selectorElements(ctx, d, "longtext.title_label")
+	if err != nil {
+		return nil, err
+	}
 	for _, elem := range titleElements {
-		text, _ := elem.Text()
-		if strings.Contains(text, "输入标题") {
-			// 检查这个元素本身是否可编辑
-			contentEditable, _ := elem.Attribute("contenteditable")
-			if contentEditable != nil && *contentEditable == "true" {
-				return elem, nil
-			}
+		// 检查这个元素本身是否可编辑
+		contentEditable, _ := elem.Attribute(ctx, "contenteditable")
+		if contentEditable != nil && *contentEditable == "true" {
+			return elem, nil
+		}
 
-			// 查找父元素中的可编辑元素
-			parent, err := elem.Parent()
-			if err == nil {
-				editableChildren := parent.MustElements("[contenteditable='true']")
-				if len(editableChildren) > 0 {
-					return editableChildren[0], nil
-				}
+		// 查找父元素中的可编辑元素
+		parent, err := elem.Parent(ctx)
+		if err == nil {
+			if editable, err := trySelector(ctx, parent, "longtext.title_editable"); err == nil {
+				return editable, nil
 			}
 		}
 	}
 
 	// 降级策略：使用第一个可编辑元素作为标题输入框
-	editableDivs := page.MustElements("div[contenteditable='true'], input[type='text'], textarea")
+	editableDivs, err := selectorElements(ctx, d, "longtext.title_fallback")
+	if err != nil {
+		return nil, err
+	}
 	if len(editableDivs) > 0 {
 		return editableDivs[0], nil
 	}
@@ -442,18 +527,19 @@ func findLongTextTitleElement(page *rod.Page) (*rod.Element, error) {
 }
 
 // findLongTextContentElement 查找长文内容输入区域
-func findLongTextContentElement(page *rod.Page) (*rod.Element, error) {
-	time.Sleep(1 * time.Second)
+func findLongTextContentElement(ctx context.Context, d Driver) (Element, error) {
+	if err := d.Sleep(ctx, 1*time.Second); err != nil {
+		return nil, err
+	}
 
 	// 查找TipTap富文本编辑器
-	editableDivs := page.MustElements("div[contenteditable='true']")
+	editableDivs, err := selectorElements(ctx, d, "longtext.content_editor")
+	if err != nil {
+		return nil, err
+	}
 	for _, div := range editableDivs {
-		className, _ := div.Attribute("class")
-		if className != nil {
-			classStr := *className
-			if strings.Contains(classStr, "ProseMirror") || strings.Contains(classStr, "tiptap") {
-				return div, nil
-			}
+		if isRichTextEditor(ctx, div) {
+			return div, nil
 		}
 	}
 
@@ -465,74 +551,55 @@ func findLongTextContentElement(page *rod.Page) (*rod.Element, error) {
 	return nil, errors.New("找不到内容输入区域")
 }
 
+// isRichTextEditor 元素是否为 ProseMirror/TipTap 富文本编辑器
+func isRichTextEditor(ctx context.Context, el Element) bool {
+	className, _ := el.Attribute(ctx, "class")
+	return className != nil && (strings.Contains(*className, "ProseMirror") || strings.Contains(*className, "tiptap"))
+}
+
 // findOneClickFormatButton 查找一键排版按钮
-func findOneClickFormatButton(page *rod.Page) (*rod.Element, error) {
-	buttons := page.MustElements("button")
-	for _, btn := range buttons {
-		text, err := btn.Text()
-		if err != nil {
-			continue
-		}
-		if strings.Contains(text, "一键排版") {
-			return btn, nil
-		}
-	}
-	return nil, errors.New("找不到一键排版按钮")
+func findOneClickFormatButton(ctx context.Context, d Driver) (Element, error) {
+	return findSelector(ctx, d, "longtext.format_button", 10*time.Second)
 }
 
 // findNextStepButton 查找下一步按钮
-func findNextStepButton(page *rod.Page) (*rod.Element, error) {
-	buttons := page.MustElements("button")
-	for _, btn := range buttons {
-		text, err := btn.Text()
-		if err != nil {
-			continue
-		}
-		if strings.Contains(text, "下一步") {
-			return btn, nil
-		}
-	}
-	return nil, errors.New("找不到下一步按钮")
+func findNextStepButton(ctx context.Context, d Driver) (Element, error) {
+	return findSelector(ctx, d, "longtext.next_button", 10*time.Second)
 }
 
 // findPublishButton 查找发布按钮
-func findPublishButton(page *rod.Page) (*rod.Element, error) {
-	buttons := page.MustElements("button")
-	for _, btn := range buttons {
-		text, err := btn.Text()
-		if err != nil {
-			continue
-		}
-		if text == "发布" || strings.Contains(text, "发布") {
-			return btn, nil
-		}
-	}
-	return nil, errors.New("找不到发布按钮")
+func findPublishButton(ctx context.Context, d Driver) (Element, error) {
+	return findSelector(ctx, d, "longtext.publish_button", 10*time.Second)
 }
 
 // findConfirmationTitleElement 查找确认页面的标题输入框
-func findConfirmationTitleElement(page *rod.Page) (*rod.Element, error) {
-	time.Sleep(1 * time.Second)
+func findConfirmationTitleElement(ctx context.Context, d Driver) (Element, error) {
+	if err := d.Sleep(ctx, 1*time.Second); err != nil {
+		return nil, err
+	}
 
 	// 查找所有输入框元素
-	allElements := page.MustElements("input, textarea, [contenteditable='true']")
+	allElements, err := selectorElements(ctx, d, "longtext.confirm_title_input")
+	if err != nil {
+		return nil, err
+	}
 	for _, elem := range allElements {
 		// 检查是否是标题相关的输入框
-		placeholder, _ := elem.Attribute("placeholder")
+		placeholder, _ := elem.Attribute(ctx, "placeholder")
 		if placeholder != nil && strings.Contains(*placeholder, "标题") {
 			return elem, nil
 		}
 
 		// 检查aria-label
-		ariaLabel, _ := elem.Attribute("aria-label")
+		ariaLabel, _ := elem.Attribute(ctx, "aria-label")
 		if ariaLabel != nil && strings.Contains(*ariaLabel, "标题") {
 			return elem, nil
 		}
 
 		// 检查父元素的文本内容
-		parent, err := elem.Parent()
+		parent, err := elem.Parent(ctx)
 		if err == nil {
-			parentText, _ := parent.Text()
+			parentText, _ := parent.Text(ctx)
 			if strings.Contains(parentText, "标题") || strings.Contains(parentText, "输入标题") {
 				return elem, nil
 			}
@@ -548,32 +615,36 @@ func findConfirmationTitleElement(page *rod.Page) (*rod.Element, error) {
 }
 
 // findConfirmationContentElement 查找确认页面的内容输入区域
-func findConfirmationContentElement(page *rod.Page) (*rod.Element, error) {
-	time.Sleep(1 * time.Second)
+func findConfirmationContentElement(ctx context.Context, d Driver) (Element, error) {
+	if err := d.Sleep(ctx, 1*time.Second); err != nil {
+		return nil, err
+	}
 
 	// 首先查找富文本编辑器
-	editableDivs := page.MustElements("div[contenteditable='true']")
+	editableDivs, err := selectorElements(ctx, d, "longtext.confirm_content_editor")
+	if err != nil {
+		return nil, err
+	}
 	for _, div := range editableDivs {
-		className, _ := div.Attribute("class")
-		if className != nil {
-			classStr := *className
-			// 查找ProseMirror或tiptap编辑器
-			if strings.Contains(classStr, "ProseMirror") || strings.Contains(classStr, "tiptap") {
-				return div, nil
-			}
+		// 查找ProseMirror或tiptap编辑器
+		if isRichTextEditor(ctx, div) {
+			return div, nil
 		}
 
 		// 检查是否包含内容相关的属性
-		ariaLabel, _ := div.Attribute("aria-label")
+		ariaLabel, _ := div.Attribute(ctx, "aria-label")
 		if ariaLabel != nil && (strings.Contains(*ariaLabel, "内容") || strings.Contains(*ariaLabel, "正文")) {
 			return div, nil
 		}
 	}
 
 	// 查找textarea元素
-	textareas := page.MustElements("textarea")
+	textareas, err := selectorElements(ctx, d, "longtext.confirm_content_textarea")
+	if err != nil {
+		return nil, err
+	}
 	for _, textarea := range textareas {
-		placeholder, _ := textarea.Attribute("placeholder")
+		placeholder, _ := textarea.Attribute(ctx, "placeholder")
 		if placeholder != nil && (strings.Contains(*placeholder, "内容") || strings.Contains(*placeholder, "正文")) {
 			return textarea, nil
 		}
@@ -587,93 +658,91 @@ func findConfirmationContentElement(page *rod.Page) (*rod.Element, error) {
 	return nil, errors.New("找不到确认页面内容输入区域")
 }
 
-func findVisibilitySelector(page *rod.Page) (*rod.Element, error) {
-    // 保证设置区域可见：滚动页面与常见内嵌容器到底部
-    page.MustEval("() => window.scrollTo(0, document.body.scrollHeight)")
-    page.MustWaitIdle()
-    // 同时尝试把内嵌可滚动容器拉到底（如 microapp 容器）
-    page.MustEval("() => { const el = document.querySelector('.microapp-container, #creator-publish-dom, .p-container'); if (el) { el.scrollTop = el.scrollHeight } }")
-    page.MustWaitIdle()
-
-    // 试探性滚动后，直接尝试命中触发器
-
-    // 直接寻找可交互的“可见范围/谁可以看/公开可见”控件
-    if ctl, err := page.Timeout(3 * time.Second).
-        ElementR("button,[role='button'],div[role='combobox'],input[role='combobox']", "可见范围|公开可见|谁可以看|谁可见"); err == nil {
-        ctl.MustScrollIntoView()
-        return ctl, nil
-    }
-    // 针对 d-select 组件：匹配当前值为“公开可见/仅自己可见”等的选择器，并提升到可点击容器
-    if val, err := page.Timeout(3 * time.Second).
-        ElementR("div.d-select-content, div.d-text, div.d-select, div.d-select-wrapper, div.d-grid.d-select-main", "公开可见|仅自己可见|仅自己|谁可以看|谁可见"); err == nil {
-        cur := val
-        for i := 0; i < 5; i++ {
-            if host, err := cur.Element("div.d-select, div.d-select-wrapper, div.d-grid.d-select-main"); err == nil {
-                host.MustScrollIntoView()
-                return host, nil
-            }
-            if p, err := cur.Parent(); err == nil {
-                cur = p
-            } else {
-                break
-            }
-        }
-        val.MustScrollIntoView()
-        return val, nil
-    }
-
-    // 兜底：命中文案标签后向上寻找触发器
-    candidates := page.MustElements("div,span,label")
-    for _, c := range candidates {
-        t, _ := c.Text()
-        if !(strings.Contains(t, "可见范围") || strings.Contains(t, "公开可见") || strings.Contains(t, "谁可以看") || strings.Contains(t, "谁可见")) {
-            continue
-        }
-        cur := c
-        for i := 0; i < 5; i++ {
-            if trigger, err := cur.ElementR("button,[role='button'],[aria-haspopup='listbox'],div[role='combobox'],input[role='combobox']", "可见范围|公开|仅自己|谁可以看|谁可见"); err == nil {
-                return trigger, nil
-            }
-            if p, err := cur.Parent(); err == nil {
-                cur = p
-            } else {
-                break
-            }
-        }
-    }
-    return nil, errors.New("找不到可见范围选择器")
+func findVisibilitySelector(ctx context.Context, d Driver) (Element, error) {
+	// 保证设置区域可见：滚动页面与常见内嵌容器到底部
+	for _, script := range []string{scrollToBottomScript, scrollContainerToBottomScript} {
+		if _, err := d.Eval(ctx, script); err != nil {
+			return nil, err
+		}
+		if err := d.WaitIdle(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	// 直接寻找可交互的“可见范围/谁可以看/公开可见”控件
+	if ctl, err := waitElement(ctx, d, 3*time.Second, func() (Element, error) {
+		return trySelector(ctx, d, "longtext.visibility_control")
+	}); err == nil {
+		return ctl, ctl.ScrollIntoView(ctx)
+	}
+	// 针对 d-select 组件：匹配当前值为“公开可见/仅自己可见”等的选择器，并提升到可点击容器
+	if val, err := waitElement(ctx, d, 3*time.Second, func() (Element, error) {
+		return trySelector(ctx, d, "longtext.visibility_value")
+	}); err == nil {
+		cur := val
+		for i := 0; i < 5; i++ {
+			if host, err := trySelector(ctx, cur, "longtext.visibility_select"); err == nil {
+				return host, host.ScrollIntoView(ctx)
+			}
+			if p, err := cur.Parent(ctx); err == nil {
+				cur = p
+			} else {
+				break
+			}
+		}
+		return val, val.ScrollIntoView(ctx)
+	}
+
+	// 兜底：命中文案标签后向上寻找触发器
+	candidates, err := selectorElements(ctx, d, "longtext.visibility_label")
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range candidates {
+		cur := c
+		for i := 0; i < 5; i++ {
+			if trigger, err := trySelector(ctx, cur, "longtext.visibility_trigger"); err == nil {
+				return trigger, nil
+			}
+			if p, err := cur.Parent(ctx); err == nil {
+				cur = p
+			} else {
+				break
+			}
+		}
+	}
+	return nil, errors.New("找不到可见范围选择器")
 }
 
 // findPrivateVisibilityOption 查找"仅自己可见"选项
-func findPrivateVisibilityOption(page *rod.Page) (*rod.Element, error) {
-    // 等待下拉/弹层完全展开
-    page.MustWaitIdle()
-    time.Sleep(200 * time.Millisecond)
-
-    // 兼容多种文案
-    pattern := "仅自己可见|仅自己|仅我可见|私密"
-
-    // 优先在下拉/弹层容器内查找真实选项节点（限制为可点击项，避免匹配容器）
-    if overlay, err := page.Timeout(3 * time.Second).
-        Element("div.d-popover.d-dropdown, [role='listbox'], div[class*='popover'][class*='dropdown']"); err == nil {
-        if item, err := overlay.ElementR("li,[role='option'],.d-dropdown-item,.ant-select-item-option,button,a,[aria-selected],div.d-grid-item,div.name,div.custom-option", pattern); err == nil {
-            return item, nil
-        }
-        // 回退：遍历候选并按文本匹配
-        opts := overlay.MustElements("li,[role='option'],.d-dropdown-item,.ant-select-item-option,button,a,[aria-selected],div.d-grid-item,div.name,div.custom-option")
-        for _, o := range opts {
-            t, _ := o.Text()
-            if strings.Contains(t, "仅自己可见") || strings.Contains(t, "仅自己") || strings.Contains(t, "仅我可见") || strings.Contains(t, "私密") {
-                return o, nil
-            }
-        }
-    }
-
-    // 回退：全局查找典型可点击选项节点
-    if el, err := page.Timeout(5 * time.Second).
-        ElementR("li,[role='option'],.d-dropdown-item,.ant-select-item-option,button,a,[aria-selected],div.d-grid-item,div.name,div.custom-option", pattern); err == nil {
-        return el, nil
-    }
-
-    return nil, errors.New("找不到仅自己可见选项")
+func findPrivateVisibilityOption(ctx context.Context, d Driver) (Element, error) {
+	// 等待下拉/弹层完全展开
+	if err := d.WaitIdle(ctx); err != nil {
+		return nil, err
+	}
+	if err := d.Sleep(ctx, 200*time.Millisecond); err != nil {
+		return nil, err
+	}
+
+	// 优先在下拉/弹层容器内查找真实选项节点（限制为可点击项，避免匹配容器）
+	if overlay, err := waitElement(ctx, d, 3*time.Second, func() (Element, error) {
+		return trySelector(ctx, d, "longtext.visibility_overlay")
+	}); err == nil {
+		if item, err := trySelector(ctx, overlay, "longtext.visibility_private_option"); err == nil {
+			return item, nil
+		}
+		// 回退：遍历候选并按文本匹配
+		if opts, _ := selectorElements(ctx, overlay, "longtext.visibility_private_option"); len(opts) > 0 {
+			return opts[0], nil
+		}
+	}
+
+	// 回退：全局查找典型可点击选项节点
+	if el, err := waitElement(ctx, d, 5*time.Second, func() (Element, error) {
+		return trySelector(ctx, d, "longtext.visibility_private_option")
+	}); err == nil {
+		return el, nil
+	}
+
+	return nil, errors.New("找不到仅自己可见选项")
 }
@@ -45,7 +45,7 @@ func TestPublishLongTextWithDetailedVerification(t *testing.T) {
 
 	// 步骤1-3: NewPublishLongTextAction 包含了导航、点击写长文、点击新的创作
 	slog.Info("开始执行步骤1-3: 创建长文发布Action")
-	action, err := NewPublishLongTextAction(page)
+	action, err := NewPublishLongTextAction(context.Background(), NewRodDriver(page))
 	if err != nil {
 		results = append(results, TestResult{
 			Step: 1, Name: "创建长文发布Action(步骤1-3)", Success: false,
@@ -71,13 +71,14 @@ func TestPublishLongTextWithDetailedVerification(t *testing.T) {
 func executeDetailedPublishFlow(t *testing.T, action *PublishAction, title, content string) []TestResult {
 	var results []TestResult
 
-	page := action.page
+	ctx := context.Background()
+	d := action.driver
 
 	fmt.Println("\n【执行发布流程各步骤验证】")
 
 	// 步骤4: 填写标题
 	slog.Info("执行步骤4: 查找并填写标题")
-	titleElem, err := findLongTextTitleElement(page)
+	titleElem, err := findLongTextTitleElement(ctx, d)
 	if err != nil {
 		results = append(results, TestResult{
 			Step: 4, Name: "查找标题输入框", Success: false,
@@ -90,10 +91,10 @@ func executeDetailedPublishFlow(t *testing.T, action *PublishAction, title, cont
 		})
 
 		// 填写标题
-		titleElem.MustClick()
+		require.NoError(t, titleElem.Click(ctx))
 		time.Sleep(500 * time.Millisecond)
-		titleElem.MustSelectAllText()
-		titleElem.MustInput(title)
+		require.NoError(t, titleElem.SelectAllText(ctx))
+		require.NoError(t, titleElem.Input(ctx, title))
 		time.Sleep(1 * time.Second)
 
 		results = append(results, TestResult{
@@ -105,7 +106,7 @@ func executeDetailedPublishFlow(t *testing.T, action *PublishAction, title, cont
 
 	// 步骤5: 填写内容
 	slog.Info("执行步骤5: 查找并填写内容")
-	contentElem, err := findLongTextContentElement(page)
+	contentElem, err := findLongTextContentElement(ctx, d)
 	if err != nil {
 		results = append(results, TestResult{
 			Step: 5, Name: "查找内容输入区域", Success: false,
@@ -118,9 +119,9 @@ func executeDetailedPublishFlow(t *testing.T, action *PublishAction, title, cont
 		})
 
 		// 填写内容
-		contentElem.MustClick()
+		require.NoError(t, contentElem.Click(ctx))
 		time.Sleep(500 * time.Millisecond)
-		contentElem.MustInput(content)
+		require.NoError(t, contentElem.Input(ctx, content))
 		time.Sleep(1 * time.Second)
 
 		results = append(results, TestResult{
@@ -132,7 +133,7 @@ func executeDetailedPublishFlow(t *testing.T, action *PublishAction, title, cont
 
 	// 步骤6: 点击"一键排版"按钮
 	slog.Info("执行步骤6: 查找并点击一键排版按钮")
-	formatButton, err := findOneClickFormatButton(page)
+	formatButton, err := findOneClickFormatButton(ctx, d)
 	if err != nil {
 		results = append(results, TestResult{
 			Step: 6, Name: "查找一键排版按钮", Success: false,
@@ -144,7 +145,7 @@ func executeDetailedPublishFlow(t *testing.T, action *PublishAction, title, cont
 			Details: "✅ findOneClickFormatButton() 找到一键排版按钮",
 		})
 
-		formatButton.MustClick()
+		require.NoError(t, formatButton.Click(ctx))
 		time.Sleep(2 * time.Second)
 
 		results = append(results, TestResult{
@@ -156,7 +157,7 @@ func executeDetailedPublishFlow(t *testing.T, action *PublishAction, title, cont
 
 	// 步骤7: 点击"下一步"按钮
 	slog.Info("执行步骤7: 查找并点击下一步按钮")
-	nextButton, err := findNextStepButton(page)
+	nextButton, err := findNextStepButton(ctx, d)
 	if err != nil {
 		results = append(results, TestResult{
 			Step: 7, Name: "查找下一步按钮", Success: false,
@@ -168,7 +169,7 @@ func executeDetailedPublishFlow(t *testing.T, action *PublishAction, title, cont
 			Details: "✅ findNextStepButton() 找到下一步按钮",
 		})
 
-		nextButton.MustClick()
+		require.NoError(t, nextButton.Click(ctx))
 		time.Sleep(3 * time.Second)
 
 		// 等待确认页面加载
@@ -185,7 +186,7 @@ func executeDetailedPublishFlow(t *testing.T, action *PublishAction, title, cont
 
 	// 步骤8: 确认页面填写标题
 	slog.Info("执行步骤8: 查找确认页面标题输入框")
-	confirmTitleElem, err := findConfirmationTitleElement(page)
+	confirmTitleElem, err := findConfirmationTitleElement(ctx, d)
 	if err != nil {
 		results = append(results, TestResult{
 			Step: 8, Name: "查找确认页面标题输入框", Success: false,
@@ -198,10 +199,10 @@ func executeDetailedPublishFlow(t *testing.T, action *PublishAction, title, cont
 		})
 
 		// 重新填写标题
-		confirmTitleElem.MustClick()
+		require.NoError(t, confirmTitleElem.Click(ctx))
 		time.Sleep(500 * time.Millisecond)
-		confirmTitleElem.MustSelectAllText()
-		confirmTitleElem.MustInput(title)
+		require.NoError(t, confirmTitleElem.SelectAllText(ctx))
+		require.NoError(t, confirmTitleElem.Input(ctx, title))
 		time.Sleep(1 * time.Second)
 
 		results = append(results, TestResult{
@@ -213,7 +214,7 @@ func executeDetailedPublishFlow(t *testing.T, action *PublishAction, title, cont
 
 	// 步骤9: 确认页面填写内容
 	slog.Info("执行步骤9: 查找确认页面内容输入区域")
-	confirmContentElem, err := findConfirmationContentElement(page)
+	confirmContentElem, err := findConfirmationContentElement(ctx, d)
 	if err != nil {
 		results = append(results, TestResult{
 			Step: 9, Name: "查找确认页面内容输入区域", Success: false,
@@ -226,10 +227,10 @@ func executeDetailedPublishFlow(t *testing.T, action *PublishAction, title, cont
 		})
 
 		// 重新填写内容
-		confirmContentElem.MustClick()
+		require.NoError(t, confirmContentElem.Click(ctx))
 		time.Sleep(500 * time.Millisecond)
-		// ProseMirror编辑器不支持MustSelectAllText，直接输入内容
-		confirmContentElem.MustInput(content)
+		// ProseMirror编辑器不支持SelectAllText，直接输入内容
+		require.NoError(t, confirmContentElem.Input(ctx, content))
 		time.Sleep(1 * time.Second)
 
 		results = append(results, TestResult{
@@ -241,7 +242,7 @@ func executeDetailedPublishFlow(t *testing.T, action *PublishAction, title, cont
 
 	// 步骤10: 设置可见范围为仅自己可见
 	slog.Info("执行步骤10: 设置可见范围")
-	err = setVisibilityToPrivate(page)
+	err = setVisibilityToPrivate(ctx, d)
 	if err != nil {
 		results = append(results, TestResult{
 			Step: 10, Name: "设置可见范围为仅自己可见", Success: false,
@@ -259,7 +260,7 @@ func executeDetailedPublishFlow(t *testing.T, action *PublishAction, title, cont
 
 	// 步骤11: 点击发布按钮
 	slog.Info("执行步骤11: 查找并点击发布按钮")
-	publishButton, err := findPublishButton(page)
+	publishButton, err := findPublishButton(ctx, d)
 	if err != nil {
 		results = append(results, TestResult{
 			Step: 11, Name: "查找发布按钮", Success: false,
@@ -271,7 +272,7 @@ func executeDetailedPublishFlow(t *testing.T, action *PublishAction, title, cont
 			Details: "✅ findPublishButton() 找到发布按钮",
 		})
 	
-		publishButton.MustClick()
+		require.NoError(t, publishButton.Click(ctx))
 		time.Sleep(3 * time.Second)
 	
 		results = append(results, TestResult{
@@ -330,7 +331,7 @@ func TestPublishLongText(t *testing.T) {
 	page := b.NewPage()
 	defer page.Close()
 
-	action, err := NewPublishLongTextAction(page)
+	action, err := NewPublishLongTextAction(context.Background(), NewRodDriver(page))
 	require.NoError(t, err)
 
 	err = action.PublishLongText(context.Background(), PublishLongTextContent{
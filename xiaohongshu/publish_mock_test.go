@@ -0,0 +1,115 @@
+package xiaohongshu
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-rod/rod/lib/launcher"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xpzouying/xiaohongshu-mcp/browser"
+	"github.com/xpzouying/xiaohongshu-mcp/pkg/mockxhs"
+)
+
+// newMockCreator 启动模拟的创作者中心并打开浏览器，发布流程指向该服务
+func newMockCreator(t *testing.T, opts mockxhs.Options) (*mockxhs.Server, Driver) {
+	t.Helper()
+	if _, ok := launcher.LookPath(); !ok {
+		t.Skip("SKIP: 没有找到浏览器")
+	}
+
+	mock := mockxhs.New(opts)
+	server := httptest.NewServer(mock)
+	t.Cleanup(server.Close)
+
+	SetCreatorBaseURL(server.URL)
+	t.Cleanup(func() { SetCreatorBaseURL("") })
+
+	b := browser.NewBrowser(true)
+	t.Cleanup(b.Close)
+	page := b.NewPage()
+	t.Cleanup(func() { page.Close() })
+
+	return mock, NewRodDriver(page)
+}
+
+func TestPublishImageWithMockServer(t *testing.T) {
+	mock, driver := newMockCreator(t, mockxhs.Options{UploadDelay: 2 * time.Second})
+
+	dir := t.TempDir()
+	var images []string
+	for _, name := range []string{"1.jpg", "2.jpg"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte("fake image"), 0644))
+		images = append(images, path)
+	}
+
+	ctx := context.Background()
+	action, err := NewPublishImageAction(ctx, driver)
+	require.NoError(t, err)
+	require.NoError(t, action.Publish(ctx, PublishImageContent{
+		Title:      "模拟发布",
+		Content:    "模拟发布的正文",
+		ImagePaths: images,
+	}))
+
+	subs := mock.Submissions()
+	require.Len(t, subs, 1)
+	assert.Equal(t, mockxhs.KindImage, subs[0].Kind)
+	assert.Equal(t, "模拟发布", subs[0].Title)
+	assert.Equal(t, "模拟发布的正文", subs[0].Content)
+	assert.Equal(t, []string{"1.jpg", "2.jpg"}, subs[0].Files)
+}
+
+func TestPublishLongTextWithMockServer(t *testing.T) {
+	mock, driver := newMockCreator(t, mockxhs.Options{})
+
+	ctx := context.Background()
+	action, err := NewPublishLongTextAction(ctx, driver)
+	require.NoError(t, err)
+	require.NoError(t, action.PublishLongText(ctx, PublishLongTextContent{
+		Title:   "模拟长文",
+		Content: "模拟长文的正文",
+	}))
+
+	subs := mock.Submissions()
+	require.Len(t, subs, 1)
+	assert.Equal(t, mockxhs.KindLongText, subs[0].Kind)
+	assert.Equal(t, "模拟长文", subs[0].Title)
+	assert.Equal(t, "模拟长文的正文", subs[0].Content)
+	assert.Equal(t, "仅自己可见", subs[0].Visibility)
+}
+
+func TestPublishRejectedWithMockServer(t *testing.T) {
+	mock, driver := newMockCreator(t, mockxhs.Options{Reject: "内容违规"})
+
+	image := filepath.Join(t.TempDir(), "1.jpg")
+	require.NoError(t, os.WriteFile(image, []byte("fake image"), 0644))
+
+	ctx := context.Background()
+	action, err := NewPublishImageAction(ctx, driver)
+	require.NoError(t, err)
+	err = action.Publish(ctx, PublishImageContent{Title: "模拟发布", Content: "正文", ImagePaths: []string{image}})
+
+	var rejectedErr *PublishRejectedError
+	require.True(t, errors.As(err, &rejectedErr), "err: %v", err)
+	assert.Equal(t, "内容违规", rejectedErr.Message)
+
+	subs := mock.Submissions()
+	require.Len(t, subs, 1)
+	assert.True(t, subs[0].Rejected)
+}
+
+func TestPublishCaptchaWithMockServer(t *testing.T) {
+	mock, driver := newMockCreator(t, mockxhs.Options{Captcha: true})
+
+	_, err := NewPublishImageAction(context.Background(), driver)
+	var riskErr *RiskControlError
+	require.True(t, errors.As(err, &riskErr), "err: %v", err)
+	assert.Empty(t, mock.Submissions())
+}
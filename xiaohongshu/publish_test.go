@@ -2,7 +2,10 @@ package xiaohongshu
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/xpzouying/xiaohongshu-mcp/browser"
 
@@ -20,7 +23,7 @@ func TestPublish(t *testing.T) {
 	page := b.NewPage()
 	defer page.Close()
 
-	action, err := NewPublishImageAction(page)
+	action, err := NewPublishImageAction(context.Background(), NewRodDriver(page))
 	require.NoError(t, err)
 
 	err = action.Publish(context.Background(), PublishImageContent{
@@ -30,3 +33,189 @@ func TestPublish(t *testing.T) {
 	})
 	assert.NoError(t, err)
 }
+
+func TestPublishImageWithFakeDriver(t *testing.T) {
+	uploadInput := &FakeElement{Selectors: []string{".upload-input"}}
+	// 标题输入框只能通过 ARIA 角色的备选规则找到
+	titleInput := &FakeElement{Role: "textbox", Attrs: map[string]string{"placeholder": "填写标题会有更多赞哦"}}
+	contentEditor := &FakeElement{Selectors: []string{"div.ql-editor"}}
+	submitButton := &FakeElement{Selectors: []string{"div.submit div.d-button-content"}, InnerText: "发布"}
+
+	publishPage := &FakePage{Elements: []*FakeElement{
+		{Selectors: []string{"div.upload-content"}},
+		{Selectors: []string{"div.creator-tab"}, InnerText: "上传视频"},
+		{Selectors: []string{"div.creator-tab"}, InnerText: "上传图文", OnClick: func(page *FakePage) {
+			page.Elements = append(page.Elements, uploadInput)
+		}},
+	}}
+	driver := NewFakeDriver(map[string]*FakePage{publishURL(): publishPage})
+
+	ctx := context.Background()
+	action, err := NewPublishImageAction(ctx, driver)
+	require.NoError(t, err)
+
+	// 上传完成后出现预览图和编辑区域
+	publishPage.Elements = append(publishPage.Elements,
+		&FakeElement{Selectors: []string{".img-preview-area .pr"}},
+		&FakeElement{Selectors: []string{".img-preview-area .pr"}},
+		titleInput, contentEditor, submitButton,
+	)
+
+	err = action.Publish(ctx, PublishImageContent{
+		Title:      "Hello World",
+		Content:    "正文 #穿搭",
+		ImagePaths: []string{"/tmp/1.jpg", "/tmp/2.jpg"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"/tmp/1.jpg", "/tmp/2.jpg"}, uploadInput.Files)
+	assert.Equal(t, "Hello World", titleInput.Value)
+	assert.Equal(t, "正文 #穿搭", contentEditor.Value)
+	assert.Equal(t, 1, submitButton.Clicks)
+	assert.Equal(t, []string{publishURL()}, driver.Visited())
+}
+
+func TestPublishRejectedWithFakeDriver(t *testing.T) {
+	newPage := func(toast string) *FakePage {
+		page := &FakePage{}
+		page.Elements = []*FakeElement{
+			{Selectors: []string{"div.d-input input"}},
+			{Selectors: []string{"div.ql-editor"}},
+			{Selectors: []string{"div.submit div.d-button-content"}, InnerText: "发布", OnClick: func(page *FakePage) {
+				page.Elements = append(page.Elements, &FakeElement{Selectors: []string{".d-toast"}, InnerText: toast})
+			}},
+		}
+		return page
+	}
+
+	ctx := context.Background()
+	progress := newStepProgress(ctx, 0, 3)
+
+	driver := NewFakeDriver(map[string]*FakePage{publishURL(): newPage("发布成功")})
+	require.NoError(t, driver.Navigate(ctx, publishURL()))
+	require.NoError(t, submitPublish(ctx, driver, "标题", "正文", progress))
+
+	driver = NewFakeDriver(map[string]*FakePage{publishURL(): newPage("内容违规")})
+	require.NoError(t, driver.Navigate(ctx, publishURL()))
+	err := submitPublish(ctx, driver, "标题", "正文", progress)
+	var rejectedErr *PublishRejectedError
+	require.ErrorAs(t, err, &rejectedErr)
+	assert.Equal(t, "内容违规", rejectedErr.Message)
+}
+
+func TestPublishSubmitTracker(t *testing.T) {
+	page := &FakePage{Elements: []*FakeElement{
+		{Selectors: []string{"div.d-input input"}},
+		{Selectors: []string{"div.ql-editor"}},
+	}}
+	driver := NewFakeDriver(map[string]*FakePage{publishURL(): page})
+	ctx := context.Background()
+	require.NoError(t, driver.Navigate(ctx, publishURL()))
+
+	// 找不到发布按钮时还没有提交
+	tracker := &SubmitTracker{}
+	trackedCtx := WithSubmitTracker(ctx, tracker)
+	require.Error(t, submitPublish(trackedCtx, driver, "标题", "正文", newStepProgress(trackedCtx, 0, 3)))
+	assert.False(t, tracker.Submitted())
+
+	// 点击发布按钮后即使没有等到结果也记为已提交
+	page.Elements = append(page.Elements, &FakeElement{Selectors: []string{"div.submit div.d-button-content"}, InnerText: "发布"})
+	require.NoError(t, submitPublish(trackedCtx, driver, "标题", "正文", newStepProgress(trackedCtx, 0, 3)))
+	assert.True(t, tracker.Submitted())
+}
+
+func TestPublishImageUploadInputMissing(t *testing.T) {
+	driver := NewFakeDriver(map[string]*FakePage{publishURL(): {Elements: []*FakeElement{
+		{Selectors: []string{"div.upload-content"}},
+	}}})
+	driver.MaxWait = time.Minute
+
+	ctx := context.Background()
+	action, err := NewPublishImageAction(ctx, driver)
+	require.NoError(t, err)
+
+	err = action.Publish(ctx, PublishImageContent{Title: "t", Content: "c", ImagePaths: []string{"/tmp/1.jpg"}})
+	assert.ErrorContains(t, err, "publish.upload_input")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestPublishLongTextWithFakeDriver(t *testing.T) {
+	title := &FakeElement{Selectors: []string{"div, span, input, textarea"}, InnerText: "输入标题",
+		Attrs: map[string]string{"contenteditable": "true"}}
+	editor := &FakeElement{Selectors: []string{"div[contenteditable='true']"},
+		Attrs: map[string]string{"class": "tiptap ProseMirror"}}
+	formatButton := &FakeElement{Selectors: []string{"button"}, InnerText: "一键排版"}
+	nextButton := &FakeElement{Selectors: []string{"button"}, InnerText: "下一步"}
+
+	confirmTitle := &FakeElement{Selectors: []string{"input, textarea, [contenteditable='true']"},
+		Attrs: map[string]string{"placeholder": "填写标题会有更多赞哦"}}
+	confirmEditor := &FakeElement{Selectors: []string{"div[contenteditable='true']"},
+		Attrs: map[string]string{"class": "ProseMirror"}}
+	visibility := &FakeElement{Selectors: []string{"div.d-select-content, div.d-text, div.d-select, div.d-select-wrapper"}, InnerText: "公开可见"}
+	privateOption := &FakeElement{Selectors: []string{"li,[role='option'],.d-dropdown-item,.ant-select-item-option,button,a,[aria-selected],div.d-grid-item,div.name,div.custom-option"}, InnerText: "仅自己可见", OnClick: func(*FakePage) {
+		visibility.InnerText = "仅自己可见"
+	}}
+	publishButton := &FakeElement{Selectors: []string{"button"}, InnerText: "发布"}
+
+	page := &FakePage{Elements: []*FakeElement{
+		{Selectors: []string{"div.upload-content"}},
+		{Selectors: []string{"div.creator-tab"}, InnerText: "写长文"},
+		{Selectors: []string{"button"}, InnerText: "新的创作"},
+	}}
+	// 点击"下一步"后进入确认页面
+	nextButton.OnClick = func(page *FakePage) {
+		page.Elements = []*FakeElement{
+			confirmTitle, confirmEditor,
+			{Selectors: []string{"button,[role='button'],div[role='combobox'],input[role='combobox']"}, InnerText: "谁可以看"},
+			{Selectors: []string{"div.d-popover.d-dropdown, [role='listbox'], div[class*='popover'][class*='dropdown']"}, Children: []*FakeElement{privateOption}},
+			visibility, publishButton,
+		}
+	}
+	driver := NewFakeDriver(map[string]*FakePage{publishURL(): page})
+
+	ctx := context.Background()
+	action, err := NewPublishLongTextAction(ctx, driver)
+	require.NoError(t, err)
+
+	page.Elements = []*FakeElement{title, editor, formatButton, nextButton}
+	err = action.PublishLongText(ctx, PublishLongTextContent{Title: "长文标题", Content: "长文正文"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "长文标题", title.Value)
+	assert.Equal(t, "长文正文", editor.Value)
+	assert.Equal(t, 1, formatButton.Clicks)
+	assert.Equal(t, "长文标题", confirmTitle.Value)
+	assert.Equal(t, "长文正文", confirmEditor.Value)
+	assert.Equal(t, 1, privateOption.Clicks)
+	assert.Equal(t, 1, publishButton.Clicks)
+}
+
+func TestVisibilityOptionSelectorOverride(t *testing.T) {
+	registry := newSelectorRegistry(mustParseSelectorFile(defaultSelectorsYAML))
+	path := filepath.Join(t.TempDir(), "selectors.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+selectors:
+  longtext.visibility_private_option:
+    candidates:
+      - css: div.visibility-item
+        text: 只给自己看
+`), 0o644))
+	require.NoError(t, registry.LoadOverrides(path))
+
+	defaults := selectors
+	selectors = registry
+	t.Cleanup(func() { selectors = defaults })
+
+	// 改版后的文案和结构只需修改选择器文件
+	option := &FakeElement{Selectors: []string{"div.visibility-item"}, InnerText: "只给自己看"}
+	driver := NewFakeDriver(map[string]*FakePage{publishURL(): {Elements: []*FakeElement{
+		{Selectors: []string{"div.visibility-item"}, InnerText: "公开可见"},
+		option,
+	}}})
+	ctx := context.Background()
+	require.NoError(t, driver.Navigate(ctx, publishURL()))
+
+	found, err := findPrivateVisibilityOption(ctx, driver)
+	require.NoError(t, err)
+	assert.Same(t, option, found)
+}
@@ -0,0 +1,277 @@
+package xiaohongshu
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ResourceKind 可以拦截的资源类型
+type ResourceKind string
+
+const (
+	ResourceImage ResourceKind = "image"
+	ResourceMedia ResourceKind = "media"
+	ResourceFont  ResourceKind = "font"
+	// ResourceAnalytics 埋点和性能监控上报，按地址匹配
+	ResourceAnalytics ResourceKind = "analytics"
+)
+
+// resourceKinds 支持的资源类型，按此顺序输出
+var resourceKinds = []ResourceKind{ResourceImage, ResourceMedia, ResourceFont, ResourceAnalytics}
+
+// analyticsURLPatterns 埋点上报的地址，格式同 CDP Fetch.RequestPattern.urlPattern
+var analyticsURLPatterns = []string{
+	"*://apm-fe.xiaohongshu.com/*",
+	"*://t2.xiaohongshu.com/*",
+	"*://*.google-analytics.com/*",
+}
+
+// ResourcePolicy 操作期间拦截的资源
+type ResourcePolicy struct {
+	Block []ResourceKind
+	// Measure 为 true 时不拦截，只统计 Block 中的资源实际加载的请求数和传输的字节数，作为估计节省流量和时间的基准
+	Measure bool
+}
+
+// Blocks 是否拦截该类型的资源
+func (p ResourcePolicy) Blocks(kind ResourceKind) bool {
+	return slices.Contains(p.Block, kind)
+}
+
+// ResourceBlock 进行中的资源拦截
+type ResourceBlock interface {
+	// Blocked 到目前为止拦截的请求数，按资源类型统计。Measure 模式下为加载完成的请求数
+	Blocked() map[ResourceKind]int
+	// Bytes Measure 模式下加载完成的请求实际传输的字节数，按资源类型统计，拦截时为空
+	Bytes() map[ResourceKind]int64
+	Stop()
+}
+
+// readOnlyActions 只读取页面数据的操作，默认拦截图片、视频、字体和埋点。
+// 发布和登录需要完整的页面，不在其中，始终不拦截
+var readOnlyActions = []string{"feeds", "search", "feed_detail", "user_profile"}
+
+var resourcePolicies = struct {
+	mu       sync.RWMutex
+	policies map[string]ResourcePolicy
+}{policies: defaultResourcePolicies()}
+
+func defaultResourcePolicies() map[string]ResourcePolicy {
+	policies := make(map[string]ResourcePolicy, len(readOnlyActions))
+	for _, action := range readOnlyActions {
+		policies[action] = ResourcePolicy{Block: slices.Clone(resourceKinds)}
+	}
+	return policies
+}
+
+// SetResourcePolicy 设置只读操作拦截的资源，空策略表示不拦截
+func SetResourcePolicy(action string, policy ResourcePolicy) error {
+	if !slices.Contains(readOnlyActions, action) {
+		return errors.Errorf("unknown read-only action %q, expected one of %s", action, strings.Join(readOnlyActions, ", "))
+	}
+	resourcePolicies.mu.Lock()
+	defer resourcePolicies.mu.Unlock()
+	resourcePolicies.policies[action] = policy
+	return nil
+}
+
+// ResourcePolicyFor 操作当前的拦截策略
+func ResourcePolicyFor(action string) ResourcePolicy {
+	resourcePolicies.mu.RLock()
+	defer resourcePolicies.mu.RUnlock()
+	return resourcePolicies.policies[action]
+}
+
+// ParseResourcePolicies 解析 -block-resources 参数，多个配置以 ; 分隔：
+// "image,font" 设置所有只读操作，"search=image,media" 只设置搜索，none 表示不拦截
+func ParseResourcePolicies(spec string) (map[string]ResourcePolicy, error) {
+	policies := make(map[string]ResourcePolicy)
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		actions := readOnlyActions
+		kinds := entry
+		if action, rest, ok := strings.Cut(entry, "="); ok {
+			action = strings.TrimSpace(action)
+			if !slices.Contains(readOnlyActions, action) {
+				return nil, errors.Errorf("unknown read-only action %q, expected one of %s", action, strings.Join(readOnlyActions, ", "))
+			}
+			actions, kinds = []string{action}, rest
+		}
+
+		policy, err := parseResourceKinds(kinds)
+		if err != nil {
+			return nil, err
+		}
+		for _, action := range actions {
+			policies[action] = policy
+		}
+	}
+	return policies, nil
+}
+
+func parseResourceKinds(s string) (ResourcePolicy, error) {
+	var policy ResourcePolicy
+	for _, name := range strings.Split(s, ",") {
+		kind := ResourceKind(strings.TrimSpace(name))
+		switch {
+		case kind == "none" || kind == "":
+		case slices.Contains(resourceKinds, kind):
+			if !policy.Blocks(kind) {
+				policy.Block = append(policy.Block, kind)
+			}
+		default:
+			return ResourcePolicy{}, errors.Errorf("unknown resource kind %q, expected one of image, media, font, analytics or none", kind)
+		}
+	}
+	return policy, nil
+}
+
+// resourceBaselineEvery 每个操作每执行这么多次，有一次不拦截资源，用来测量拦截节省的流量和时间。
+// 进程启动后每个操作的第一次执行即为基准
+var resourceBaselineEvery = 20
+
+// resourceStats 操作的资源统计：基准运行实际加载的资源和耗时，以及拦截时的耗时
+type resourceStats struct {
+	runs int
+	// baselineRuns、baselineTime 不拦截资源的基准运行次数和总耗时
+	baselineRuns int
+	baselineTime time.Duration
+	// blockedRuns、blockedTime 拦截资源的运行次数和总耗时
+	blockedRuns int
+	blockedTime time.Duration
+	// requests、bytes 基准运行中各类资源加载完成的请求数和传输的字节数
+	requests map[ResourceKind]int
+	bytes    map[ResourceKind]int64
+}
+
+var resourceStatsByAction = struct {
+	mu    sync.Mutex
+	stats map[string]*resourceStats
+}{stats: map[string]*resourceStats{}}
+
+// nextResourceRun 记录一次运行，返回这次是否为基准运行
+func nextResourceRun(action string) bool {
+	resourceStatsByAction.mu.Lock()
+	defer resourceStatsByAction.mu.Unlock()
+
+	stats, ok := resourceStatsByAction.stats[action]
+	if !ok {
+		stats = &resourceStats{requests: map[ResourceKind]int{}, bytes: map[ResourceKind]int64{}}
+		resourceStatsByAction.stats[action] = stats
+	}
+	baseline := resourceBaselineEvery > 0 && stats.runs%resourceBaselineEvery == 0
+	stats.runs++
+	return baseline
+}
+
+// recordBaseline 记录基准运行实际加载的资源和耗时
+func recordBaseline(action string, block ResourceBlock, elapsed time.Duration) {
+	resourceStatsByAction.mu.Lock()
+	defer resourceStatsByAction.mu.Unlock()
+
+	stats := resourceStatsByAction.stats[action]
+	stats.baselineRuns++
+	stats.baselineTime += elapsed
+	for kind, n := range block.Blocked() {
+		stats.requests[kind] += n
+	}
+	for kind, n := range block.Bytes() {
+		stats.bytes[kind] += n
+	}
+}
+
+// recordBlocked 记录拦截资源的运行耗时，按基准运行测得的每类资源平均大小估计这次节省的字节数，
+// 以及与基准运行平均耗时的差值。还没有基准数据的资源类型不计入，没有基准运行时 hasBaseline 为 false
+func recordBlocked(action string, blocked map[ResourceKind]int, elapsed time.Duration) (savedBytes int64, savedTime time.Duration, hasBaseline bool) {
+	resourceStatsByAction.mu.Lock()
+	defer resourceStatsByAction.mu.Unlock()
+
+	stats, ok := resourceStatsByAction.stats[action]
+	if !ok {
+		return 0, 0, false
+	}
+	stats.blockedRuns++
+	stats.blockedTime += elapsed
+	if stats.baselineRuns == 0 {
+		return 0, 0, false
+	}
+
+	for kind, n := range blocked {
+		if requests := stats.requests[kind]; requests > 0 {
+			savedBytes += int64(n) * stats.bytes[kind] / int64(requests)
+		}
+	}
+	savedTime = stats.baselineTime/time.Duration(stats.baselineRuns) - stats.blockedTime/time.Duration(stats.blockedRuns)
+	return savedBytes, savedTime, true
+}
+
+// blockResources 按操作的策略在打开页面前开始拦截资源，返回的函数在操作结束时调用，
+// 停止拦截并在调试日志中输出拦截的请求数和操作耗时。
+// 每 resourceBaselineEvery 次中有一次不拦截，测量这些资源实际的流量作为基准，
+// 之后拦截时按基准估计节省的流量（saved_bytes）和时间（saved_time）。拦截失败时照常加载
+func blockResources(ctx context.Context, d Driver, action string) func() {
+	start := time.Now()
+	policy := ResourcePolicyFor(action)
+	if len(policy.Block) == 0 {
+		return func() {}
+	}
+	policy.Measure = nextResourceRun(action)
+
+	block, err := d.BlockResources(ctx, policy)
+	if err != nil {
+		slog.WarnContext(ctx, "拦截资源失败，照常加载", "action", action, "error", err)
+		return func() {}
+	}
+
+	return func() {
+		elapsed := time.Since(start)
+		block.Stop()
+
+		blocked := block.Blocked()
+		var requests int
+		var counts []any
+		for _, kind := range resourceKinds {
+			if n := blocked[kind]; n > 0 {
+				requests += n
+				counts = append(counts, string(kind), n)
+			}
+		}
+
+		if policy.Measure {
+			recordBaseline(action, block, elapsed)
+			var bytes int64
+			for _, n := range block.Bytes() {
+				bytes += n
+			}
+			slog.DebugContext(ctx, "资源拦截基准：本次不拦截，测量资源的实际流量",
+				"action", action,
+				"requests", requests,
+				slog.Group("loaded", counts...),
+				"bytes", bytes,
+				"elapsed", elapsed,
+			)
+			return
+		}
+
+		attrs := []any{
+			"action", action,
+			"requests", requests,
+			slog.Group("blocked", counts...),
+			"elapsed", elapsed,
+		}
+		if savedBytes, savedTime, ok := recordBlocked(action, blocked, elapsed); ok {
+			attrs = append(attrs, "saved_bytes", savedBytes, "saved_time", savedTime)
+		}
+		slog.DebugContext(ctx, "拦截资源", attrs...)
+	}
+}
@@ -0,0 +1,112 @@
+package xiaohongshu
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseResourcePolicies(t *testing.T) {
+	policies, err := ParseResourcePolicies("image, font; search=image,media,analytics; user_profile=none")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]ResourcePolicy{
+		"feeds":        {Block: []ResourceKind{ResourceImage, ResourceFont}},
+		"feed_detail":  {Block: []ResourceKind{ResourceImage, ResourceFont}},
+		"search":       {Block: []ResourceKind{ResourceImage, ResourceMedia, ResourceAnalytics}},
+		"user_profile": {},
+	}, policies)
+
+	_, err = ParseResourcePolicies("publish=image")
+	assert.ErrorContains(t, err, `unknown read-only action "publish"`)
+
+	_, err = ParseResourcePolicies("css")
+	assert.ErrorContains(t, err, `unknown resource kind "css"`)
+}
+
+// resetResourceStats 清空资源统计并设置基准运行的间隔，测试结束后恢复
+func resetResourceStats(t *testing.T, baselineEvery int) {
+	t.Helper()
+	every := resourceBaselineEvery
+	resourceBaselineEvery = baselineEvery
+	resourceStatsByAction.stats = map[string]*resourceStats{}
+	t.Cleanup(func() {
+		resourceBaselineEvery = every
+		resourceStatsByAction.stats = map[string]*resourceStats{}
+	})
+}
+
+func TestReadActionsBlockResources(t *testing.T) {
+	resetResourceStats(t, 0)
+
+	newDriver := func() *FakeDriver {
+		return NewFakeDriver(map[string]*FakePage{
+			webURL("/search_result"): {
+				State:     map[string]any{"search": map[string]any{"feeds": map[string]any{"_value": []any{}}}},
+				Resources: []ResourceKind{ResourceImage, ResourceImage, ResourceFont, ResourceAnalytics},
+			},
+		})
+	}
+
+	driver := newDriver()
+	_, err := NewSearchAction(driver).Search(context.Background(), "Kimi")
+	require.NoError(t, err)
+	require.Len(t, driver.Blocks(), 1)
+	assert.Equal(t, map[ResourceKind]int{ResourceImage: 2, ResourceFont: 1, ResourceAnalytics: 1}, driver.blocks[0].Blocked())
+	assert.True(t, driver.blocks[0].stopped)
+
+	// 关闭后不再拦截
+	require.NoError(t, SetResourcePolicy("search", ResourcePolicy{}))
+	t.Cleanup(func() {
+		require.NoError(t, SetResourcePolicy("search", defaultResourcePolicies()["search"]))
+	})
+
+	driver = newDriver()
+	_, err = NewSearchAction(driver).Search(context.Background(), "Kimi")
+	require.NoError(t, err)
+	assert.Empty(t, driver.Blocks())
+}
+
+func TestResourceBaselineEstimatesSavings(t *testing.T) {
+	resetResourceStats(t, 2)
+
+	newDriver := func() *FakeDriver {
+		return NewFakeDriver(map[string]*FakePage{
+			webURL("/search_result"): {
+				State:         map[string]any{"search": map[string]any{"feeds": map[string]any{"_value": []any{}}}},
+				Resources:     []ResourceKind{ResourceImage, ResourceImage, ResourceFont},
+				ResourceSizes: map[ResourceKind]int64{ResourceImage: 1000, ResourceFont: 500},
+			},
+		})
+	}
+
+	// 第一次运行为基准，不拦截，测量资源的实际大小
+	driver := newDriver()
+	_, err := NewSearchAction(driver).Search(context.Background(), "Kimi")
+	require.NoError(t, err)
+	require.Len(t, driver.Blocks(), 1)
+	assert.True(t, driver.Blocks()[0].Measure)
+	assert.Equal(t, map[ResourceKind]int64{ResourceImage: 2000, ResourceFont: 500}, driver.blocks[0].Bytes())
+
+	// 第二次运行拦截资源
+	driver = newDriver()
+	_, err = NewSearchAction(driver).Search(context.Background(), "Kimi")
+	require.NoError(t, err)
+	require.Len(t, driver.Blocks(), 1)
+	assert.False(t, driver.Blocks()[0].Measure)
+	assert.Empty(t, driver.blocks[0].Bytes())
+
+	stats := resourceStatsByAction.stats["search"]
+	assert.Equal(t, 1, stats.baselineRuns)
+	assert.Equal(t, 1, stats.blockedRuns)
+
+	// 按基准测得的平均大小估计节省的流量
+	savedBytes, _, ok := recordBlocked("search", map[ResourceKind]int{ResourceImage: 3, ResourceFont: 1}, 0)
+	assert.True(t, ok)
+	assert.Equal(t, int64(3*1000+500), savedBytes)
+
+	// 没有基准数据的操作不估计
+	_, _, ok = recordBlocked("feeds", map[ResourceKind]int{ResourceImage: 1}, 0)
+	assert.False(t, ok)
+}
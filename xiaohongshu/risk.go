@@ -0,0 +1,132 @@
+package xiaohongshu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// 已知的风控页面特征
+var (
+	// riskControlURLPatterns 触发风控后跳转的页面
+	riskControlURLPatterns = []string{
+		"/website-login/captcha",
+		"/website-login/error",
+		"/web-login/captcha",
+		"verifyUuid=",
+	}
+	// riskControlSelectors 滑块、图形验证码弹窗
+	riskControlSelectors = []string{
+		"#red-captcha",
+		".red-captcha",
+		".captcha-container",
+		".nc-container",
+	}
+	// riskControlKeywords 验证页面上的提示文字，只在没有页面数据时检查，避免误判笔记正文
+	riskControlKeywords = []string{
+		"安全验证",
+		"请完成验证",
+		"滑块验证",
+		"访问频繁",
+		"账号存在异常",
+	}
+)
+
+// riskControlScript 检测当前页面是否为风控页面，返回原因，不是时返回空字符串
+var riskControlScript = fmt.Sprintf(`() => {
+	const urlPatterns = %s, selectors = %s, keywords = %s;
+	for (const p of urlPatterns) {
+		if (location.href.includes(p)) return "跳转到验证页面 " + p;
+	}
+	for (const s of selectors) {
+		if (document.querySelector(s)) return "出现验证码 " + s;
+	}
+	if (window.__INITIAL_STATE__ === undefined && document.body) {
+		const text = document.body.innerText.slice(0, 5000);
+		for (const k of keywords) {
+			if (text.includes(k)) return "页面提示「" + k + "」";
+		}
+	}
+	return "";
+}`, mustJSON(riskControlURLPatterns), mustJSON(riskControlSelectors), mustJSON(riskControlKeywords))
+
+func mustJSON(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+// RiskControlError 页面被小红书风控拦截（滑块验证码、安全验证等），需要人工处理
+type RiskControlError struct {
+	URL    string
+	Reason string
+}
+
+func (e *RiskControlError) Error() string {
+	return fmt.Sprintf("触发小红书风控：%s（%s）", e.Reason, e.URL)
+}
+
+// initialStateReadyScript 页面数据已加载或已跳转到风控页面
+var initialStateReadyScript = `() => window.__INITIAL_STATE__ !== undefined || (` + riskControlScript + `)() !== ""`
+
+// InitialStateScript 导出 __INITIAL_STATE__ 的脚本，页面没有该变量或无法序列化时返回空字符串。
+// 保存失败现场等直接使用 rod 页面的地方也使用这份脚本
+const InitialStateScript = `() => {
+	try {
+		return window.__INITIAL_STATE__ ? JSON.stringify(window.__INITIAL_STATE__) : "";
+	} catch (e) {
+		return "";
+	}
+}`
+
+// detectRiskControl 检查当前页面是否为风控页面，是时返回 *RiskControlError
+func detectRiskControl(ctx context.Context, d Driver) error {
+	result, err := d.Eval(ctx, riskControlScript)
+	if err != nil {
+		// 页面正在跳转等情况下执行失败，交给后续的等待处理
+		return nil
+	}
+
+	if reason := result.Str(); reason != "" {
+		url, _ := d.URL(ctx)
+		return &RiskControlError{URL: url, Reason: reason}
+	}
+	return nil
+}
+
+// navigate 打开页面并等待加载完成，被风控拦截时返回 *RiskControlError
+func navigate(ctx context.Context, d Driver, url string) error {
+	if err := d.Navigate(ctx, url); err != nil {
+		return err
+	}
+	return detectRiskControl(ctx, d)
+}
+
+// waitForInitialState 等待页面数据 __INITIAL_STATE__ 加载，
+// 同时检测风控页面，避免在验证页面上一直等到超时
+func waitForInitialState(ctx context.Context, d Driver) error {
+	for {
+		if result, err := d.Eval(ctx, initialStateReadyScript); err == nil && result.Bool() {
+			return detectRiskControl(ctx, d)
+		}
+		if err := d.Sleep(ctx, selectorPollInterval); err != nil {
+			return errors.Wrap(err, "等待页面数据 __INITIAL_STATE__ 超时")
+		}
+	}
+}
+
+// readInitialState 读取页面的 __INITIAL_STATE__ JSON
+func readInitialState(ctx context.Context, d Driver) (string, error) {
+	result, err := d.Eval(ctx, InitialStateScript)
+	if err != nil {
+		return "", errors.Wrap(err, "read __INITIAL_STATE__")
+	}
+	if result.Str() == "" {
+		return "", errors.New("__INITIAL_STATE__ not found")
+	}
+	return result.Str(), nil
+}
@@ -0,0 +1,442 @@
+package xiaohongshu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// 数据结构变化的类型
+const (
+	// DriftMissing 必需字段不存在或为 null
+	DriftMissing = "missing"
+	// DriftType 字段类型与解析使用的结构体不一致
+	DriftType = "type"
+	// DriftUnknown 结构体中没有的字段，可能是新增字段，也可能是改名后的字段
+	DriftUnknown = "unknown"
+)
+
+// stateSchema 从 __INITIAL_STATE__ 中解析数据时预期的结构
+type stateSchema struct {
+	name string
+	// root 解析的数据在 __INITIAL_STATE__ 中的路径，{id} 为笔记 ID，为空表示整个数据
+	root string
+	// typ 解析使用的结构体，字段和类型按 json 标签比对
+	typ reflect.Type
+	// required 必需的字段，root 为数组时相对于每个元素
+	required []string
+	// noteOnly 只对 modelType 为 note 的元素检查必需字段，搜索结果中还有推荐词等其他类型
+	noteOnly bool
+}
+
+// noteCardRequired 首页和搜索结果中笔记卡片的必需字段
+var noteCardRequired = []string{
+	"id",
+	"xsecToken",
+	"modelType",
+	"noteCard.type",
+	"noteCard.displayTitle",
+	"noteCard.user.userId",
+	"noteCard.interactInfo",
+	"noteCard.cover",
+}
+
+var (
+	feedsSchema = &stateSchema{
+		name:     "feeds",
+		root:     "feed.feeds._value",
+		typ:      reflect.TypeOf([]Feed{}),
+		required: noteCardRequired,
+		noteOnly: true,
+	}
+	searchSchema = &stateSchema{
+		name:     "search",
+		root:     "search.feeds._value",
+		typ:      reflect.TypeOf([]Feed{}),
+		required: noteCardRequired,
+		noteOnly: true,
+	}
+	feedDetailSchema = &stateSchema{
+		name: "feed_detail",
+		root: "note.noteDetailMap.{id}",
+		typ:  reflect.TypeOf(feedDetailState{}),
+		required: []string{
+			"note.noteId",
+			"note.title",
+			"note.desc",
+			"note.type",
+			"note.user.userId",
+			"note.interactInfo",
+			"note.imageList",
+			"comments",
+		},
+	}
+	userProfileSchema = &stateSchema{
+		name: "user_profile",
+		typ:  reflect.TypeOf(userProfileState{}),
+		required: []string{
+			"userPageData.basicInfo.nickname",
+			"userPageData.interactions",
+			"notes",
+		},
+	}
+)
+
+// SchemaDrift 一处数据结构变化
+type SchemaDrift struct {
+	Kind string `json:"kind" description:"missing 缺少必需字段、type 类型不符、unknown 未知字段"`
+	// Path 字段路径，数组元素用 [] 表示
+	Path     string `json:"path"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+// SchemaReport 一次数据结构校验的结果
+type SchemaReport struct {
+	Schema string        `json:"schema"`
+	Drifts []SchemaDrift `json:"drifts,omitempty"`
+}
+
+// Breaking 会导致解析结果缺失或出错的变化：缺少必需字段和类型不符
+func (r *SchemaReport) Breaking() []SchemaDrift {
+	var breaking []SchemaDrift
+	for _, drift := range r.Drifts {
+		if drift.Kind != DriftUnknown {
+			breaking = append(breaking, drift)
+		}
+	}
+	return breaking
+}
+
+// Diff 与预期结构的差异，每行一处：- 缺少的字段，~ 类型不符的字段，+ 未知字段
+func (r *SchemaReport) Diff() string {
+	var b strings.Builder
+	for _, drift := range r.Drifts {
+		switch drift.Kind {
+		case DriftMissing:
+			fmt.Fprintf(&b, "- %s (%s)\n", drift.Path, drift.Expected)
+		case DriftType:
+			fmt.Fprintf(&b, "~ %s: %s → %s\n", drift.Path, drift.Expected, drift.Actual)
+		case DriftUnknown:
+			fmt.Fprintf(&b, "+ %s (%s)\n", drift.Path, drift.Actual)
+		}
+	}
+	return b.String()
+}
+
+func (r *SchemaReport) add(kind, path, expected, actual string) {
+	for _, drift := range r.Drifts {
+		if drift.Kind == kind && drift.Path == path {
+			return
+		}
+	}
+	r.Drifts = append(r.Drifts, SchemaDrift{Kind: kind, Path: path, Expected: expected, Actual: actual})
+}
+
+// SchemaDriftError 严格模式下页面数据缺少必需字段或类型不符
+type SchemaDriftError struct {
+	Report *SchemaReport
+}
+
+func (e *SchemaDriftError) Error() string {
+	breaking := &SchemaReport{Schema: e.Report.Schema, Drifts: e.Report.Breaking()}
+	return fmt.Sprintf("小红书页面数据结构与预期不符（%s）：\n%s", e.Report.Schema, strings.TrimRight(breaking.Diff(), "\n"))
+}
+
+// strictSchema 严格模式：发现会导致解析出错的结构变化时请求失败，而不是返回缺失字段的结果
+var strictSchema atomic.Bool
+
+// SetStrictSchema 开启或关闭严格模式
+func SetStrictSchema(strict bool) {
+	strictSchema.Store(strict)
+}
+
+// validate 校验 __INITIAL_STATE__ 的 JSON，vars 替换 root 中的占位符
+func (s *stateSchema) validate(data string, vars *strings.Replacer) (*SchemaReport, error) {
+	var state any
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal __INITIAL_STATE__")
+	}
+	report := &SchemaReport{Schema: s.name}
+
+	value := state
+	if s.root != "" {
+		for _, key := range strings.Split(s.root, ".") {
+			if vars != nil {
+				key = vars.Replace(key)
+			}
+			obj, _ := value.(map[string]any)
+			if value = obj[key]; value == nil {
+				report.add(DriftMissing, s.root, kindOfType(s.typ), "")
+				return report, nil
+			}
+		}
+	}
+
+	s.checkType(report, s.root, s.typ, value)
+
+	if items, ok := value.([]any); ok {
+		for _, item := range items {
+			s.checkRequired(report, s.root+"[]", item)
+		}
+	} else {
+		s.checkRequired(report, s.root, value)
+	}
+
+	sort.SliceStable(report.Drifts, func(i, j int) bool {
+		if report.Drifts[i].Kind != report.Drifts[j].Kind {
+			return driftOrder(report.Drifts[i].Kind) < driftOrder(report.Drifts[j].Kind)
+		}
+		return report.Drifts[i].Path < report.Drifts[j].Path
+	})
+	return report, nil
+}
+
+// checkType 按结构体的 json 标签比对字段类型，并记录结构体中没有的字段
+func (s *stateSchema) checkType(report *SchemaReport, path string, typ reflect.Type, value any) {
+	if value == nil {
+		return
+	}
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() == reflect.Interface {
+		return
+	}
+
+	expected, actual := kindOfType(typ), kindOfValue(value)
+	if expected != actual {
+		report.add(DriftType, path, expected, actual)
+		return
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		fields := jsonFields(typ)
+		for key, v := range value.(map[string]any) {
+			field, ok := fields[key]
+			if !ok {
+				report.add(DriftUnknown, joinPath(path, key), "", kindOfValue(v))
+				continue
+			}
+			s.checkType(report, joinPath(path, key), field, v)
+		}
+	case reflect.Map:
+		for key, v := range value.(map[string]any) {
+			s.checkType(report, joinPath(path, key), typ.Elem(), v)
+		}
+	case reflect.Slice, reflect.Array:
+		for _, v := range value.([]any) {
+			s.checkType(report, path+"[]", typ.Elem(), v)
+		}
+	}
+}
+
+// checkRequired 检查必需字段是否存在且不为 null
+func (s *stateSchema) checkRequired(report *SchemaReport, path string, value any) {
+	obj, ok := value.(map[string]any)
+	if !ok {
+		return
+	}
+	if s.noteOnly {
+		if modelType, ok := obj["modelType"]; ok && modelType != "note" {
+			return
+		}
+	}
+
+	for _, field := range s.required {
+		var v any = obj
+		for _, key := range strings.Split(field, ".") {
+			m, _ := v.(map[string]any)
+			v = m[key]
+		}
+		if v == nil {
+			report.add(DriftMissing, joinPath(path, field), kindOfType(fieldType(s.typ, field)), "")
+		}
+	}
+}
+
+// jsonFields 结构体按 json 名称索引的字段类型
+func jsonFields(typ reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// fieldType 按 json 路径查找字段类型，找不到时返回 nil
+func fieldType(typ reflect.Type, path string) reflect.Type {
+	for _, key := range strings.Split(path, ".") {
+		for typ != nil && (typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array) {
+			typ = typ.Elem()
+		}
+		if typ == nil || typ.Kind() != reflect.Struct {
+			return nil
+		}
+		typ = jsonFields(typ)[key]
+	}
+	return typ
+}
+
+func kindOfType(typ reflect.Type) string {
+	if typ == nil {
+		return "any"
+	}
+	switch typ.Kind() {
+	case reflect.Ptr:
+		return kindOfType(typ.Elem())
+	case reflect.Struct, reflect.Map:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	default:
+		return "any"
+	}
+}
+
+func kindOfValue(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	default:
+		return "any"
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func driftOrder(kind string) int {
+	switch kind {
+	case DriftMissing:
+		return 0
+	case DriftType:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// SchemaStatus 单个数据结构的校验统计
+type SchemaStatus struct {
+	Schema string `json:"schema"`
+	Checks int    `json:"checks"`
+	// Drifted 按变化类型统计出现变化的校验次数
+	Drifted     map[string]int `json:"drifted"`
+	LastDrift   *SchemaReport  `json:"last_drift,omitempty"`
+	LastDriftAt *time.Time     `json:"last_drift_at,omitempty"`
+}
+
+var (
+	schemaStatsMu sync.Mutex
+	schemaStats   = map[string]*SchemaStatus{}
+)
+
+func recordSchemaReport(report *SchemaReport) {
+	schemaStatsMu.Lock()
+	defer schemaStatsMu.Unlock()
+
+	status, ok := schemaStats[report.Schema]
+	if !ok {
+		status = &SchemaStatus{Schema: report.Schema, Drifted: map[string]int{}}
+		schemaStats[report.Schema] = status
+	}
+	status.Checks++
+	if len(report.Drifts) == 0 {
+		return
+	}
+
+	kinds := map[string]bool{}
+	for _, drift := range report.Drifts {
+		kinds[drift.Kind] = true
+	}
+	for kind := range kinds {
+		status.Drifted[kind]++
+	}
+	now := time.Now()
+	status.LastDrift, status.LastDriftAt = report, &now
+}
+
+// GetSchemaStatus 各数据结构的校验统计，按名称排序
+func GetSchemaStatus() []SchemaStatus {
+	schemaStatsMu.Lock()
+	defer schemaStatsMu.Unlock()
+
+	result := make([]SchemaStatus, 0, len(schemaStats))
+	for _, status := range schemaStats {
+		s := *status
+		s.Drifted = make(map[string]int, len(status.Drifted))
+		for kind, count := range status.Drifted {
+			s.Drifted[kind] = count
+		}
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Schema < result[j].Schema })
+	return result
+}
+
+// checkStateSchema 解析前校验页面数据的结构并记录统计。缺少必需字段或类型不符时输出警告，
+// 严格模式下返回 *SchemaDriftError；只有未知字段时只输出调试日志
+func checkStateSchema(ctx context.Context, schema *stateSchema, data string, vars *strings.Replacer) error {
+	report, err := schema.validate(data, vars)
+	if err != nil {
+		return err
+	}
+	recordSchemaReport(report)
+
+	if len(report.Breaking()) == 0 {
+		if len(report.Drifts) > 0 {
+			slog.DebugContext(ctx, "页面数据中有未知字段", "schema", schema.name, "diff", report.Diff())
+		}
+		return nil
+	}
+
+	slog.WarnContext(ctx, "页面数据结构与预期不符，解析结果可能缺少字段", "schema", schema.name, "diff", report.Diff())
+	if strictSchema.Load() {
+		return &SchemaDriftError{Report: report}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package xiaohongshu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validFeedsState = `{"feed":{"feeds":{"_value":[
+	{"id":"n1","xsecToken":"t1","modelType":"note","index":0,"noteCard":{"type":"normal","displayTitle":"标题",
+		"user":{"userId":"u1","nickname":"作者"},"interactInfo":{"likedCount":"10"},"cover":{"urlDefault":"https://img"}}},
+	{"id":"q1","modelType":"hot_query","hotQuery":{"queries":[]}}
+]}}}`
+
+func TestValidateStateSchema(t *testing.T) {
+	report, err := feedsSchema.validate(validFeedsState, nil)
+	require.NoError(t, err)
+	// 推荐词不是笔记，只会报告未知字段，不检查笔记卡片的必需字段
+	assert.Equal(t, []SchemaDrift{
+		{Kind: DriftUnknown, Path: "feed.feeds._value[].hotQuery", Actual: "object"},
+	}, report.Drifts)
+	assert.Empty(t, report.Breaking())
+
+	// displayTitle 改名为 title，likedCount 变成数字
+	drifted := strings.NewReplacer(`"displayTitle"`, `"title"`, `"likedCount":"10"`, `"likedCount":10`).Replace(validFeedsState)
+	report, err = feedsSchema.validate(drifted, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "- feed.feeds._value[].noteCard.displayTitle (string)\n"+
+		"~ feed.feeds._value[].noteCard.interactInfo.likedCount: string → number\n"+
+		"+ feed.feeds._value[].hotQuery (object)\n"+
+		"+ feed.feeds._value[].noteCard.title (string)\n", report.Diff())
+	assert.Len(t, report.Breaking(), 2)
+}
+
+func TestValidateStateSchemaRoot(t *testing.T) {
+	state := `{"note":{"noteDetailMap":{"n1":{"note":{"noteId":"n1","title":"","desc":"","type":"normal",
+		"user":{"userId":"u1"},"interactInfo":{},"imageList":[]},"comments":{"list":[]}}}}}`
+
+	report, err := feedDetailSchema.validate(state, strings.NewReplacer("{id}", "n1"))
+	require.NoError(t, err)
+	assert.Empty(t, report.Drifts)
+
+	report, err = feedDetailSchema.validate(state, strings.NewReplacer("{id}", "n2"))
+	require.NoError(t, err)
+	assert.Equal(t, []SchemaDrift{{Kind: DriftMissing, Path: "note.noteDetailMap.{id}", Expected: "object"}}, report.Drifts)
+}
+
+func TestStrictSchema(t *testing.T) {
+	var state any
+	require.NoError(t, json.Unmarshal([]byte(strings.Replace(validFeedsState, `"xsecToken":"t1",`, "", 1)), &state))
+	newDriver := func() *FakeDriver {
+		return NewFakeDriver(map[string]*FakePage{BaseURL(): {State: state}})
+	}
+	before := schemaStatus(t, "feeds")
+
+	ctx := context.Background()
+	action, err := NewFeedsListAction(ctx, newDriver())
+	require.NoError(t, err)
+	feeds, err := action.GetFeedsList(ctx)
+	require.NoError(t, err)
+	assert.Len(t, feeds, 2)
+
+	SetStrictSchema(true)
+	t.Cleanup(func() { SetStrictSchema(false) })
+
+	action, err = NewFeedsListAction(ctx, newDriver())
+	require.NoError(t, err)
+	_, err = action.GetFeedsList(ctx)
+	var driftErr *SchemaDriftError
+	require.True(t, errors.As(err, &driftErr), "err: %v", err)
+	assert.Contains(t, err.Error(), "- feed.feeds._value[].xsecToken (string)")
+	assert.NotContains(t, err.Error(), "hotQuery")
+
+	after := schemaStatus(t, "feeds")
+	assert.Equal(t, before.Checks+2, after.Checks)
+	assert.Equal(t, before.Drifted[DriftMissing]+2, after.Drifted[DriftMissing])
+	require.NotNil(t, after.LastDrift)
+	assert.Equal(t, "feeds", after.LastDrift.Schema)
+}
+
+func schemaStatus(t *testing.T, name string) SchemaStatus {
+	t.Helper()
+	for _, status := range GetSchemaStatus() {
+		if status.Schema == name {
+			return status
+		}
+	}
+	return SchemaStatus{Drifted: map[string]int{}}
+}
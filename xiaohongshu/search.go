@@ -6,8 +6,6 @@ import (
 	"fmt"
 	"net/url"
 	"time"
-
-	"github.com/go-rod/rod"
 )
 
 type SearchResult struct {
@@ -17,34 +15,48 @@ type SearchResult struct {
 }
 
 type SearchAction struct {
-	page *rod.Page
+	driver Driver
 }
 
-func NewSearchAction(page *rod.Page) *SearchAction {
-	pp := page.Timeout(60 * time.Second)
-
-	return &SearchAction{page: pp}
+func NewSearchAction(driver Driver) *SearchAction {
+	return &SearchAction{driver: driver}
 }
 
 func (s *SearchAction) Search(ctx context.Context, keyword string) ([]Feed, error) {
-	page := s.page.Context(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	progress := newStepProgress(ctx, 0, 3)
 
+	defer blockResources(ctx, s.driver, "search")()
+
+	// 首屏之后的搜索结果只出现在搜索接口的响应中
+	capture := startAPICapture(ctx, s.driver)
+	defer capture.stop()
+
+	progress.step("打开搜索页面")
 	searchURL := makeSearchURL(keyword)
-	page.MustNavigate(searchURL)
-	page.MustWaitStable()
+	if err := s.driver.Navigate(ctx, searchURL); err != nil {
+		return nil, err
+	}
+	if err := s.driver.WaitStable(ctx); err != nil {
+		return nil, err
+	}
 
-	page.MustWait(`() => window.__INITIAL_STATE__ !== undefined`)
+	progress.step("等待搜索结果")
+	if err := waitForInitialState(ctx, s.driver); err != nil {
+		return nil, err
+	}
 
 	// 获取 window.__INITIAL_STATE__ 并转换为 JSON 字符串
-	result := page.MustEval(`() => {
-			if (window.__INITIAL_STATE__) {
-				return JSON.stringify(window.__INITIAL_STATE__);
-			}
-			return "";
-		}`).String()
-
-	if result == "" {
-		return nil, fmt.Errorf("__INITIAL_STATE__ not found")
+	result, err := readInitialState(ctx, s.driver)
+	if err != nil {
+		return nil, err
+	}
+
+	progress.step("解析搜索结果")
+	if err := checkStateSchema(ctx, searchSchema, result, nil); err != nil {
+		return nil, err
 	}
 
 	var searchResult SearchResult
@@ -52,7 +64,9 @@ func (s *SearchAction) Search(ctx context.Context, keyword string) ([]Feed, erro
 		return nil, fmt.Errorf("failed to unmarshal __INITIAL_STATE__: %w", err)
 	}
 
-	return searchResult.Search.Feeds.Value, nil
+	capture.loadMore(ctx, s.driver, apiSearchNotes)
+
+	return mergeFeeds(searchResult.Search.Feeds.Value, capture.searchFeeds(ctx)), nil
 }
 
 func makeSearchURL(keyword string) string {
@@ -61,5 +75,5 @@ func makeSearchURL(keyword string) string {
 	values.Set("keyword", keyword)
 	values.Set("source", "web_explore_feed")
 
-	return fmt.Sprintf("https://www.xiaohongshu.com/search_result?%s", values.Encode())
+	return webURL("/search_result?" + values.Encode())
 }
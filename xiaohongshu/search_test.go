@@ -2,9 +2,11 @@ package xiaohongshu
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/xpzouying/xiaohongshu-mcp/browser"
 )
@@ -19,7 +21,7 @@ func TestSearch(t *testing.T) {
 	page := b.NewPage()
 	defer page.Close()
 
-	action := NewSearchAction(page)
+	action := NewSearchAction(NewRodDriver(page))
 
 	feeds, err := action.Search(context.Background(), "Kimi")
 	require.NoError(t, err)
@@ -32,3 +34,16 @@ func TestSearch(t *testing.T) {
 		fmt.Printf("Feed Title: %s\n", feed.NoteCard.DisplayTitle)
 	}
 }
+
+func TestSearchDetectsRiskControl(t *testing.T) {
+	driver := NewFakeDriver(map[string]*FakePage{
+		"https://www.xiaohongshu.com/search_result": {RiskReason: "出现验证码 .red-captcha"},
+	})
+
+	_, err := NewSearchAction(driver).Search(context.Background(), "Kimi")
+
+	var riskErr *RiskControlError
+	require.True(t, errors.As(err, &riskErr), "err: %v", err)
+	assert.Equal(t, "出现验证码 .red-captcha", riskErr.Reason)
+	assert.Equal(t, makeSearchURL("Kimi"), riskErr.URL)
+}
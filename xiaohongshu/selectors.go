@@ -0,0 +1,530 @@
+package xiaohongshu
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed selectors.yaml
+var defaultSelectorsYAML []byte
+
+// selectorPollInterval 等待元素出现时轮询的间隔
+const selectorPollInterval = 200 * time.Millisecond
+
+// roleScript 按 ARIA 角色查找元素，name 不为空时还需匹配可访问名称
+const roleScript = `(role, name) => {
+	const implicit = {
+		button: "button, input[type=button], input[type=submit]",
+		textbox: "input:not([type]), input[type=text], textarea, [contenteditable=true]",
+		combobox: "select",
+		option: "option",
+		link: "a[href]",
+	};
+	const selector = '[role="' + role + '"]' + (implicit[role] ? ", " + implicit[role] : "");
+	const re = name ? new RegExp(name) : null;
+	for (const el of document.querySelectorAll(selector)) {
+		if (!re) return el;
+		const hint = el.querySelector("[data-placeholder]");
+		const label = [
+			el.getAttribute("aria-label"),
+			el.getAttribute("placeholder"),
+			el.getAttribute("data-placeholder"),
+			hint && hint.getAttribute("data-placeholder"),
+			el.innerText,
+		].filter(Boolean).join(" ");
+		if (re.test(label)) return el;
+	}
+	return null;
+}`
+
+// SelectorCandidate 选择器的一条规则，css 和 role 二选一
+type SelectorCandidate struct {
+	// CSS CSS 选择器
+	CSS string `yaml:"css,omitempty" json:"css,omitempty"`
+	// Text 与 CSS 搭配使用，元素文本需要匹配的正则
+	Text string `yaml:"text,omitempty" json:"text,omitempty"`
+	// Role ARIA 角色
+	Role string `yaml:"role,omitempty" json:"role,omitempty"`
+	// Name 与 Role 搭配使用，可访问名称需要匹配的正则
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+}
+
+// String 规则的可读形式，用于日志和命中统计
+func (c SelectorCandidate) String() string {
+	switch {
+	case c.Role != "" && c.Name != "":
+		return fmt.Sprintf("role=%s name=/%s/", c.Role, c.Name)
+	case c.Role != "":
+		return "role=" + c.Role
+	case c.Text != "":
+		return fmt.Sprintf("css=%s text=/%s/", c.CSS, c.Text)
+	default:
+		return "css=" + c.CSS
+	}
+}
+
+func (c SelectorCandidate) validate() error {
+	if (c.CSS == "") == (c.Role == "") {
+		return errors.New("css and role must be set exactly one")
+	}
+	if c.Text != "" && c.CSS == "" {
+		return errors.New("text requires css")
+	}
+	if c.Name != "" && c.Role == "" {
+		return errors.New("name requires role")
+	}
+	for _, pattern := range []string{c.Text, c.Name} {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return errors.Wrapf(err, "invalid regexp %q", pattern)
+		}
+	}
+	return nil
+}
+
+// find 在页面或元素内按规则查找一次元素，不等待。role 规则只能在页面上查找
+func (c SelectorCandidate) find(ctx context.Context, f Finder) (Element, error) {
+	switch {
+	case c.Role != "":
+		d, ok := f.(Driver)
+		if !ok {
+			return nil, ErrElementNotFound
+		}
+		return d.ElementByRole(ctx, c.Role, c.Name)
+	case c.Text != "":
+		return f.ElementR(ctx, c.CSS, c.Text)
+	default:
+		return f.Element(ctx, c.CSS)
+	}
+}
+
+// findAll 在页面或元素内查找所有匹配 css 且文本匹配 text 的元素，role 规则不支持，返回空列表
+func (c SelectorCandidate) findAll(ctx context.Context, f Finder) ([]Element, error) {
+	if c.CSS == "" {
+		return nil, nil
+	}
+	elems, err := f.Elements(ctx, c.CSS)
+	if err != nil || c.Text == "" {
+		return elems, err
+	}
+
+	re, err := regexp.Compile(c.Text)
+	if err != nil {
+		return nil, err
+	}
+	var matched []Element
+	for _, el := range elems {
+		if text, _ := el.Text(ctx); re.MatchString(text) {
+			matched = append(matched, el)
+		}
+	}
+	return matched, nil
+}
+
+// SelectorSpec 一个页面元素的选择器，按顺序尝试 Candidates
+type SelectorSpec struct {
+	Description string              `yaml:"description" json:"description"`
+	Candidates  []SelectorCandidate `yaml:"candidates" json:"candidates"`
+	// Canary 巡检时检查该元素的页面，为空表示元素需要上传图片等操作后才出现，巡检不检查
+	Canary string `yaml:"canary,omitempty" json:"canary,omitempty"`
+}
+
+// SelectorFile 选择器配置文件
+type SelectorFile struct {
+	// Version 选择器集合的版本，页面改版更新选择器时修改
+	Version   string                  `yaml:"version" json:"version"`
+	Selectors map[string]SelectorSpec `yaml:"selectors" json:"selectors"`
+}
+
+// parseSelectorFile 解析并校验选择器配置
+func parseSelectorFile(data []byte) (*SelectorFile, error) {
+	var file SelectorFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, errors.Wrap(err, "parse selectors")
+	}
+
+	for key, spec := range file.Selectors {
+		if len(spec.Candidates) == 0 {
+			return nil, errors.Errorf("selector %s: no candidates", key)
+		}
+		for i, c := range spec.Candidates {
+			if err := c.validate(); err != nil {
+				return nil, errors.Wrapf(err, "selector %s candidate %d", key, i)
+			}
+		}
+		if spec.Canary != "" && !isCanaryPage(spec.Canary) {
+			return nil, errors.Errorf("selector %s: unknown canary page %s", key, spec.Canary)
+		}
+	}
+	return &file, nil
+}
+
+// SelectorStatus 单个选择器的命中情况
+type SelectorStatus struct {
+	Key          string            `json:"key"`
+	Description  string            `json:"description"`
+	Candidates   []CandidateStatus `json:"candidates" description:"按顺序尝试的规则及命中次数"`
+	Misses       int               `json:"misses" description:"所有规则都没有命中的次数"`
+	LastMatch    string            `json:"last_match,omitempty" description:"最近一次命中的规则"`
+	LastFallback int               `json:"last_fallback" description:"最近一次命中规则的序号，大于 0 表示主规则已失效"`
+	LastUsedAt   *time.Time        `json:"last_used_at,omitempty"`
+}
+
+// CandidateStatus 规则的命中次数
+type CandidateStatus struct {
+	Rule    string `json:"rule"`
+	Matches int    `json:"matches"`
+}
+
+// SelectorReport 当前生效的选择器及其命中情况
+type SelectorReport struct {
+	Version   string           `json:"version" description:"选择器集合的版本"`
+	Source    string           `json:"source" description:"选择器来源：内置或覆盖文件路径"`
+	LoadedAt  time.Time        `json:"loaded_at"`
+	Selectors []SelectorStatus `json:"selectors"`
+}
+
+type selectorStats struct {
+	// matches 按规则的可读形式统计，重新加载后规则不变的统计继续累计
+	matches      map[string]int
+	misses       int
+	lastMatch    string
+	lastFallback int
+	lastUsedAt   time.Time
+}
+
+// SelectorRegistry 选择器注册表：内置默认选择器，可以通过覆盖文件替换其中的部分选择器
+type SelectorRegistry struct {
+	mu        sync.RWMutex
+	defaults  *SelectorFile
+	version   string
+	source    string
+	loadedAt  time.Time
+	selectors map[string]SelectorSpec
+	stats     map[string]*selectorStats
+}
+
+// newSelectorRegistry 使用默认选择器创建注册表
+func newSelectorRegistry(defaults *SelectorFile) *SelectorRegistry {
+	r := &SelectorRegistry{defaults: defaults, stats: make(map[string]*selectorStats)}
+	r.apply(defaults.Version, "embedded", defaults.Selectors)
+	return r
+}
+
+// selectors 全局选择器注册表
+var selectors = newSelectorRegistry(mustParseSelectorFile(defaultSelectorsYAML))
+
+func mustParseSelectorFile(data []byte) *SelectorFile {
+	file, err := parseSelectorFile(data)
+	if err != nil {
+		panic(err)
+	}
+	return file
+}
+
+func (r *SelectorRegistry) apply(version, source string, overrides map[string]SelectorSpec) {
+	merged := make(map[string]SelectorSpec, len(r.defaults.Selectors))
+	for key, spec := range r.defaults.Selectors {
+		merged[key] = spec
+	}
+	for key, spec := range overrides {
+		if spec.Description == "" {
+			spec.Description = merged[key].Description
+		}
+		if spec.Canary == "" {
+			spec.Canary = merged[key].Canary
+		}
+		merged[key] = spec
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.version = version
+	r.source = source
+	r.loadedAt = time.Now()
+	r.selectors = merged
+}
+
+// LoadOverrides 加载覆盖文件，文件中的选择器替换同名的默认选择器。
+// 文件不合法或包含未知的选择器时返回错误，当前选择器保持不变
+func (r *SelectorRegistry) LoadOverrides(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "read selectors")
+	}
+
+	file, err := parseSelectorFile(data)
+	if err != nil {
+		return errors.Wrap(err, path)
+	}
+	for key := range file.Selectors {
+		if _, ok := r.defaults.Selectors[key]; !ok {
+			return errors.Errorf("%s: unknown selector %s", path, key)
+		}
+	}
+
+	version := file.Version
+	if version == "" {
+		version = r.defaults.Version + "+override"
+	}
+	r.apply(version, path, file.Selectors)
+	return nil
+}
+
+// Watch 定期检查覆盖文件，修改后重新加载，直到 ctx 结束
+func (r *SelectorRegistry) Watch(ctx context.Context, path string, interval time.Duration) {
+	var lastMod time.Time
+	if info, err := os.Stat(path); err == nil {
+		lastMod = info.ModTime()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil || info.ModTime().Equal(lastMod) {
+			continue
+		}
+		lastMod = info.ModTime()
+
+		if err := r.LoadOverrides(path); err != nil {
+			slog.ErrorContext(ctx, "重新加载选择器失败，继续使用当前选择器", "path", path, "error", err)
+			continue
+		}
+		slog.InfoContext(ctx, "已重新加载选择器", "path", path, "version", r.Report().Version)
+	}
+}
+
+// Report 当前生效的选择器及其命中情况，按名称排序
+func (r *SelectorRegistry) Report() SelectorReport {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	report := SelectorReport{
+		Version:   r.version,
+		Source:    r.source,
+		LoadedAt:  r.loadedAt,
+		Selectors: make([]SelectorStatus, 0, len(r.selectors)),
+	}
+
+	for key, spec := range r.selectors {
+		status := SelectorStatus{Key: key, Description: spec.Description}
+		stats := r.stats[key]
+		for _, c := range spec.Candidates {
+			candidate := CandidateStatus{Rule: c.String()}
+			if stats != nil {
+				candidate.Matches = stats.matches[candidate.Rule]
+			}
+			status.Candidates = append(status.Candidates, candidate)
+		}
+		if stats != nil {
+			status.Misses = stats.misses
+			status.LastMatch = stats.lastMatch
+			status.LastFallback = stats.lastFallback
+			if !stats.lastUsedAt.IsZero() {
+				lastUsedAt := stats.lastUsedAt
+				status.LastUsedAt = &lastUsedAt
+			}
+		}
+		report.Selectors = append(report.Selectors, status)
+	}
+
+	sort.Slice(report.Selectors, func(i, j int) bool { return report.Selectors[i].Key < report.Selectors[j].Key })
+	return report
+}
+
+// spec 查找选择器，名称不存在属于程序错误
+func (r *SelectorRegistry) spec(key string) SelectorSpec {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	spec, ok := r.selectors[key]
+	if !ok {
+		panic("unknown selector " + key)
+	}
+	return spec
+}
+
+func (r *SelectorRegistry) statsLocked(key string) *selectorStats {
+	stats, ok := r.stats[key]
+	if !ok {
+		stats = &selectorStats{matches: make(map[string]int)}
+		r.stats[key] = stats
+	}
+	return stats
+}
+
+// recordMatch 记录命中的规则，主规则以外的规则命中时输出警告，提示需要更新选择器
+func (r *SelectorRegistry) recordMatch(ctx context.Context, key string, index int, c SelectorCandidate) {
+	rule := c.String()
+
+	r.mu.Lock()
+	stats := r.statsLocked(key)
+	stats.matches[rule]++
+	stats.lastMatch = rule
+	stats.lastFallback = index
+	stats.lastUsedAt = time.Now()
+	r.mu.Unlock()
+
+	if index > 0 {
+		slog.WarnContext(ctx, "选择器主规则未命中，使用备选规则", "selector", key, "fallback", index, "rule", rule)
+		return
+	}
+	slog.DebugContext(ctx, "选择器命中", "selector", key, "rule", rule)
+}
+
+func (r *SelectorRegistry) recordMiss(ctx context.Context, key string, spec SelectorSpec) {
+	r.mu.Lock()
+	stats := r.statsLocked(key)
+	stats.misses++
+	stats.lastUsedAt = time.Now()
+	r.mu.Unlock()
+
+	rules := make([]string, 0, len(spec.Candidates))
+	for _, c := range spec.Candidates {
+		rules = append(rules, c.String())
+	}
+	slog.WarnContext(ctx, "选择器所有规则均未命中", "selector", key, "rules", strings.Join(rules, " | "))
+}
+
+// tryFind 按顺序尝试一次所有规则，返回命中规则的序号
+func (r *SelectorRegistry) tryFind(ctx context.Context, f Finder, key string) (Element, int, bool) {
+	for i, c := range r.spec(key).Candidates {
+		if el, err := c.find(ctx, f); err == nil {
+			r.recordMatch(ctx, key, i, c)
+			return el, i, true
+		}
+	}
+	return nil, -1, false
+}
+
+// find 等待任意一条规则命中，返回元素和命中规则的序号，超过 timeout（按轮询间隔累计，为 0 时只受 ctx 限制）后返回错误。
+// check 不为空时每轮查找后调用，返回错误则停止等待
+func (r *SelectorRegistry) find(ctx context.Context, d Driver, key string, timeout time.Duration, check func() error) (Element, int, error) {
+	var waited time.Duration
+	for {
+		if el, index, ok := r.tryFind(ctx, d, key); ok {
+			return el, index, nil
+		}
+
+		if check != nil {
+			if err := check(); err != nil {
+				return nil, -1, err
+			}
+		}
+
+		err := d.Sleep(ctx, selectorPollInterval)
+		waited += selectorPollInterval
+		if err == nil && (timeout == 0 || waited < timeout) {
+			continue
+		}
+
+		spec := r.spec(key)
+		r.recordMiss(ctx, key, spec)
+		if err == nil {
+			return nil, -1, errors.Errorf("找不到%s（选择器 %s）", spec.Description, key)
+		}
+		return nil, -1, errors.Wrapf(err, "找不到%s（选择器 %s）", spec.Description, key)
+	}
+}
+
+// all 第一条有匹配结果的规则匹配的所有元素
+func (r *SelectorRegistry) all(ctx context.Context, f Finder, key string) ([]Element, error) {
+	for i, c := range r.spec(key).Candidates {
+		elems, err := c.findAll(ctx, f)
+		if err != nil {
+			return nil, err
+		}
+		if len(elems) > 0 {
+			r.recordMatch(ctx, key, i, c)
+			return elems, nil
+		}
+	}
+	return nil, nil
+}
+
+// count 统计第一条有匹配结果的 CSS 规则匹配的元素数量
+func (r *SelectorRegistry) count(ctx context.Context, d Driver, key string) int {
+	for _, c := range r.spec(key).Candidates {
+		if c.CSS == "" || c.Text != "" {
+			continue
+		}
+		if elems, err := d.Elements(ctx, c.CSS); err == nil && len(elems) > 0 {
+			return len(elems)
+		}
+	}
+	return 0
+}
+
+// findSelector 等待选择器对应的元素出现，timeout 为 0 时一直等到 ctx 结束
+func findSelector(ctx context.Context, d Driver, key string, timeout time.Duration) (Element, error) {
+	el, _, err := selectors.find(ctx, d, key, timeout, nil)
+	return el, err
+}
+
+// hasSelector 页面上当前是否存在选择器对应的元素，不等待
+func hasSelector(ctx context.Context, d Driver, key string) bool {
+	_, _, ok := selectors.tryFind(ctx, d, key)
+	return ok
+}
+
+// trySelector 在页面或元素内查找一次选择器对应的元素，不等待，没有命中时返回 ErrElementNotFound，
+// 可以与 waitElement 搭配等待
+func trySelector(ctx context.Context, f Finder, key string) (Element, error) {
+	el, _, ok := selectors.tryFind(ctx, f, key)
+	if !ok {
+		return nil, ErrElementNotFound
+	}
+	return el, nil
+}
+
+// selectorElements 在页面或元素内查找选择器第一条有匹配结果的规则匹配的所有元素，不等待
+func selectorElements(ctx context.Context, f Finder, key string) ([]Element, error) {
+	return selectors.all(ctx, f, key)
+}
+
+// waitForSelector 等待选择器对应的元素出现并可见，同时检测风控页面
+func waitForSelector(ctx context.Context, d Driver, key string) (Element, error) {
+	el, _, err := selectors.find(ctx, d, key, 0, func() error { return detectRiskControl(ctx, d) })
+	if err != nil {
+		return nil, err
+	}
+	return el, el.WaitVisible(ctx)
+}
+
+// countSelector 选择器对应的元素数量
+func countSelector(ctx context.Context, d Driver, key string) int {
+	return selectors.count(ctx, d, key)
+}
+
+// LoadSelectorOverrides 加载选择器覆盖文件
+func LoadSelectorOverrides(path string) error {
+	return selectors.LoadOverrides(path)
+}
+
+// WatchSelectorOverrides 覆盖文件修改后自动重新加载，直到 ctx 结束
+func WatchSelectorOverrides(ctx context.Context, path string, interval time.Duration) {
+	selectors.Watch(ctx, path, interval)
+}
+
+// GetSelectorReport 当前生效的选择器及其命中情况
+func GetSelectorReport() SelectorReport {
+	return selectors.Report()
+}
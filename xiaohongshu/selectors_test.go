@@ -0,0 +1,144 @@
+package xiaohongshu
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultSelectorsCoverUsedKeys(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	require.NoError(t, err)
+
+	used := regexp.MustCompile(`(?:findSelector|hasSelector|waitForSelector|countSelector|trySelector|selectorElements)\([^,]+, [^,]+, "([a-z_.]+)"`)
+	report := newSelectorRegistry(mustParseSelectorFile(defaultSelectorsYAML)).Report()
+	known := map[string]bool{}
+	for _, status := range report.Selectors {
+		known[status.Key] = true
+		assert.NotEmpty(t, status.Description, status.Key)
+	}
+
+	count := 0
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		for _, match := range used.FindAllStringSubmatch(string(data), -1) {
+			count++
+			assert.True(t, known[match[1]], "%s: selector %s is not defined in selectors.yaml", file, match[1])
+		}
+	}
+	assert.NotZero(t, count)
+	assert.Equal(t, "embedded", report.Source)
+}
+
+func TestParseSelectorFileValidation(t *testing.T) {
+	cases := map[string]string{
+		"no candidates":  "selectors:\n  a:\n    candidates: []\n",
+		"css and role":   "selectors:\n  a:\n    candidates:\n      - css: button\n        role: button\n",
+		"text with role": "selectors:\n  a:\n    candidates:\n      - role: button\n        text: 发布\n",
+		"bad regexp":     "selectors:\n  a:\n    candidates:\n      - css: button\n        text: \"(\"\n",
+		"unknown canary": "selectors:\n  a:\n    canary: settings\n    candidates:\n      - css: button\n",
+	}
+	for name, data := range cases {
+		_, err := parseSelectorFile([]byte(data))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestSelectorOverrides(t *testing.T) {
+	registry := newSelectorRegistry(mustParseSelectorFile(defaultSelectorsYAML))
+	path := filepath.Join(t.TempDir(), "selectors.yaml")
+
+	require.NoError(t, os.WriteFile(path, []byte(`
+version: "2025.11.1"
+selectors:
+  publish.title_input:
+    candidates:
+      - css: input.title
+      - role: textbox
+        name: 标题
+`), 0o644))
+	require.NoError(t, registry.LoadOverrides(path))
+
+	spec := registry.spec("publish.title_input")
+	require.Len(t, spec.Candidates, 2)
+	assert.Equal(t, "input.title", spec.Candidates[0].CSS)
+	// 未填写描述时沿用默认选择器的描述
+	assert.Equal(t, "图文标题输入框", spec.Description)
+	// 没有覆盖的选择器保持默认
+	assert.Equal(t, "div.upload-content", registry.spec("publish.upload_area").Candidates[0].CSS)
+
+	report := registry.Report()
+	assert.Equal(t, "2025.11.1", report.Version)
+	assert.Equal(t, path, report.Source)
+
+	// 未知的选择器名称多半是拼写错误，拒绝加载并保留当前选择器
+	require.NoError(t, os.WriteFile(path, []byte("selectors:\n  publish.titel_input:\n    candidates:\n      - css: input\n"), 0o644))
+	assert.ErrorContains(t, registry.LoadOverrides(path), "unknown selector publish.titel_input")
+	assert.Equal(t, "input.title", registry.spec("publish.title_input").Candidates[0].CSS)
+}
+
+func TestSelectorWatchReloads(t *testing.T) {
+	registry := newSelectorRegistry(mustParseSelectorFile(defaultSelectorsYAML))
+	path := filepath.Join(t.TempDir(), "selectors.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("version: v1\nselectors: {}\n"), 0o644))
+	require.NoError(t, registry.LoadOverrides(path))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go registry.Watch(ctx, path, 10*time.Millisecond)
+
+	// 不合法的文件不会替换当前选择器
+	require.NoError(t, os.WriteFile(path, []byte("version: bad\nselectors:\n  login.qrcode:\n    candidates: []\n"), 0o644))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Second)))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, "v1", registry.Report().Version)
+
+	require.NoError(t, os.WriteFile(path, []byte("version: v2\nselectors:\n  login.qrcode:\n    candidates:\n      - css: img.qrcode\n"), 0o644))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(2*time.Second)))
+	assert.Eventually(t, func() bool { return registry.Report().Version == "v2" }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "img.qrcode", registry.spec("login.qrcode").Candidates[0].CSS)
+}
+
+func TestSelectorReportStats(t *testing.T) {
+	registry := newSelectorRegistry(mustParseSelectorFile(defaultSelectorsYAML))
+	spec := registry.spec("publish.submit_button")
+	ctx := context.Background()
+
+	registry.recordMatch(ctx, "publish.submit_button", 0, spec.Candidates[0])
+	registry.recordMatch(ctx, "publish.submit_button", 1, spec.Candidates[1])
+	registry.recordMiss(ctx, "publish.submit_button", spec)
+
+	var status SelectorStatus
+	for _, s := range registry.Report().Selectors {
+		if s.Key == "publish.submit_button" {
+			status = s
+		}
+	}
+	assert.Equal(t, []CandidateStatus{
+		{Rule: "css=div.submit div.d-button-content", Matches: 1},
+		{Rule: `css=button text=/^\s*发布\s*$/`, Matches: 1},
+	}, status.Candidates)
+	assert.Equal(t, 1, status.Misses)
+	assert.Equal(t, 1, status.LastFallback)
+	assert.Equal(t, `css=button text=/^\s*发布\s*$/`, status.LastMatch)
+	assert.NotNil(t, status.LastUsedAt)
+}
+
+func TestSelectorCanaryPages(t *testing.T) {
+	registry := newSelectorRegistry(mustParseSelectorFile(defaultSelectorsYAML))
+	assert.Equal(t, []string{"publish.image_tab", "publish.longtext_tab", "publish.upload_area"}, registry.canaryKeys(CanaryPagePublish))
+	assert.Equal(t, []string{"longtext.new_button"}, registry.canaryKeys(CanaryPagePublishLongText))
+
+	// 覆盖文件没有填写 canary 时沿用默认的巡检页面
+	path := filepath.Join(t.TempDir(), "selectors.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("selectors:\n  login.logged_in:\n    candidates:\n      - css: .user .channel\n"), 0o644))
+	require.NoError(t, registry.LoadOverrides(path))
+	assert.Equal(t, []string{"login.logged_in"}, registry.canaryKeys(CanaryPageExplore))
+}
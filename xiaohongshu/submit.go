@@ -0,0 +1,31 @@
+package xiaohongshu
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// SubmitTracker 记录发布是否已经点击了发布按钮。点击之后的失败（超时、取消、读取结果失败等）
+// 笔记可能已经发出，调用方不能当作没有发布处理
+type SubmitTracker struct {
+	submitted atomic.Bool
+}
+
+// Submitted 是否已经点击过发布按钮
+func (t *SubmitTracker) Submitted() bool {
+	return t.submitted.Load()
+}
+
+type submitTrackerContextKey struct{}
+
+// WithSubmitTracker 返回携带 tracker 的 context，发布时在点击发布按钮前记录
+func WithSubmitTracker(ctx context.Context, tracker *SubmitTracker) context.Context {
+	return context.WithValue(ctx, submitTrackerContextKey{}, tracker)
+}
+
+// markSubmitted 记录即将点击发布按钮，点击失败时也可能已经提交，所以在点击前记录
+func markSubmitted(ctx context.Context) {
+	if tracker, ok := ctx.Value(submitTrackerContextKey{}).(*SubmitTracker); ok && tracker != nil {
+		tracker.submitted.Store(true)
+	}
+}
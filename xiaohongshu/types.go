@@ -142,3 +142,31 @@ type PublishLongTextContent struct {
 	Title   string
 	Content string
 }
+
+// ================ 用户主页相关结构体 ================
+
+// UserProfileResponse 表示用户主页数据
+type UserProfileResponse struct {
+	UserID       string            `json:"userId"`
+	BasicInfo    UserBasicInfo     `json:"basicInfo"`
+	Interactions []UserInteraction `json:"interactions"`
+	Feeds        []Feed            `json:"feeds"`
+}
+
+// UserBasicInfo 表示用户基本信息
+type UserBasicInfo struct {
+	Nickname   string `json:"nickname"`
+	RedID      string `json:"redId"`
+	Desc       string `json:"desc"`
+	Gender     int    `json:"gender"`
+	IPLocation string `json:"ipLocation"`
+	Images     string `json:"images"`
+	Imageb     string `json:"imageb"`
+}
+
+// UserInteraction 表示用户的关注/粉丝/获赞与收藏数据
+type UserInteraction struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Count string `json:"count"`
+}
@@ -0,0 +1,116 @@
+package xiaohongshu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// UserProfileAction 用户主页动作
+type UserProfileAction struct {
+	driver Driver
+}
+
+// NewUserProfileAction 创建用户主页动作
+func NewUserProfileAction(driver Driver) *UserProfileAction {
+	return &UserProfileAction{driver: driver}
+}
+
+// UserProfile 获取用户主页的基本信息、互动数据和笔记列表
+func (u *UserProfileAction) UserProfile(ctx context.Context, userID, xsecToken string) (*UserProfileResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	progress := newStepProgress(ctx, 0, 3)
+
+	defer blockResources(ctx, u.driver, "user_profile")()
+
+	// 首屏之后的笔记只出现在用户笔记接口的响应中
+	capture := startAPICapture(ctx, u.driver)
+	defer capture.stop()
+
+	progress.step("打开用户主页")
+	if err := u.driver.Navigate(ctx, makeUserProfileURL(userID, xsecToken)); err != nil {
+		return nil, err
+	}
+	if err := u.driver.WaitStable(ctx); err != nil {
+		return nil, err
+	}
+
+	progress.step("等待用户数据")
+	if err := waitForInitialState(ctx, u.driver); err != nil {
+		return nil, err
+	}
+
+	value, err := u.driver.Eval(ctx, userProfileScript)
+	if err != nil {
+		return nil, err
+	}
+
+	result := value.Str()
+	if result == "" {
+		return nil, fmt.Errorf("__INITIAL_STATE__.user not found")
+	}
+
+	progress.step("解析用户数据")
+	if err := checkStateSchema(ctx, userProfileSchema, result, nil); err != nil {
+		return nil, err
+	}
+
+	var state userProfileState
+	if err := json.Unmarshal([]byte(result), &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal user state: %w", err)
+	}
+
+	response := &UserProfileResponse{
+		UserID:       userID,
+		BasicInfo:    state.UserPageData.BasicInfo,
+		Interactions: state.UserPageData.Interactions,
+	}
+	if len(state.Notes) > 0 {
+		response.Feeds = state.Notes[0]
+	}
+
+	capture.loadMore(ctx, u.driver, apiUserPosted)
+	response.Feeds = mergeFeeds(response.Feeds, capture.userFeeds(ctx))
+
+	return response, nil
+}
+
+// userProfileState userProfileScript 导出的用户数据
+type userProfileState struct {
+	UserPageData struct {
+		BasicInfo    UserBasicInfo     `json:"basicInfo"`
+		Interactions []UserInteraction `json:"interactions"`
+	} `json:"userPageData"`
+	// 笔记按 tab 分组（笔记、收藏、点赞），第一组为用户发布的笔记
+	Notes [][]Feed `json:"notes"`
+}
+
+// userProfileScript 导出用户信息和笔记列表。user store 中的字段是 Vue ref，需要取出实际值后再序列化
+const userProfileScript = `() => {
+	const state = window.__INITIAL_STATE__;
+	if (!state || !state.user) {
+		return "";
+	}
+	const unref = (v) => v && (v._rawValue ?? v._value ?? v.value ?? v);
+	return JSON.stringify({
+		userPageData: unref(state.user.userPageData),
+		notes: unref(state.user.notes),
+	});
+}`
+
+func makeUserProfileURL(userID, xsecToken string) string {
+	profileURL := webURL("/user/profile/" + url.PathEscape(userID))
+	if xsecToken == "" {
+		return profileURL
+	}
+
+	values := url.Values{}
+	values.Set("xsec_token", xsecToken)
+	values.Set("xsec_source", "pc_note")
+
+	return profileURL + "?" + values.Encode()
+}
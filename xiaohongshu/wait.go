@@ -0,0 +1,20 @@
+package xiaohongshu
+
+import (
+	"context"
+	"time"
+)
+
+// sleep 等待指定时长，context 取消时立即返回错误。
+// 用于替代 time.Sleep，保证固定等待期间也能及时响应取消。
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}